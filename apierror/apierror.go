@@ -0,0 +1,210 @@
+// Package apierror - 所有 HTTP 接口统一的错误响应
+//
+// 默认返回 JSON:
+//
+//	{"code": "invalid_credentials", "message": "...", "request_id": "...", "details": [...]}
+//
+// code 为 domain.ErrorCode, 客户端应据此判断错误类型; 5xx 与未带错误码的错误 只返回通用的 message,
+// 具体原因写入日志. 启用 WithProblemJSON 或请求的 Accept 为 application/problem+json 时,
+// 按 RFC 7807 返回 application/problem+json.
+package apierror
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/requestid"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ProblemContentType - RFC 7807 的 Content-Type
+const ProblemContentType = "application/problem+json"
+
+// DefaultTypeBase - problem 的 type 为 DefaultTypeBase + code
+const DefaultTypeBase = "urn:identity:error:"
+
+// formatKey - Middleware 将输出格式写入 gin.Context 的键
+const formatKey = "apierror.format"
+
+// Body - 默认的 JSON 错误响应
+type Body struct {
+	Code      domain.ErrorCode    `json:"code"`
+	Message   string              `json:"message"`
+	RequestID string              `json:"request_id,omitempty"`
+	Details   []domain.FieldError `json:"details,omitempty"`
+}
+
+// Problem - RFC 7807 problem details, code request_id errors 为扩展成员
+type Problem struct {
+	Type      string              `json:"type"`
+	Title     string              `json:"title"`
+	Status    int                 `json:"status"`
+	Detail    string              `json:"detail"`
+	Instance  string              `json:"instance,omitempty"`
+	Code      domain.ErrorCode    `json:"code"`
+	RequestID string              `json:"request_id,omitempty"`
+	Errors    []domain.FieldError `json:"errors,omitempty"`
+}
+
+type format struct {
+	problemJSON bool
+	typeBase    string
+}
+
+// Option - Middleware 可选配置
+type Option func(*format)
+
+// WithProblemJSON - 总是返回 application/problem+json
+func WithProblemJSON() Option {
+	return func(f *format) {
+		f.problemJSON = true
+	}
+}
+
+// WithTypeBase - problem 的 type 前缀, 如 "https://docs.example.com/errors/"
+func WithTypeBase(base string) Option {
+	return func(f *format) {
+		f.typeBase = base
+	}
+}
+
+// Middleware - 配置本请求的错误输出格式; 不使用时 按默认配置输出
+func Middleware(opts ...Option) gin.HandlerFunc {
+	f := newFormat(opts...)
+	return func(c *gin.Context) {
+		c.Set(formatKey, f)
+		c.Next()
+	}
+}
+
+func newFormat(opts ...Option) *format {
+	f := &format{typeBase: DefaultTypeBase}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// statusErrors - 未带错误码时 按 status 包中的错误 决定状态码与错误码
+var statusErrors = []struct {
+	err     error
+	status  int
+	code    domain.ErrorCode
+	message string
+}{
+	{status.ErrBadParamInput, http.StatusBadRequest, domain.CodeInvalidRequest, "invalid request"},
+	{status.ErrUnauthorized, http.StatusUnauthorized, domain.CodeUnauthorized, "unauthorized"},
+	{status.ErrForbidden, http.StatusForbidden, domain.CodeForbidden, "forbidden"},
+	{status.ErrNotFound, http.StatusNotFound, domain.CodeNotFound, "not found"},
+	{status.ErrConflict, http.StatusConflict, domain.CodeConflict, "already exists"},
+}
+
+// internalMessage - 5xx 只返回此 message
+const internalMessage = "internal server error"
+
+// StatusCode - err 对应的 HTTP 状态码, 也识别经 fmt.Errorf("%w") 包装的错误
+func StatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	for _, s := range statusErrors {
+		if errors.Is(err, s.err) {
+			return s.status
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// New - 将 err 转换为错误响应, 返回状态码
+func New(err error, requestID string) (int, *Body) {
+	code := StatusCode(err)
+	body := &Body{Code: domain.CodeInternal, Message: internalMessage, RequestID: requestID}
+	if code >= http.StatusInternalServerError {
+		return code, body
+	}
+
+	if coded := domain.AsCoded(err); coded != nil {
+		body.Code = coded.ErrorCode()
+		body.Message = coded.Error()
+		var ce *domain.CodedError
+		if errors.As(err, &ce) {
+			body.Message = ce.Message
+		}
+		body.Details = coded.FieldErrors()
+		return code, body
+	}
+	for _, s := range statusErrors {
+		if errors.Is(err, s.err) {
+			body.Code = s.code
+			body.Message = s.message
+			break
+		}
+	}
+	return code, body
+}
+
+// Respond - 返回错误响应, 5xx 的具体原因只写入日志
+func Respond(c *gin.Context, err error) {
+	code, body := New(err, requestid.FromContext(c.Request.Context()))
+	if code >= http.StatusInternalServerError {
+		logging.FromContext(c.Request.Context()).Error("request failed", zap.Error(err))
+	}
+
+	f := formatOf(c)
+	if !f.problemJSON && !acceptsProblem(c) {
+		c.JSON(code, body)
+		return
+	}
+	c.Header("Content-Type", ProblemContentType)
+	c.JSON(code, &Problem{
+		Type:      f.typeBase + string(body.Code),
+		Title:     http.StatusText(code),
+		Status:    code,
+		Detail:    body.Message,
+		Instance:  c.Request.URL.Path,
+		Code:      body.Code,
+		RequestID: body.RequestID,
+		Errors:    body.Details,
+	})
+}
+
+// Abort - 返回错误响应 并中止后续 handler, 用于中间件
+func Abort(c *gin.Context, err error) {
+	c.Abort()
+	Respond(c, err)
+}
+
+// Recovery - 替代 gin.Recovery: 后者会把请求头 (含 Cookie) 写入日志;
+// 此处只通过 Respond 记录 panic 与调用栈, 并返回 internal_error
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(nil, func(c *gin.Context, recovered interface{}) {
+		Abort(c, fmt.Errorf("%w: panic: %v", status.ErrInternalServerError, recovered))
+	})
+}
+
+func formatOf(c *gin.Context) *format {
+	if f, ok := c.Get(formatKey); ok {
+		return f.(*format)
+	}
+	return newFormat()
+}
+
+func acceptsProblem(c *gin.Context) bool {
+	for _, accept := range c.Request.Header.Values("Accept") {
+		for _, mediaType := range strings.Split(accept, ",") {
+			if i := strings.IndexByte(mediaType, ';'); i >= 0 {
+				mediaType = mediaType[:i]
+			}
+			if strings.EqualFold(strings.TrimSpace(mediaType), ProblemContentType) {
+				return true
+			}
+		}
+	}
+	return false
+}
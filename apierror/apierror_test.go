@@ -0,0 +1,95 @@
+package apierror_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/apierror"
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/gin-gonic/gin"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		err     error
+		status  int
+		code    domain.ErrorCode
+		message string
+	}{
+		{"Coded", domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidCredentials, "username or password invalid"),
+			http.StatusBadRequest, domain.CodeInvalidCredentials, "username or password invalid"},
+		{"WrappedCoded", fmt.Errorf("login: %w", domain.NewCodedError(status.ErrForbidden, domain.CodeAccountPendingDeletion, "account is scheduled for deletion")),
+			http.StatusForbidden, domain.CodeAccountPendingDeletion, "account is scheduled for deletion"},
+		// 未带错误码时 不返回包装的细节
+		{"Uncoded", fmt.Errorf("%w : userID not match", status.ErrUnauthorized), http.StatusUnauthorized, domain.CodeUnauthorized, "unauthorized"},
+		{"NotFound", status.ErrNotFound, http.StatusNotFound, domain.CodeNotFound, "not found"},
+		{"Internal", fmt.Errorf("%w : jwt parse error", status.ErrInternalServerError), http.StatusInternalServerError, domain.CodeInternal, "internal server error"},
+		{"Unknown", errors.New("mongo: connection refused"), http.StatusInternalServerError, domain.CodeInternal, "internal server error"},
+		{"CodedInternal", domain.NewCodedError(status.ErrInternalServerError, domain.CodeInvalidRequest, "secret detail"),
+			http.StatusInternalServerError, domain.CodeInternal, "internal server error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := apierror.New(tt.err, "req-1")
+			if code != tt.status || body.Code != tt.code || body.Message != tt.message || body.RequestID != "req-1" {
+				t.Fatalf("New() = %d %+v, want %d %s %q", code, body, tt.status, tt.code, tt.message)
+			}
+		})
+	}
+}
+
+func TestMiddlewareProblemJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(apierror.Middleware(apierror.WithProblemJSON(), apierror.WithTypeBase("https://errors.example.com/")))
+	engine.GET("/users/:id", func(c *gin.Context) {
+		apierror.Respond(c, status.ErrNotFound)
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, apierror.ProblemContentType) {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	var problem apierror.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+		t.Fatal(err)
+	}
+	want := apierror.Problem{
+		Type:     "https://errors.example.com/not_found",
+		Title:    "Not Found",
+		Status:   http.StatusNotFound,
+		Detail:   "not found",
+		Instance: "/users/42",
+		Code:     domain.CodeNotFound,
+	}
+	if fmt.Sprint(problem) != fmt.Sprint(want) {
+		t.Fatalf("problem %+v, want %+v", problem, want)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(apierror.Recovery())
+	engine.GET("/panic", func(c *gin.Context) {
+		panic("boom")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	var body apierror.Body
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusInternalServerError || body.Code != domain.CodeInternal || strings.Contains(body.Message, "boom") {
+		t.Fatalf("got %d %+v", w.Code, body)
+	}
+}
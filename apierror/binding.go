@@ -0,0 +1,87 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// 校验错误中的字段名 使用 json / form tag, 与客户端提交的字段一致
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(fieldName)
+	}
+}
+
+func fieldName(f reflect.StructField) string {
+	for _, tag := range []string{"json", "form"} {
+		name := strings.SplitN(f.Tag.Get(tag), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return f.Name
+}
+
+// Binding - 将 c.ShouldBind 的错误 转换为 validation_failed 或 invalid_request,
+// 校验失败时 details 中列出每个字段
+func Binding(err error) error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		details := make([]domain.FieldError, 0, len(validationErrs))
+		for _, fe := range validationErrs {
+			details = append(details, domain.FieldError{Field: fe.Field(), Rule: fe.Tag(), Message: fieldMessage(fe)})
+		}
+		return &domain.CodedError{
+			Code:    domain.CodeValidationFailed,
+			Message: "request validation failed",
+			Details: details,
+			Err:     status.ErrBadParamInput,
+		}
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &domain.CodedError{
+			Code:    domain.CodeValidationFailed,
+			Message: "request validation failed",
+			Details: []domain.FieldError{{Field: typeErr.Field, Rule: "type", Message: fmt.Sprintf("%s must be %s", typeErr.Field, typeErr.Type)}},
+			Err:     status.ErrBadParamInput,
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		return domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidRequest, "request body is empty")
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidRequest, "request body is not valid JSON")
+	}
+	// 其他错误 如 query 中的数字无法解析, 原始信息来自解析库 不返回给客户端
+	return domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidRequest, "request parameters are malformed")
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return fe.Field() + " is required"
+	case "url":
+		return fe.Field() + " must be a valid URL"
+	case "min", "max", "len", "gt", "gte", "lt", "lte":
+		return fmt.Sprintf("%s must satisfy %s=%s", fe.Field(), fe.Tag(), fe.Param())
+	case "oneof":
+		return fmt.Sprintf("%s must be one of [%s]", fe.Field(), fe.Param())
+	default:
+		return fmt.Sprintf("%s failed the %s rule", fe.Field(), fe.Tag())
+	}
+}
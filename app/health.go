@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+
+	"github.com/alibug/go-identity-entry/health"
+	_tokenUseCase "github.com/alibug/go-identity-entry/token/usecase"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+// newHealthChecker - 只检查已启用的依赖; health.timeoutSeconds 为所有检查共同的超时时间,
+// sqlDB 为 postgres 或 sqlite 用户存储
+func newHealthChecker(mongoDB *mongo.Database, sqlDB *sql.DB, redisConn redis.UniversalClient, tuc *_tokenUseCase.TokensUsecase) *health.Checker {
+	viper.SetDefault("health.timeoutSeconds", 2)
+	opts := []health.Option{
+		health.WithTimeout(time.Duration(viper.GetInt("health.timeoutSeconds")) * time.Second),
+		health.WithCheck("signing_keys", func(context.Context) error {
+			return tuc.CheckSigningKeys()
+		}),
+	}
+	if mongoDB != nil {
+		opts = append(opts, health.WithCheck("mongo", func(ctx context.Context) error {
+			return mongoDB.Client().Ping(ctx, readpref.Primary())
+		}))
+	}
+	if sqlDB != nil {
+		opts = append(opts, health.WithCheck(viper.GetString("user.store"), func(ctx context.Context) error {
+			return sqlDB.PingContext(ctx)
+		}))
+	}
+	if redisConn != nil {
+		opts = append(opts, health.WithCheck("redis", func(ctx context.Context) error {
+			return redisConn.Ping(ctx).Err()
+		}))
+	}
+	return health.NewChecker(opts...)
+}
+
+// serve - 收到退出信号后: /readyz 立即返回 503, 等待 shutdown.drainSeconds 使负载均衡摘除本实例,
+// 再在 shutdown.timeoutSeconds 内 等待处理中的请求完成; metricsServer 为 nil 时 不提供指标
+func serve(ctx context.Context, srv *http.Server, metricsServer *http.Server, grpcServer *grpc.Server, checker *health.Checker) {
+	go listenAndServe(srv)
+	if metricsServer != nil {
+		go listenAndServe(metricsServer)
+	}
+	<-ctx.Done()
+
+	viper.SetDefault("shutdown.drainSeconds", 5)
+	viper.SetDefault("shutdown.timeoutSeconds", 20)
+	zap.L().Info("shutting down")
+	checker.SetShuttingDown()
+	time.Sleep(time.Duration(viper.GetInt("shutdown.drainSeconds")) * time.Second)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(viper.GetInt("shutdown.timeoutSeconds"))*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		zap.L().Warn("http server shutdown incomplete", zap.Error(err))
+	}
+	// 指标最后关闭, 退出期间仍可被采集
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			zap.L().Warn("metrics server shutdown incomplete", zap.Error(err))
+		}
+	}
+	if grpcServer == nil {
+		return
+	}
+	stopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+}
+
+func listenAndServe(srv *http.Server) {
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		zap.L().Fatal("http server stopped", zap.String("addr", srv.Addr), zap.Error(err))
+	}
+}
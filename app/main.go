@@ -3,73 +3,462 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
 
-	_tokenRepo "github.com/alibug/go-identity-entry/token/repository/redisdb"
+	"github.com/alibug/go-identity-entry/apierror"
+	_auditHttpDelivery "github.com/alibug/go-identity-entry/audit/delivery/restgin"
+	_auditMemRepo "github.com/alibug/go-identity-entry/audit/repository/memory"
+	_auditRepo "github.com/alibug/go-identity-entry/audit/repository/mongodb"
+	_auditUseCase "github.com/alibug/go-identity-entry/audit/usecase"
+	"github.com/alibug/go-identity-entry/domain"
+	_eventRepo "github.com/alibug/go-identity-entry/event/repository/mongodb"
+	_eventStream "github.com/alibug/go-identity-entry/event/repository/redisdb"
+	_eventUseCase "github.com/alibug/go-identity-entry/event/usecase"
+	"github.com/alibug/go-identity-entry/health"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/metrics"
+	"github.com/alibug/go-identity-entry/requestid"
 	_tokenUseCase "github.com/alibug/go-identity-entry/token/usecase"
+	"github.com/alibug/go-identity-entry/tracing"
+	"github.com/alibug/go-identity-entry/user/account"
+	_userGrpcDelivery "github.com/alibug/go-identity-entry/user/delivery/grpc"
 	_userHttpDelivery "github.com/alibug/go-identity-entry/user/delivery/restgin"
-	_userRepo "github.com/alibug/go-identity-entry/user/repository/mongodb"
+	"github.com/alibug/go-identity-entry/user/password"
 	_userUseCase "github.com/alibug/go-identity-entry/user/usecase"
+	_webhookHttpDelivery "github.com/alibug/go-identity-entry/webhook/delivery/restgin"
+	_webhookRepo "github.com/alibug/go-identity-entry/webhook/repository/mongodb"
+	_webhookUseCase "github.com/alibug/go-identity-entry/webhook/usecase"
 	"github.com/alibug/go-identity-utils/config"
-	"github.com/alibug/go-identity-utils/mongoconn"
-	"github.com/alibug/go-identity-utils/redisconn"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func main() {
+	logger := newLogger()
+	defer logger.Sync()
+	// 后台任务的 ctx 携带 logger
+	ctx := logging.NewContext(context.Background(), logger)
+
+	shutdownTracing := setupTracing(ctx)
+	defer func() {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Warn("flush traces failed", zap.Error(err))
+		}
+	}()
 
 	duration := config.ReadCustomIntConfig("mongo.duration", false)
 	timeDuration := time.Duration(duration) * time.Second
 
-	mongourl := config.ReadMongoConfig("mongo")
+	// 用户与 token 均使用嵌入式或内存存储时, 可以关闭 MongoDB 和 Redis, 便于本地开发
+	viper.SetDefault("mongo.enabled", true)
+	viper.SetDefault("redis.enabled", true)
+	viper.SetDefault("user.store", "mongo")
+	viper.SetDefault("token.store", "redis")
 
 	// 3、初始化 MongoDB 数据读取器
-	conn, err := mongoconn.NewConn(mongourl, timeDuration)
-	if err != nil {
-		log.Fatalf("创建数据库连接失败: %v", err)
-	}
+	var mongoDB *mongo.Database
+	if viper.GetBool("mongo.enabled") {
+		mongourl := config.ReadMongoConfig("mongo")
+		var err error
+		mongoDB, err = newMongoDatabase(mongourl, timeDuration,
+			metrics.MongoMonitor(),
+			// 不记录 db.statement: 其中包含查询条件与写入的文档, 如密码哈希
+			otelmongo.NewMonitor(otelmongo.WithCommandAttributeDisabled(true)),
+		)
+		if err != nil {
+			logger.Fatal("create mongo connection failed", zap.Error(err))
+		}
 
-	defer func() {
-		err := conn.Close()
+		defer func() {
+			ctx, cancel := context.WithTimeout(ctx, timeDuration)
+			defer cancel()
+			if err := mongoDB.Client().Disconnect(ctx); err != nil {
+				logger.Error("close mongo connection failed", zap.Error(err))
+			}
+		}()
+
+		err = mongoDB.Client().Ping(ctx, nil)
 		if err != nil {
-			log.Fatal(err)
+			logger.Fatal("connect mongo failed", zap.Error(err))
 		}
-	}()
+	}
 
-	err = conn.Ping()
-	if err != nil {
-		log.Fatal("连接MongoDB数据库失败")
+	// 3.1、数据库迁移: `server migrate ...` 只执行迁移, 否则默认在启动时执行
+	viper.SetDefault("account.canonicalizeEmail", false)
+	accountNormalizer := account.NewNormalizer(viper.GetBool("account.canonicalizeEmail"))
+	userRepo, migrator, sqlDB := newUserStore(mongoDB, accountNormalizer)
+	if sqlDB != nil {
+		defer sqlDB.Close()
+	}
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if migrator == nil {
+			logger.Fatal("user store has no migrations", zap.String("store", viper.GetString("user.store")))
+		}
+		migrateCommand(migrator, os.Args[2:])
+		return
+	}
+	viper.SetDefault("migrate.onStartup", true)
+	if viper.GetBool("migrate.onStartup") && migrator != nil {
+		runMigrations(migrator)
+	}
+	if viper.GetString("user.store") == "mongo" {
+		checkAccountIndex(mongoDB)
 	}
 
-	// redisURL := config.ReadRedisConfig("redis")
-	// redisConn, err := redisconn.NewConn(redisURL)
-	redisConn := redisconn.NewConnFromConfig("redis")
-	if err != nil {
-		log.Fatalf("创建Redis数据库连接失败:%v", err)
+	var redisConn redis.UniversalClient
+	if viper.GetBool("redis.enabled") {
+		redisConn = newRedisClient()
+		redisConn.AddHook(metrics.RedisHook())
+		redisConn.AddHook(tracing.RedisHook())
+		_, err := redisConn.Ping(ctx).Result()
+		if err != nil {
+			logger.Fatal("connect redis failed", zap.Error(err))
+		}
 	}
 
-	_, err = redisConn.Ping(context.TODO()).Result()
-	if err != nil {
-		log.Fatal("连接Redis数据库失败")
+	// 收到 SIGTERM 或 SIGINT 后 后台任务随即结束, 服务优雅退出 (见 9、);
+	// 等待后台任务退出后 再执行上面的 defer: 导出 span, 断开 MongoDB
+	signalCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	background := &backgroundTasks{ctx: signalCtx}
+
+	var (
+		userOpts       []_userUseCase.Option
+		tokenOpts      []_tokenUseCase.Option
+		webhookUsecase domain.WebhookUsecase
+		auditRepo      domain.AuditRepository
+	)
+	viper.SetDefault("events.enabled", true)
+	if mongoDB != nil && viper.GetBool("events.enabled") {
+		// 4.1、领域事件 写入 Mongo 发件箱, 再由 relay 转发到 Redis Stream 和 webhook;
+		// 部署支持事务时 与用户数据在同一事务中写入, 见 newTransactor
+		outbox := _eventRepo.NewMongoEventOutbox(mongoDB.Collection("event_outbox"))
+		userOpts = append(userOpts, _userUseCase.WithEventPublisher(outbox))
+		if transactor := newTransactor(mongoDB); transactor != nil {
+			userOpts = append(userOpts, _userUseCase.WithTransactor(transactor))
+		}
+		tokenOpts = append(tokenOpts, _tokenUseCase.WithEventPublisher(outbox))
+		var publishers []domain.EventPublisher
+		if redisConn != nil {
+			viper.SetDefault("events.stream", "identity-events")
+			publishers = append(publishers, _eventStream.NewRedisStreamPublisher(redisConn, viper.GetString("events.stream")))
+		}
+
+		// 4.2、webhook: relay 为订阅者创建投递记录, worker 负责投递与重试
+		webhookRepo := _webhookRepo.NewMongoWebhookRepository(mongoDB.Collection("webhooks"), mongoDB.Collection("webhook_deliveries"))
+		webhookUsecase = _webhookUseCase.NewWebhookUsecase(webhookRepo, timeDuration)
+		viper.SetDefault("webhook.maxAttempts", 8)
+		viper.SetDefault("webhook.backoffSeconds", 30)
+		deliveryWorker := _webhookUseCase.NewDeliveryWorker(webhookRepo, &http.Client{Timeout: 10 * time.Second},
+			viper.GetInt("webhook.maxAttempts"), time.Duration(viper.GetInt("webhook.backoffSeconds"))*time.Second)
+		background.Go(func(ctx context.Context) { deliveryWorker.Run(ctx, time.Second) })
+		publishers = append(publishers, _webhookUseCase.NewDispatcher(webhookRepo))
+
+		relay := _eventUseCase.NewOutboxRelay(outbox, timeDuration, publishers...)
+		background.Go(func(ctx context.Context) { relay.Run(ctx, time.Second) })
+	} else {
+		logger.Warn("domain events and webhooks are disabled")
+	}
+	if mongoDB != nil {
+		auditRepo = _auditRepo.NewMongoAuditRepository(mongoDB.Collection("audit_events"))
+	} else {
+		logger.Warn("mongo disabled: audit events are kept in memory")
+		auditRepo = _auditMemRepo.NewMemoryAuditRepository()
 	}
 
-	// 4、配置指定的 Collection
-	usersColl := conn.GetColl("users")
-	userRepo := _userRepo.NewMongoUserRepository(usersColl)
-	userUsercase := _userUseCase.NewUserUsecase(userRepo, timeDuration)
+	// 默认 30 天宽限期, 期间用户可登录并撤销注销; 设为 0 时立即删除
+	viper.SetDefault("user.deletionGraceHours", 720)
+	deletionGrace := time.Duration(config.ReadCustomIntConfig("user.deletionGraceHours", true)) * time.Hour
+	userOpts = append(userOpts,
+		_userUseCase.WithAccountNormalizer(accountNormalizer),
+		_userUseCase.WithPasswordHasher(newPasswordHasher()),
+		_userUseCase.WithPasswordPolicy(newPasswordPolicy()),
+		_userUseCase.WithPasswordHistory(viper.GetInt("password.historySize")),
+		_userUseCase.WithPasswordMaxAge(readPasswordMaxAge()),
+		_userUseCase.WithDeletionGrace(deletionGrace),
+		_userUseCase.WithEraseOnDelete(viper.GetBool("user.deletionErase")),
+	)
+	userUsercase := _userUseCase.NewUserUsecase(userRepo, timeDuration, userOpts...)
+	background.Go(func(ctx context.Context) { purgeDeletedUsers(ctx, userUsercase, time.Hour) })
 
 	// 5、配置 TokenUserCase
 	tokenConfig := config.ReadTokenConfig("token", "maxage")
-	tokenRepo := _tokenRepo.NewRedisTokensRepository(redisConn, tokenConfig)
-	tokenUsercase := _tokenUseCase.NewTokensUsecase(tokenRepo, tokenConfig)
+	tokenRepo := newTokensRepository(redisConn)
+	if revocations := newRevocationList(background, redisConn); revocations != nil {
+		tokenOpts = append(tokenOpts, _tokenUseCase.WithRevocationList(revocations))
+	}
+	if keys := readAccessSigningKeys(); len(keys) > 0 {
+		tokenOpts = append(tokenOpts, _tokenUseCase.WithAccessSigningKeys(keys...))
+	}
+	tokenOpts = append(tokenOpts, _tokenUseCase.WithUserRepository(userRepo))
+	tokenUsercase := _tokenUseCase.NewTokensUsecase(tokenRepo, tokenConfig, tokenOpts...)
+
+	// 6、配置 审计日志
+	auditUsecase := _auditUseCase.NewAuditUsecase(auditRepo, timeDuration)
 
-	route := gin.Default()
+	// 不使用 gin.Default: 其 Logger 与 Recovery 不是 JSON 格式, Recovery 还会记录 Cookie
+	route := gin.New()
+
+	// 探针在中间件之前注册: 频繁的探测 不产生访问日志、指标与 span
+	checker := newHealthChecker(mongoDB, sqlDB, redisConn, tokenUsercase)
+	health.NewHandler(route, checker)
+	route.Use(otelgin.Middleware(viper.GetString("tracing.serviceName")), requestid.Middleware(), logging.Middleware(logger), metrics.Middleware(), apierror.Recovery(), apierror.Middleware(errorFormatOptions()...))
 
 	cookieConfig := config.ReadCookieConfig("cookie", "maxage")
-	_userHttpDelivery.NewUsersHandler(route, userUsercase, tokenUsercase, cookieConfig)
+	_userHttpDelivery.NewUsersHandler(route, userUsercase, tokenUsercase, auditUsecase, cookieConfig)
+	_userHttpDelivery.NewJWKSHandler(route, tokenUsercase)
+
+	admin := route.Group("/admin",
+		_userHttpDelivery.MustLoginInterceptor(tokenUsercase, cookieConfig),
+		_userHttpDelivery.MustHaveRoleInterceptor(userUsercase, "admin"),
+	)
+	_auditHttpDelivery.NewAuditHandler(admin, auditUsecase)
+	if webhookUsecase != nil {
+		_webhookHttpDelivery.NewWebhookHandler(admin, webhookUsecase)
+	}
+
+	// 7、其他服务通过 client 包 调用 introspection 校验 token, 未配置凭据时 不开放
+	if secret := viper.GetString("introspection.clientSecret"); secret != "" {
+		oauth := route.Group("/oauth", gin.BasicAuth(gin.Accounts{viper.GetString("introspection.clientID"): secret}))
+		_userHttpDelivery.NewIntrospectionHandler(oauth, userUsercase, tokenUsercase)
+	}
+
+	// 8、内部服务使用的 gRPC 接口, 未配置端口时 不启动
+	var grpcServer *grpc.Server
+	if grpcPort := viper.GetString("grpc.port"); grpcPort != "" {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", grpcPort))
+		if err != nil {
+			logger.Fatal("listen grpc port failed", zap.Error(err))
+		}
+		grpcServer = grpc.NewServer(grpcCredentials(), grpc.ChainUnaryInterceptor(
+			_userGrpcDelivery.UnaryLoggingInterceptor(logger),
+			_userGrpcDelivery.UnaryAuthInterceptor(tokenUsercase),
+		))
+		_userGrpcDelivery.NewIdentityServer(grpcServer, userUsercase, tokenUsercase, auditUsecase)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				logger.Fatal("grpc server stopped", zap.Error(err))
+			}
+		}()
+	}
 
 	port := config.ReadCustomStringConfig("rest.port")
+	metricsServer := newMetricsServer()
+	// 9、优雅退出, 之后等待后台任务退出
+	serve(signalCtx, &http.Server{Addr: fmt.Sprintf(":%s", port), Handler: route}, metricsServer, grpcServer, checker)
+	background.Wait()
+}
+
+// backgroundTasks - 后台任务共用的 ctx, 收到退出信号后结束; Wait 等待所有任务退出
+type backgroundTasks struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+}
+
+// Go - 启动一个后台任务, run 须在 ctx 结束后返回
+func (b *backgroundTasks) Go(run func(ctx context.Context)) {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		run(b.ctx)
+	}()
+}
+
+// Wait - 等待所有后台任务退出
+func (b *backgroundTasks) Wait() {
+	b.wg.Wait()
+}
 
-	route.Run(fmt.Sprintf(":%s", port))
+// setupTracing - tracing.exporter 为 none (默认), stdout 或 otlp; 返回的函数用于退出前导出剩余的 span
+func setupTracing(ctx context.Context) func(context.Context) error {
+	viper.SetDefault("tracing.exporter", tracing.ExporterNone)
+	viper.SetDefault("tracing.serviceName", "go-identity-entry")
+	viper.SetDefault("tracing.endpoint", "localhost:4317")
+	viper.SetDefault("tracing.sampleRatio", 1.0)
+	shutdown, err := tracing.Setup(ctx,
+		tracing.WithExporter(viper.GetString("tracing.exporter")),
+		tracing.WithServiceName(viper.GetString("tracing.serviceName")),
+		tracing.WithOTLPEndpoint(viper.GetString("tracing.endpoint"), viper.GetBool("tracing.insecure")),
+		tracing.WithSampleRatio(viper.GetFloat64("tracing.sampleRatio")),
+	)
+	if err != nil {
+		zap.L().Fatal("setup tracing failed", zap.Error(err))
+	}
+	return shutdown
+}
+
+// newMetricsServer - Prometheus 指标默认关闭; 开启后在单独的 metrics.addr 上提供, 不经过对外的 REST 端口
+func newMetricsServer() *http.Server {
+	viper.SetDefault("metrics.enabled", false)
+	viper.SetDefault("metrics.addr", ":9100")
+	viper.SetDefault("metrics.path", "/metrics")
+	if !viper.GetBool("metrics.enabled") {
+		return nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(viper.GetString("metrics.path"), metrics.Handler())
+	return &http.Server{Addr: viper.GetString("metrics.addr"), Handler: mux}
+}
+
+// errorFormatOptions - errors.problemJSON 为 true 时 总是返回 RFC 7807 problem+json,
+// 否则只在客户端的 Accept 要求时返回
+func errorFormatOptions() []apierror.Option {
+	var opts []apierror.Option
+	if viper.GetBool("errors.problemJSON") {
+		opts = append(opts, apierror.WithProblemJSON())
+	}
+	if base := viper.GetString("errors.typeBase"); base != "" {
+		opts = append(opts, apierror.WithTypeBase(base))
+	}
+	return opts
+}
+
+// readAccessSigningKeys - token.accessKeyFiles 为 PEM 私钥文件列表, 第一个用于签发 AccessToken;
+// 未配置时 AccessToken 使用 token.accessSecret (HMAC) 签名
+func readAccessSigningKeys() []*_tokenUseCase.SigningKey {
+	var keys []*_tokenUseCase.SigningKey
+	for _, path := range viper.GetStringSlice("token.accessKeyFiles") {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			zap.L().Fatal("read access signing key failed", zap.String("path", path), zap.Error(err))
+		}
+		key, err := _tokenUseCase.ParseSigningKeyPEM(data)
+		if err != nil {
+			zap.L().Fatal("parse access signing key failed", zap.String("path", path), zap.Error(err))
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// grpcCredentials - Login 等方法传输密码, 须配置 grpc.tlsCert 与 grpc.tlsKey;
+// 只有显式设置 grpc.insecure (本地开发) 时 才允许明文
+func grpcCredentials() grpc.ServerOption {
+	certFile, keyFile := viper.GetString("grpc.tlsCert"), viper.GetString("grpc.tlsKey")
+	if certFile == "" && keyFile == "" {
+		if !viper.GetBool("grpc.insecure") {
+			zap.L().Fatal("grpc requires grpc.tlsCert and grpc.tlsKey, or grpc.insecure for local development")
+		}
+		zap.L().Warn("grpc serves without TLS: passwords are sent in plaintext")
+		return grpc.Creds(insecure.NewCredentials())
+	}
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	if err != nil {
+		zap.L().Fatal("load grpc TLS certificate failed", zap.Error(err))
+	}
+	return grpc.Creds(creds)
+}
+
+// newPasswordHasher - 根据 password.algorithm 选择新密码使用的哈希算法
+func newPasswordHasher() domain.PasswordHasher {
+	viper.SetDefault("password.algorithm", "bcrypt")
+	var current password.Algorithm
+	switch algorithm := viper.GetString("password.algorithm"); algorithm {
+	case "bcrypt":
+		current = password.NewBcrypt(viper.GetInt("password.bcryptCost"))
+	case "argon2id":
+		current = password.NewArgon2id(
+			viper.GetUint32("password.argon2Memory"),
+			viper.GetUint32("password.argon2Iterations"),
+			uint8(viper.GetUint("password.argon2Parallelism")),
+		)
+	case "scrypt":
+		current = password.NewScrypt(viper.GetInt("password.scryptLogN"), viper.GetInt("password.scryptR"), viper.GetInt("password.scryptP"))
+	default:
+		zap.L().Fatal("unsupported password algorithm", zap.String("algorithm", algorithm))
+	}
+	if err := password.CheckParams(current); err != nil {
+		zap.L().Fatal("invalid password hashing parameters", zap.Error(err))
+	}
+	return password.New(current)
+}
+
+// newPasswordPolicy - 读取 password 段的密码策略配置
+func newPasswordPolicy() domain.PasswordPolicy {
+	viper.SetDefault("password.minLength", 6)
+	// 默认使用整个内置列表
+	viper.SetDefault("password.commonTopN", password.CommonPasswordCount())
+	policyConfig := password.PolicyConfig{
+		MinLength:     viper.GetInt("password.minLength"),
+		MaxLength:     viper.GetInt("password.maxLength"),
+		MaxBytes:      viper.GetInt("password.maxBytes"),
+		RequireLower:  viper.GetBool("password.requireLower"),
+		RequireUpper:  viper.GetBool("password.requireUpper"),
+		RequireDigit:  viper.GetBool("password.requireDigit"),
+		RequireSymbol: viper.GetBool("password.requireSymbol"),
+		CommonTopN:    viper.GetInt("password.commonTopN"),
+	}
+	// 可选: 本地 HIBP 泄露密码文件
+	if path := viper.GetString("password.hibpFile"); path != "" {
+		policyConfig.Breached = password.NewHIBPFile(path, viper.GetInt("password.hibpMinCount"))
+	}
+	// bcrypt 忽略 72 字节之后的内容, 在策略中拒绝, 以便返回 password_policy 而不是哈希失败
+	if viper.GetString("password.algorithm") == "bcrypt" &&
+		(policyConfig.MaxBytes == 0 || policyConfig.MaxBytes > password.BcryptMaxBytes) {
+		policyConfig.MaxBytes = password.BcryptMaxBytes
+	}
+	return password.NewPolicy(policyConfig)
+}
+
+// readPasswordMaxAge - password.maxAgeDays 为 角色 -> 天数
+func readPasswordMaxAge() map[string]time.Duration {
+	var days map[string]int
+	if err := viper.UnmarshalKey("password.maxAgeDays", &days); err != nil {
+		zap.L().Fatal("read password.maxAgeDays failed", zap.Error(err))
+	}
+	maxAge := make(map[string]time.Duration, len(days))
+	for role, d := range days {
+		maxAge[role] = time.Duration(d) * 24 * time.Hour
+	}
+	return maxAge
+}
+
+// purgeDeletedUsers - 定期 删除或匿名化 已过注销宽限期的用户, 直到 ctx 结束
+func purgeDeletedUsers(ctx context.Context, uuc domain.UserUsecase, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n, err := uuc.PurgeDeletedUsersUC(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Error("purge deleted users failed", zap.Error(err))
+			continue
+		}
+		if n > 0 {
+			logging.FromContext(ctx).Info("deleted users purged", zap.Int("count", n))
+		}
+	}
+}
+
+// newLogger - log.level 为 debug, info, warn 或 error; 同时替换全局 logger 与标准库 log 的输出
+func newLogger() *zap.Logger {
+	viper.SetDefault("log.level", "info")
+	logger, err := logging.New(logging.WithLevel(viper.GetString("log.level")))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid log.level: %v\n", err)
+		os.Exit(1)
+	}
+	zap.ReplaceGlobals(logger)
+	zap.RedirectStdLog(logger)
+	return logger
 }
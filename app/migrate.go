@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/alibug/go-identity-entry/migration"
+	_userRepo "github.com/alibug/go-identity-entry/user/repository/mongodb"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+// runMigrations - 启动时执行未执行的迁移, 失败则退出
+func runMigrations(migrator *migration.Migrator) {
+	ctx, cancel := migrationContext()
+	defer cancel()
+
+	done, err := migrator.Up(ctx)
+	if err != nil {
+		zap.L().Fatal("apply migrations failed", zap.Error(err))
+	}
+	if len(done) > 0 {
+		zap.L().Info("migrations applied", zap.Ints("versions", done))
+	}
+}
+
+// checkAccountIndex - 注册依赖 account 唯一索引拦截重复账号; 未执行迁移 (migrate.onStartup 为 false 且未手动执行) 时 拒绝启动
+func checkAccountIndex(mongoDB *mongo.Database) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := _userRepo.CheckAccountIndex(ctx, mongoDB.Collection("users")); err != nil {
+		zap.L().Fatal("check account index failed", zap.Error(err))
+	}
+}
+
+// migrateCommand - server migrate [up | down [n] | status]
+func migrateCommand(migrator *migration.Migrator, args []string) {
+	ctx, cancel := migrationContext()
+	defer cancel()
+
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "up":
+		done, err := migrator.Up(ctx)
+		if err != nil {
+			zap.L().Fatal("apply migrations failed", zap.Error(err))
+		}
+		fmt.Printf("applied: %v\n", done)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				zap.L().Fatal("invalid rollback steps", zap.String("steps", args[1]))
+			}
+			steps = n
+		}
+		done, err := migrator.Down(ctx, steps)
+		if err != nil {
+			zap.L().Fatal("revert migrations failed", zap.Error(err))
+		}
+		fmt.Printf("reverted: %v\n", done)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			zap.L().Fatal("read migration status failed", zap.Error(err))
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-25s  %s\n", s.Version, applied, s.Description)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "usage: %s migrate [up | down [n] | status]\n", os.Args[0])
+		os.Exit(2)
+	}
+}
+
+// migrationContext - 包含等待迁移锁的时间
+func migrationContext() (context.Context, context.CancelFunc) {
+	viper.SetDefault("migrate.timeoutSeconds", 600)
+	return context.WithTimeout(context.Background(), time.Duration(viper.GetInt("migrate.timeoutSeconds"))*time.Second)
+}
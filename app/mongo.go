@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	_eventRepo "github.com/alibug/go-identity-entry/event/repository/mongodb"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/event"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+	"go.uber.org/zap"
+)
+
+// newMongoDatabase - 代替 mongoconn.NewConn: 后者无法设置客户端选项, 此处需要设置 CommandMonitor;
+// 返回 url 中指定的数据库
+func newMongoDatabase(url string, timeout time.Duration, monitors ...*event.CommandMonitor) (*mongo.Database, error) {
+	connString, err := connstring.ParseAndValidate(url)
+	if err != nil {
+		return nil, err
+	}
+	if connString.Database == "" {
+		return nil, errors.New("database name not provided")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(url).SetMonitor(combineMonitors(monitors...)))
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(connString.Database), nil
+}
+
+// combineMonitors - 客户端只能设置一个 CommandMonitor, 依次调用各个 monitor
+func combineMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				if m.Started != nil {
+					m.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				if m.Succeeded != nil {
+					m.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				if m.Failed != nil {
+					m.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
+
+// newTransactor - 让用户数据与领域事件在同一事务中写入, 需要 user.store 为 mongo 且部署为副本集或分片集群.
+// 明确设置 mongo.transactions 为 true 时 不满足条件则拒绝启动; 未设置时 记录警告并退回到事务之外写入事件,
+// 此时用户数据已提交 而事件写入失败, 只记录日志; 设置为 false 时 不使用事务
+func newTransactor(mongoDB *mongo.Database) domain.Transactor {
+	required := viper.GetBool("mongo.transactions")
+	if viper.IsSet("mongo.transactions") && !required {
+		zap.L().Warn("mongo.transactions is false: domain events are written outside the user transaction")
+		return nil
+	}
+
+	var err error
+	if store := viper.GetString("user.store"); store != "mongo" {
+		err = fmt.Errorf("user.store is %q, not mongo", store)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		err = _eventRepo.CheckTransactions(ctx, mongoDB.Client())
+	}
+	if err != nil {
+		if required {
+			zap.L().Fatal("mongo.transactions is true but transactions are unavailable", zap.Error(err))
+		}
+		zap.L().Warn("mongo transactions unavailable: domain events are written outside the user transaction", zap.Error(err))
+		return nil
+	}
+	return _eventRepo.NewMongoTransactor(mongoDB.Client())
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/migration"
+	_tokenBloomRepo "github.com/alibug/go-identity-entry/token/repository/bloom"
+	_tokenMemRepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	_tokenRepo "github.com/alibug/go-identity-entry/token/repository/redisdb"
+	_userMemRepo "github.com/alibug/go-identity-entry/user/repository/memory"
+	_userRepo "github.com/alibug/go-identity-entry/user/repository/mongodb"
+	_userPgRepo "github.com/alibug/go-identity-entry/user/repository/postgres"
+	_userSQLiteRepo "github.com/alibug/go-identity-entry/user/repository/sqlite"
+	"github.com/alibug/go-identity-utils/redisconn"
+	"github.com/go-redis/redis/v8"
+	_ "github.com/lib/pq"
+	"github.com/spf13/viper"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite"
+)
+
+// newUserStore - 根据 user.store 选择用户存储, 返回仓库及其迁移; 内存存储没有迁移,
+// 使用 postgres 或 sqlite 时 同时返回 *sql.DB, 用于健康检查与退出时关闭
+func newUserStore(mongoDB *mongo.Database, normalizer domain.AccountNormalizer) (domain.UserRepository, *migration.Migrator, *sql.DB) {
+	switch store := viper.GetString("user.store"); store {
+	case "mongo":
+		if mongoDB == nil {
+			zap.L().Fatal("user.store mongo requires mongo.enabled")
+		}
+		migrator := migration.NewMigrator(migration.NewMongoStore(mongoDB, 5*time.Minute), _userRepo.Migrations(mongoDB, normalizer))
+		return _userRepo.NewMongoUserRepository(mongoDB.Collection("users")), migrator, nil
+	case "postgres":
+		db, err := sql.Open("postgres", viper.GetString("postgres.dsn"))
+		if err != nil {
+			zap.L().Fatal("open postgres failed", zap.Error(err))
+		}
+		if err := db.Ping(); err != nil {
+			zap.L().Fatal("connect postgres failed", zap.Error(err))
+		}
+		db.SetMaxOpenConns(viper.GetInt("postgres.maxOpenConns"))
+		migrator := migration.NewMigrator(migration.NewPostgresStore(db), _userPgRepo.Migrations(db))
+		return _userPgRepo.NewPostgresUserRepository(db), migrator, db
+	case "sqlite":
+		// 嵌入式存储, 用于本地开发
+		viper.SetDefault("sqlite.path", "identity.db")
+		db, err := _userSQLiteRepo.Open(viper.GetString("sqlite.path"))
+		if err != nil {
+			zap.L().Fatal("open sqlite failed", zap.Error(err))
+		}
+		migrator := migration.NewMigrator(migration.NewSQLiteStore(db), _userSQLiteRepo.Migrations(db))
+		return _userSQLiteRepo.NewSQLiteUserRepository(db), migrator, db
+	case "memory":
+		zap.L().Warn("users are kept in memory and lost on restart")
+		return _userMemRepo.NewMemoryUserRepository(), nil, nil
+	default:
+		zap.L().Fatal("unsupported user store", zap.String("store", store))
+		return nil, nil, nil
+	}
+}
+
+// newTokensRepository - 根据 token.store 选择 token 存储
+func newTokensRepository(client redis.UniversalClient) domain.TokensRepository {
+	switch store := viper.GetString("token.store"); store {
+	case "redis":
+		if client == nil {
+			zap.L().Fatal("token.store redis requires redis.enabled")
+		}
+		viper.SetDefault("redis.legacyTokenKeys", true)
+		viper.SetDefault("redis.tokenKeyPrefix", _tokenRepo.DefaultKeyPrefix)
+		return _tokenRepo.NewRedisTokensRepository(client,
+			_tokenRepo.WithKeyPrefix(viper.GetString("redis.tokenKeyPrefix")),
+			_tokenRepo.WithLegacyKeys(viper.GetBool("redis.legacyTokenKeys")),
+		)
+	case "memory":
+		zap.L().Warn("tokens are kept in memory, all users must log in again after a restart")
+		return _tokenMemRepo.NewMemoryTokensRepository()
+	default:
+		zap.L().Fatal("unsupported token store", zap.String("store", store))
+		return nil
+	}
+}
+
+// newRevocationList - token.statelessAccess 为 true 时 AccessToken 只校验签名与已撤销列表;
+// Redis 中的列表 在进程内用 bloom filter 缓存, 通过 pub/sub 与其他实例同步
+func newRevocationList(background *backgroundTasks, client redis.UniversalClient) domain.RevocationList {
+	viper.SetDefault("token.statelessAccess", false)
+	if !viper.GetBool("token.statelessAccess") {
+		return nil
+	}
+	switch store := viper.GetString("token.store"); store {
+	case "redis":
+		viper.SetDefault("token.revocation.expectedItems", 100000)
+		viper.SetDefault("token.revocation.falsePositiveRate", 0.001)
+		viper.SetDefault("token.revocation.rebuildSeconds", 300)
+		list := _tokenBloomRepo.NewBloomRevocationList(
+			_tokenRepo.NewRedisRevocationList(client, viper.GetString("redis.tokenKeyPrefix")),
+			_tokenBloomRepo.WithExpectedItems(viper.GetInt("token.revocation.expectedItems")),
+			_tokenBloomRepo.WithFalsePositiveRate(viper.GetFloat64("token.revocation.falsePositiveRate")),
+			_tokenBloomRepo.WithRebuildInterval(time.Duration(viper.GetInt("token.revocation.rebuildSeconds"))*time.Second),
+		)
+		background.Go(list.Run)
+		return list
+	default:
+		return _tokenMemRepo.NewMemoryRevocationList()
+	}
+}
+
+// newRedisClient - 根据 redis.mode 连接 单机, Sentinel 或 Cluster
+func newRedisClient() redis.UniversalClient {
+	viper.SetDefault("redis.mode", "standalone")
+	switch mode := viper.GetString("redis.mode"); mode {
+	case "standalone":
+		return redisconn.NewConnFromConfig("redis")
+	case "sentinel":
+		return redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:       viper.GetString("redis.masterName"),
+			SentinelAddrs:    viper.GetStringSlice("redis.addrs"),
+			SentinelPassword: viper.GetString("redis.sentinelPass"),
+			Password:         viper.GetString("redis.pass"),
+			DB:               viper.GetInt("redis.database"),
+		})
+	case "cluster":
+		// Cluster 只有 0 号库
+		return redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:    viper.GetStringSlice("redis.addrs"),
+			Password: viper.GetString("redis.pass"),
+		})
+	default:
+		zap.L().Fatal("unsupported redis mode", zap.String("mode", mode))
+		return nil
+	}
+}
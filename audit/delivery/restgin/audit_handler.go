@@ -0,0 +1,54 @@
+package restgin
+
+import (
+	"net/http"
+
+	"github.com/alibug/go-identity-entry/apierror"
+	"github.com/alibug/go-identity-entry/audit/repository/body"
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// AuditHandler represent the httphandler for audit events
+type AuditHandler struct {
+	auditUsecase domain.AuditUsecase
+}
+
+// NewAuditHandler - 在给定的 (已鉴权的) 路由组上 注册审计查询接口
+func NewAuditHandler(route gin.IRoutes, auc domain.AuditUsecase) {
+	handler := &AuditHandler{
+		auditUsecase: auc,
+	}
+
+	route.GET("/audit", handler.Query)
+}
+
+// Query - 按条件分页查询审计记录
+func (a *AuditHandler) Query(c *gin.Context) {
+	var query body.QueryBody
+	if err := c.ShouldBindQuery(&query); err != nil {
+		apierror.Respond(c, apierror.Binding(err))
+		return
+	}
+	if query.Page == 0 {
+		query.Page = 1
+	}
+	if query.Limit == 0 {
+		query.Limit = 20
+	}
+
+	ctx := c.Request.Context()
+	res, err := a.auditUsecase.QueryUC(ctx, query.ToFilter(), query.Page, query.Limit)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	// 返回实际使用的 limit, 超过上限时由 usecase 修正
+	c.JSON(http.StatusOK, gin.H{
+		"items": res.Items,
+		"total": res.Total,
+		"page":  res.Page,
+		"limit": res.Limit,
+	})
+}
@@ -0,0 +1,146 @@
+package restgin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/apierror"
+	"github.com/alibug/go-identity-entry/audit/delivery/restgin"
+	"github.com/alibug/go-identity-entry/audit/repository/body"
+	memrepo "github.com/alibug/go-identity-entry/audit/repository/memory"
+	"github.com/alibug/go-identity-entry/audit/usecase"
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/gin-gonic/gin"
+)
+
+var base = time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+// newEngine - 审计接口挂在 /admin 下, 鉴权由调用方的路由组负责, 此处省略
+func newEngine(t *testing.T) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	auc := usecase.NewAuditUsecase(memrepo.NewMemoryAuditRepository(), time.Second)
+	ctx := context.Background()
+	for i := 0; i < 5; i++ {
+		auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogin, Outcome: domain.AuditSuccess, UserID: "u1", Timestamp: base.Add(time.Duration(i) * time.Hour)})
+	}
+	auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogin, Outcome: domain.AuditFailure, Account: "alice", IP: "203.0.113.7", Timestamp: base})
+	auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogout, Outcome: domain.AuditSuccess, UserID: "u2", Timestamp: base})
+
+	engine := gin.New()
+	engine.Use(apierror.Middleware())
+	restgin.NewAuditHandler(engine.Group("/admin"), auc)
+	return engine
+}
+
+type queryResponse struct {
+	Items []body.EventBody `json:"items"`
+	Total int64            `json:"total"`
+	Page  int64            `json:"page"`
+	Limit int64            `json:"limit"`
+}
+
+func query(t *testing.T, engine *gin.Engine, params url.Values) (int, queryResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/audit?"+params.Encode(), nil))
+	var resp queryResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code, resp
+}
+
+func TestQueryFilters(t *testing.T) {
+	engine := newEngine(t)
+	tests := []struct {
+		name   string
+		params url.Values
+		total  int64
+	}{
+		{"All", url.Values{}, 7},
+		{"UserID", url.Values{"user_id": {"u1"}}, 5},
+		{"Account", url.Values{"account": {"alice"}}, 1},
+		{"Type", url.Values{"type": {"logout"}}, 1},
+		{"Outcome", url.Values{"outcome": {"failure"}}, 1},
+		{"TimeRange", url.Values{"user_id": {"u1"}, "from": {base.Add(time.Hour).Format(time.RFC3339)}, "to": {base.Add(3 * time.Hour).Format(time.RFC3339)}}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, resp := query(t, engine, tt.params)
+			if code != http.StatusOK {
+				t.Fatalf("status %d", code)
+			}
+			if resp.Total != tt.total || int64(len(resp.Items)) != tt.total {
+				t.Fatalf("items %d, total %d, want %d", len(resp.Items), resp.Total, tt.total)
+			}
+		})
+	}
+
+	// 记录中的客户端信息 原样返回
+	_, resp := query(t, engine, url.Values{"outcome": {"failure"}})
+	if e := resp.Items[0]; e.Account != "alice" || e.IP != "203.0.113.7" || e.Type != domain.AuditLogin {
+		t.Fatalf("failure event = %+v", e)
+	}
+}
+
+func TestQueryPagination(t *testing.T) {
+	engine := newEngine(t)
+
+	// 缺省 page 1, limit 20
+	_, resp := query(t, engine, url.Values{})
+	if resp.Page != 1 || resp.Limit != 20 {
+		t.Fatalf("defaults = page %d, limit %d", resp.Page, resp.Limit)
+	}
+
+	// 按时间倒序分页
+	var items []body.EventBody
+	for page := 1; page <= 3; page++ {
+		code, resp := query(t, engine, url.Values{"user_id": {"u1"}, "page": {strconv.Itoa(page)}, "limit": {"2"}})
+		if code != http.StatusOK || resp.Total != 5 || resp.Page != int64(page) || resp.Limit != 2 {
+			t.Fatalf("page %d: status %d, %+v", page, code, resp)
+		}
+		items = append(items, resp.Items...)
+	}
+	if len(items) != 5 {
+		t.Fatalf("items across pages = %d, want 5", len(items))
+	}
+	for i, e := range items {
+		if want := base.Add(time.Duration(4-i) * time.Hour); !e.Timestamp.Equal(want) {
+			t.Fatalf("item %d at %v, want %v", i, e.Timestamp, want)
+		}
+	}
+}
+
+// limit 超过上限时 按上限返回, 响应中的 limit 为实际值, 以便客户端正确翻页
+func TestQueryClampsLimit(t *testing.T) {
+	engine := newEngine(t)
+	code, resp := query(t, engine, url.Values{"limit": {"500"}})
+	if code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if resp.Limit != 100 || resp.Page != 1 || resp.Total != 7 || len(resp.Items) != 7 {
+		t.Fatalf("response = page %d, limit %d, total %d, %d items; want limit 100", resp.Page, resp.Limit, resp.Total, len(resp.Items))
+	}
+}
+
+func TestQueryRejectsInvalidParams(t *testing.T) {
+	engine := newEngine(t)
+	for _, params := range []url.Values{
+		{"outcome": {"maybe"}},
+		{"page": {"-1"}},
+		{"limit": {"-5"}},
+		{"from": {"yesterday"}},
+	} {
+		if code, _ := query(t, engine, params); code != http.StatusBadRequest {
+			t.Errorf("%v: status %d, want 400", params, code)
+		}
+	}
+}
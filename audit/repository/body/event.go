@@ -0,0 +1,51 @@
+package body
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-utils/converter"
+)
+
+// EventBody - implement domain.AuditEvent
+type EventBody struct {
+	ID        converter.StrToObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Type      domain.AuditEventType   `bson:"type" json:"type"`
+	Outcome   domain.AuditOutcome     `bson:"outcome" json:"outcome"`
+	Reason    string                  `bson:"reason,omitempty" json:"reason,omitempty"`
+	UserID    string                  `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Account   string                  `bson:"account,omitempty" json:"account,omitempty"`
+	IP        string                  `bson:"ip,omitempty" json:"ip,omitempty"`
+	UserAgent string                  `bson:"user_agent,omitempty" json:"user_agent,omitempty"`
+	Timestamp time.Time               `bson:"timestamp" json:"timestamp"`
+}
+
+// GetType - implement domain.AuditEvent
+func (e *EventBody) GetType() domain.AuditEventType {
+	return e.Type
+}
+
+// GetOutcome - implement domain.AuditEvent
+func (e *EventBody) GetOutcome() domain.AuditOutcome {
+	return e.Outcome
+}
+
+// GetUserID - implement domain.AuditEvent
+func (e *EventBody) GetUserID() string {
+	return e.UserID
+}
+
+// GetAccount - implement domain.AuditEvent
+func (e *EventBody) GetAccount() string {
+	return e.Account
+}
+
+// GetTimestamp - implement domain.AuditEvent
+func (e *EventBody) GetTimestamp() time.Time {
+	return e.Timestamp
+}
+
+// SetTimestamp - implement domain.AuditEvent
+func (e *EventBody) SetTimestamp(t time.Time) {
+	e.Timestamp = t
+}
@@ -0,0 +1,31 @@
+package body
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// QueryBody - 审计记录查询参数
+type QueryBody struct {
+	UserID  string     `form:"user_id"`
+	Account string     `form:"account"`
+	Type    string     `form:"type"`
+	Outcome string     `form:"outcome" binding:"omitempty,oneof=success failure"`
+	From    *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00"`
+	To      *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00"`
+	Page    int64      `form:"page" binding:"omitempty,gte=1"`
+	Limit   int64      `form:"limit" binding:"omitempty,gte=1"`
+}
+
+// ToFilter - 转换为 domain.AuditFilter
+func (q *QueryBody) ToFilter() domain.AuditFilter {
+	return domain.AuditFilter{
+		UserID:  q.UserID,
+		Account: q.Account,
+		Type:    domain.AuditEventType(q.Type),
+		Outcome: domain.AuditOutcome(q.Outcome),
+		From:    q.From,
+		To:      q.To,
+	}
+}
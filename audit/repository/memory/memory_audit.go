@@ -0,0 +1,65 @@
+package memrepo
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// memoryAuditRepository - 仅用于本地开发和测试, 数据不持久化
+type memoryAuditRepository struct {
+	mu     sync.RWMutex
+	events []domain.AuditEvent
+}
+
+// NewMemoryAuditRepository will create an object that represent the domain.AuditRepository interface
+func NewMemoryAuditRepository() domain.AuditRepository {
+	return &memoryAuditRepository{}
+}
+
+func (m *memoryAuditRepository) Insert(ctx context.Context, event domain.AuditEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+// Find - 与 Mongo 一致 按时间倒序
+func (m *memoryAuditRepository) Find(ctx context.Context, filter domain.AuditFilter, skip int64, limit int64) ([]domain.AuditEvent, error) {
+	matched := m.match(filter)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return matched[i].GetTimestamp().After(matched[j].GetTimestamp())
+	})
+
+	events := make([]domain.AuditEvent, 0)
+	for i := skip; i < int64(len(matched)) && (limit <= 0 || i < skip+limit); i++ {
+		events = append(events, matched[i])
+	}
+	return events, nil
+}
+
+func (m *memoryAuditRepository) Count(ctx context.Context, filter domain.AuditFilter) (int64, error) {
+	return int64(len(m.match(filter))), nil
+}
+
+func (m *memoryAuditRepository) match(filter domain.AuditFilter) []domain.AuditEvent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matched []domain.AuditEvent
+	for _, e := range m.events {
+		switch {
+		case filter.UserID != "" && e.GetUserID() != filter.UserID,
+			filter.Account != "" && e.GetAccount() != filter.Account,
+			filter.Type != "" && e.GetType() != filter.Type,
+			filter.Outcome != "" && e.GetOutcome() != filter.Outcome,
+			filter.From != nil && e.GetTimestamp().Before(*filter.From),
+			filter.To != nil && e.GetTimestamp().After(*filter.To):
+			continue
+		}
+		matched = append(matched, e)
+	}
+	return matched
+}
@@ -0,0 +1,78 @@
+package mongorepo
+
+import (
+	"context"
+
+	"github.com/alibug/go-identity-entry/audit/repository/body"
+	"github.com/alibug/go-identity-entry/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoAuditRepository struct {
+	auditColl *mongo.Collection
+}
+
+// NewMongoAuditRepository will create an object that represent the domain.AuditRepository interface
+func NewMongoAuditRepository(coll *mongo.Collection) domain.AuditRepository {
+	return &mongoAuditRepository{coll}
+}
+
+func (m *mongoAuditRepository) Insert(ctx context.Context, event domain.AuditEvent) error {
+	_, err := m.auditColl.InsertOne(ctx, event)
+	return err
+}
+
+func (m *mongoAuditRepository) Find(ctx context.Context, filter domain.AuditFilter, skip int64, limit int64) ([]domain.AuditEvent, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "timestamp", Value: -1}}).
+		SetSkip(skip).
+		SetLimit(limit)
+	cur, err := m.auditColl.Find(ctx, toBsonFilter(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	events := make([]domain.AuditEvent, 0)
+	for cur.Next(ctx) {
+		var e body.EventBody
+		if err := cur.Decode(&e); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, cur.Err()
+}
+
+func (m *mongoAuditRepository) Count(ctx context.Context, filter domain.AuditFilter) (int64, error) {
+	return m.auditColl.CountDocuments(ctx, toBsonFilter(filter))
+}
+
+func toBsonFilter(filter domain.AuditFilter) bson.M {
+	f := bson.M{}
+	if filter.UserID != "" {
+		f["user_id"] = filter.UserID
+	}
+	if filter.Account != "" {
+		f["account"] = filter.Account
+	}
+	if filter.Type != "" {
+		f["type"] = filter.Type
+	}
+	if filter.Outcome != "" {
+		f["outcome"] = filter.Outcome
+	}
+	if filter.From != nil || filter.To != nil {
+		ts := bson.M{}
+		if filter.From != nil {
+			ts["$gte"] = *filter.From
+		}
+		if filter.To != nil {
+			ts["$lte"] = *filter.To
+		}
+		f["timestamp"] = ts
+	}
+	return f
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"go.uber.org/zap"
+)
+
+// maxLimit - 分页查询 每页最大条数
+const maxLimit = 100
+
+type auditUsecase struct {
+	auditRepo      domain.AuditRepository
+	contextTimeout time.Duration
+}
+
+// NewAuditUsecase will create new an auditUsecase object representation of domain.AuditUsecase interface
+func NewAuditUsecase(repo domain.AuditRepository, timeout time.Duration) domain.AuditUsecase {
+	return &auditUsecase{
+		auditRepo:      repo,
+		contextTimeout: timeout,
+	}
+}
+
+func (a *auditUsecase) RecordUC(c context.Context, event domain.AuditEvent) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if event.GetTimestamp().IsZero() {
+		event.SetTimestamp(time.Now())
+	}
+	// 审计记录失败 不应影响登录等业务, 只记录日志
+	if err := a.auditRepo.Insert(ctx, event); err != nil {
+		logging.FromContext(ctx).Error("record audit event failed",
+			zap.String("type", string(event.GetType())), zap.String("outcome", string(event.GetOutcome())), zap.Error(err))
+	}
+}
+
+func (a *auditUsecase) QueryUC(c context.Context, filter domain.AuditFilter, page int64, limit int64) (domain.AuditPage, error) {
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > maxLimit {
+		limit = maxLimit
+	}
+
+	total, err := a.auditRepo.Count(ctx, filter)
+	if err != nil {
+		return domain.AuditPage{}, err
+	}
+	events, err := a.auditRepo.Find(ctx, filter, (page-1)*limit, limit)
+	if err != nil {
+		return domain.AuditPage{}, err
+	}
+	return domain.AuditPage{Items: events, Total: total, Page: page, Limit: limit}, nil
+}
@@ -0,0 +1,128 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/audit/repository/body"
+	memrepo "github.com/alibug/go-identity-entry/audit/repository/memory"
+	"github.com/alibug/go-identity-entry/audit/usecase"
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// failingRepo - 写入总是失败
+type failingRepo struct {
+	domain.AuditRepository
+}
+
+func (failingRepo) Insert(ctx context.Context, event domain.AuditEvent) error {
+	return errors.New("audit store unavailable")
+}
+
+func TestRecordUC(t *testing.T) {
+	repo := memrepo.NewMemoryAuditRepository()
+	auc := usecase.NewAuditUsecase(repo, time.Second)
+	ctx := context.Background()
+
+	// 未设置时间时 使用当前时间, 已设置的保持不变
+	before := time.Now()
+	auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogin, Outcome: domain.AuditSuccess, UserID: "u1"})
+	at := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogout, Outcome: domain.AuditSuccess, UserID: "u1", Timestamp: at})
+
+	events, err := repo.Find(ctx, domain.AuditFilter{}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(events))
+	}
+	if ts := events[0].GetTimestamp(); events[0].GetType() != domain.AuditLogin || ts.Before(before) || ts.After(time.Now()) {
+		t.Fatalf("login event = %v at %v", events[0].GetType(), ts)
+	}
+	if events[1].GetType() != domain.AuditLogout || !events[1].GetTimestamp().Equal(at) {
+		t.Fatalf("logout event = %v at %v", events[1].GetType(), events[1].GetTimestamp())
+	}
+
+	// 写入失败 不影响调用方
+	usecase.NewAuditUsecase(failingRepo{}, time.Second).RecordUC(ctx, &body.EventBody{Type: domain.AuditLogin, Outcome: domain.AuditFailure})
+}
+
+func TestQueryUC(t *testing.T) {
+	repo := memrepo.NewMemoryAuditRepository()
+	auc := usecase.NewAuditUsecase(repo, time.Second)
+	ctx := context.Background()
+
+	base := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogin, Outcome: domain.AuditSuccess, UserID: "u1", Timestamp: base.Add(time.Duration(i) * time.Hour)})
+	}
+	auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogin, Outcome: domain.AuditFailure, Account: "alice", Timestamp: base.Add(30 * time.Minute)})
+	auc.RecordUC(ctx, &body.EventBody{Type: domain.AuditLogout, Outcome: domain.AuditSuccess, UserID: "u2", Timestamp: base.Add(2 * time.Hour)})
+
+	from, to := base.Add(time.Hour), base.Add(3*time.Hour)
+	tests := []struct {
+		name   string
+		filter domain.AuditFilter
+		total  int64
+	}{
+		{"All", domain.AuditFilter{}, 7},
+		{"UserID", domain.AuditFilter{UserID: "u1"}, 5},
+		{"Account", domain.AuditFilter{Account: "alice"}, 1},
+		{"Type", domain.AuditFilter{Type: domain.AuditLogout}, 1},
+		{"Outcome", domain.AuditFilter{Outcome: domain.AuditFailure}, 1},
+		{"TypeAndOutcome", domain.AuditFilter{Type: domain.AuditLogin, Outcome: domain.AuditSuccess}, 5},
+		// From 与 To 均包含边界
+		{"TimeRange", domain.AuditFilter{UserID: "u1", From: &from, To: &to}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := auc.QueryUC(ctx, tt.filter, 1, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Total != tt.total || int64(len(res.Items)) != tt.total {
+				t.Fatalf("QueryUC = %d events, total %d, want %d", len(res.Items), res.Total, tt.total)
+			}
+		})
+	}
+
+	// 分页按时间倒序, total 为全部匹配的条数
+	filter := domain.AuditFilter{UserID: "u1"}
+	var pages [][]domain.AuditEvent
+	for page := int64(1); page <= 3; page++ {
+		res, err := auc.QueryUC(ctx, filter, page, 2)
+		if err != nil || res.Total != 5 {
+			t.Fatalf("page %d: total %d, err %v", page, res.Total, err)
+		}
+		pages = append(pages, res.Items)
+	}
+	if len(pages[0]) != 2 || len(pages[1]) != 2 || len(pages[2]) != 1 {
+		t.Fatalf("page sizes = %d, %d, %d, want 2, 2, 1", len(pages[0]), len(pages[1]), len(pages[2]))
+	}
+	var previous time.Time
+	for i, page := range pages {
+		for _, e := range page {
+			if !previous.IsZero() && !e.GetTimestamp().Before(previous) {
+				t.Fatalf("page %d not in descending order: %v after %v", i+1, e.GetTimestamp(), previous)
+			}
+			previous = e.GetTimestamp()
+		}
+	}
+
+	// 超出范围的 page 与 limit 被修正
+	res, err := auc.QueryUC(ctx, filter, 0, 0)
+	if err != nil || len(res.Items) != 5 || res.Page != 1 || res.Limit != 100 {
+		t.Fatalf("QueryUC(page 0, limit 0) = %d events, page %d, limit %d, %v, want 5 on page 1 of 100", len(res.Items), res.Page, res.Limit, err)
+	}
+	res, err = auc.QueryUC(ctx, filter, 1, 500)
+	if err != nil || res.Limit != 100 {
+		t.Fatalf("QueryUC(limit 500) limit = %d, %v, want 100", res.Limit, err)
+	}
+	res, err = auc.QueryUC(ctx, filter, 4, 2)
+	if err != nil || len(res.Items) != 0 {
+		t.Fatalf("QueryUC(past last page) = %d events, %v, want 0", len(res.Items), err)
+	}
+}
@@ -0,0 +1,39 @@
+// Package ginclient - client 包的 gin 中间件
+package ginclient
+
+import (
+	"github.com/alibug/go-identity-entry/client"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/gin-gonic/gin"
+)
+
+// PrincipalKey - Middleware 将 Principal 写入 gin.Context 的键
+const PrincipalKey = "principal"
+
+// Middleware - 校验 access token, 并将 Principal 写入 gin.Context 与 c.Request.Context()
+func Middleware(v client.Verifier, opts ...client.Option) gin.HandlerFunc {
+	a := client.NewAuthenticator(v, opts...)
+	return func(c *gin.Context) {
+		p, err := a.Authenticate(c.Request)
+		if err != nil {
+			if challenge := client.Challenge(err); challenge != "" {
+				c.Header("WWW-Authenticate", challenge)
+			}
+			c.AbortWithStatusJSON(client.StatusCode(err), status.ResponseError{Message: client.ErrorMessage(err)})
+			return
+		}
+		c.Set(PrincipalKey, p)
+		c.Request = c.Request.WithContext(client.WithPrincipal(c.Request.Context(), p))
+		c.Next()
+	}
+}
+
+// PrincipalFrom - 取出 Middleware 写入的 Principal
+func PrincipalFrom(c *gin.Context) (*client.Principal, bool) {
+	p, ok := c.Get(PrincipalKey)
+	if !ok {
+		return nil, false
+	}
+	principal, ok := p.(*client.Principal)
+	return principal, ok
+}
@@ -0,0 +1,50 @@
+package ginclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/client"
+	"github.com/alibug/go-identity-entry/client/ginclient"
+	"github.com/gin-gonic/gin"
+)
+
+type staticVerifier map[string]*client.Principal
+
+func (s staticVerifier) Verify(ctx context.Context, token string) (*client.Principal, error) {
+	if p, ok := s[token]; ok {
+		return p, nil
+	}
+	return nil, client.ErrInvalidToken
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	verifier := staticVerifier{"admin": {Subject: "u1", Roles: []string{"admin"}}}
+	engine.GET("/admin", ginclient.Middleware(verifier, client.RequireRoles("admin")), func(c *gin.Context) {
+		p, ok := ginclient.PrincipalFrom(c)
+		if fromCtx, _ := client.PrincipalFrom(c.Request.Context()); !ok || fromCtx != p {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.String(http.StatusOK, p.Subject)
+	})
+
+	for token, code := range map[string]int{"admin": http.StatusOK, "nope": http.StatusUnauthorized, "": http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != code {
+			t.Fatalf("%q: status = %d, want %d, body %s", token, w.Code, code, w.Body)
+		}
+		if code == http.StatusOK && w.Body.String() != "u1" {
+			t.Fatalf("%q: body = %s", token, w.Body)
+		}
+	}
+}
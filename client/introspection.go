@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IntrospectionVerifier - 通过签发方的 introspection 接口 (RFC 7662) 校验 token,
+// 撤销即时生效; 结果按 token 缓存, 以减少请求
+type IntrospectionVerifier struct {
+	endpoint     string
+	httpClient   *http.Client
+	clientID     string
+	clientSecret string
+	cacheTTL     time.Duration
+	negativeTTL  time.Duration
+	maxEntries   int
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]cacheEntry
+}
+
+type cacheEntry struct {
+	principal *Principal
+	expires   time.Time
+}
+
+// IntrospectionOption - IntrospectionVerifier 可选配置
+type IntrospectionOption func(*IntrospectionVerifier)
+
+// WithIntrospectionHTTPClient - 调用 introspection 接口使用的 http.Client, 默认超时 5 秒
+func WithIntrospectionHTTPClient(c *http.Client) IntrospectionOption {
+	return func(v *IntrospectionVerifier) {
+		v.httpClient = c
+	}
+}
+
+// WithClientCredentials - 以 HTTP Basic 方式 向 introspection 接口认证
+func WithClientCredentials(id string, secret string) IntrospectionOption {
+	return func(v *IntrospectionVerifier) {
+		v.clientID, v.clientSecret = id, secret
+	}
+}
+
+// WithResultCacheTTL - 有效 token 的缓存时间 (不超过 token 的过期时间), 默认 30 秒, 即撤销最多延迟 30 秒生效;
+// 无效 token 的缓存时间, 默认 10 秒; 为 0 时不缓存
+func WithResultCacheTTL(active time.Duration, inactive time.Duration) IntrospectionOption {
+	return func(v *IntrospectionVerifier) {
+		v.cacheTTL, v.negativeTTL = active, inactive
+	}
+}
+
+// NewIntrospectionVerifier - endpoint 为签发方的 introspection 地址
+func NewIntrospectionVerifier(endpoint string, opts ...IntrospectionOption) *IntrospectionVerifier {
+	v := &IntrospectionVerifier{
+		endpoint:    endpoint,
+		httpClient:  &http.Client{Timeout: 5 * time.Second},
+		cacheTTL:    30 * time.Second,
+		negativeTTL: 10 * time.Second,
+		maxEntries:  10000,
+		cache:       map[[sha256.Size]byte]cacheEntry{},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// introspectionResponse - RFC 7662 的响应, roles 为本服务的扩展
+type introspectionResponse struct {
+	Active bool     `json:"active"`
+	Sub    string   `json:"sub"`
+	Scope  string   `json:"scope"`
+	Jti    string   `json:"jti"`
+	Exp    int64    `json:"exp"`
+	Roles  []string `json:"roles"`
+}
+
+// Verify - implement Verifier interface
+func (v *IntrospectionVerifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	// 缓存以哈希为键, 不在内存中保留 token 原文
+	key := sha256.Sum256([]byte(token))
+	if p, ok := v.cached(key); ok {
+		if p == nil {
+			return nil, ErrInvalidToken
+		}
+		return p, nil
+	}
+
+	p, err := v.introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	v.store(key, p)
+	if p == nil {
+		return nil, ErrInvalidToken
+	}
+	return p, nil
+}
+
+// introspect - token 无效时 返回 nil, nil
+func (v *IntrospectionVerifier) introspect(ctx context.Context, token string) (*Principal, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if v.clientID != "" {
+		req.SetBasicAuth(v.clientID, v.clientSecret)
+	}
+
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("introspect token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspect token: %s", resp.Status)
+	}
+	var body introspectionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode introspection response: %w", err)
+	}
+	if !body.Active || body.Sub == "" {
+		return nil, nil
+	}
+
+	p := &Principal{Subject: body.Sub, TokenID: body.Jti, Scopes: strings.Fields(body.Scope), Roles: body.Roles}
+	if body.Exp > 0 {
+		p.ExpiresAt = time.Unix(body.Exp, 0)
+	}
+	return p, nil
+}
+
+// cached - 第二个返回值表示命中缓存, 命中时 principal 为 nil 表示 token 无效
+func (v *IntrospectionVerifier) cached(key [sha256.Size]byte) (*Principal, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	e, ok := v.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(e.expires) {
+		delete(v.cache, key)
+		return nil, false
+	}
+	return e.principal, true
+}
+
+func (v *IntrospectionVerifier) store(key [sha256.Size]byte, p *Principal) {
+	now := time.Now()
+	ttl := v.negativeTTL
+	if p != nil {
+		ttl = v.cacheTTL
+		if !p.ExpiresAt.IsZero() && p.ExpiresAt.Sub(now) < ttl {
+			ttl = p.ExpiresAt.Sub(now)
+		}
+	}
+	if ttl <= 0 {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// 超出上限时 先清理过期的, 仍超出则清空
+	if len(v.cache) >= v.maxEntries {
+		for k, e := range v.cache {
+			if !now.Before(e.expires) {
+				delete(v.cache, k)
+			}
+		}
+		if len(v.cache) >= v.maxEntries {
+			v.cache = map[[sha256.Size]byte]cacheEntry{}
+		}
+	}
+	v.cache[key] = cacheEntry{principal: p, expires: now.Add(ttl)}
+}
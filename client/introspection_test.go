@@ -0,0 +1,85 @@
+package client_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/client"
+)
+
+func TestIntrospectionVerifier(t *testing.T) {
+	var calls int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		if id, secret, ok := r.BasicAuth(); !ok || id != "svc" || secret != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.PostFormValue("token") {
+		case "good":
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"active": true, "sub": "u1", "scope": "a b", "jti": "t1++u1",
+				"exp": time.Now().Add(time.Hour).Unix(), "roles": []string{"admin"},
+			})
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"active": false})
+		}
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	v := client.NewIntrospectionVerifier(srv.URL, client.WithClientCredentials("svc", "s3cret"))
+
+	for i := 0; i < 3; i++ {
+		p, err := v.Verify(ctx, "good")
+		if err != nil {
+			t.Fatalf("Verify: %v", err)
+		}
+		if p.Subject != "u1" || !p.HasScope("b") || !p.HasRole("admin") || p.ExpiresAt.IsZero() {
+			t.Fatalf("principal = %+v", p)
+		}
+		if _, err := v.Verify(ctx, "bad"); !errors.Is(err, client.ErrInvalidToken) {
+			t.Fatalf("Verify(bad) err = %v, want ErrInvalidToken", err)
+		}
+	}
+	// 有效与无效的结果 均被缓存
+	if n := atomic.LoadInt64(&calls); n != 2 {
+		t.Fatalf("introspection called %d times, want 2", n)
+	}
+
+	// 凭据错误 不是 token 无效
+	wrong := client.NewIntrospectionVerifier(srv.URL, client.WithClientCredentials("svc", "wrong"))
+	if _, err := wrong.Verify(ctx, "good"); err == nil || errors.Is(err, client.ErrInvalidToken) {
+		t.Fatalf("Verify(wrong credentials) err = %v", err)
+	}
+}
+
+func TestIntrospectionVerifierCacheBoundedByExpiry(t *testing.T) {
+	var calls int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&calls, 1)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"active": true, "sub": "u1", "exp": time.Now().Add(time.Second).Unix(),
+		})
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+	v := client.NewIntrospectionVerifier(srv.URL, client.WithResultCacheTTL(time.Hour, time.Hour))
+
+	if _, err := v.Verify(ctx, "short-lived"); err != nil {
+		t.Fatal(err)
+	}
+	// token 过期后 缓存不再有效
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := v.Verify(ctx, "short-lived"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&calls); n != 2 {
+		t.Fatalf("introspection called %d times, want 2", n)
+	}
+}
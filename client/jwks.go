@@ -0,0 +1,299 @@
+package client
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// jwksFetchTimeout - 获取 JWKS 的超时时间, 与 http.Client 的超时取较小者
+const jwksFetchTimeout = 10 * time.Second
+
+// JWKSVerifier - 用 JWKS 中的公钥 在本地校验 JWT, 不访问签发方;
+// 只接受非对称签名 (RS*, PS*, ES*)
+type JWKSVerifier struct {
+	url        string
+	httpClient *http.Client
+	issuer     string
+	cacheTTL   time.Duration
+	minRefresh time.Duration
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	// refreshing - 非 nil 时 已有请求在获取 JWKS, 获取结束后关闭; fetchErr 为其结果
+	refreshing chan struct{}
+	fetchErr   error
+}
+
+// JWKSOption - JWKSVerifier 可选配置
+type JWKSOption func(*JWKSVerifier)
+
+// WithJWKSHTTPClient - 获取 JWKS 使用的 http.Client, 默认超时 10 秒
+func WithJWKSHTTPClient(c *http.Client) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.httpClient = c
+	}
+}
+
+// WithIssuer - 要求 iss 与之相同, 默认不校验
+func WithIssuer(issuer string) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.issuer = issuer
+	}
+}
+
+// WithKeyCacheTTL - 公钥的缓存时间, 默认 10 分钟
+func WithKeyCacheTTL(d time.Duration) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.cacheTTL = d
+	}
+}
+
+// WithMinRefreshInterval - 遇到未知的 kid 时 提前刷新 JWKS 的最小间隔, 默认 10 秒,
+// 以免伪造的 kid 导致 频繁请求签发方
+func WithMinRefreshInterval(d time.Duration) JWKSOption {
+	return func(v *JWKSVerifier) {
+		v.minRefresh = d
+	}
+}
+
+// NewJWKSVerifier - url 为签发方的 JWKS 地址
+func NewJWKSVerifier(url string, opts ...JWKSOption) *JWKSVerifier {
+	v := &JWKSVerifier{
+		url:        url,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		cacheTTL:   10 * time.Minute,
+		minRefresh: 10 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify - implement Verifier interface
+func (v *JWKSVerifier) Verify(ctx context.Context, tokenStr string) (*Principal, error) {
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA:
+		default:
+			return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+		}
+		kid, _ := token.Header["kid"].(string)
+		return v.key(ctx, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	if v.issuer != "" && !claims.VerifyIssuer(v.issuer, true) {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	// RefreshToken 等其他类型的 token 不能当作 AccessToken 使用
+	if typ, _ := claims["typ"].(string); typ != "access" {
+		return nil, fmt.Errorf("%w: unexpected token type %q", ErrInvalidToken, typ)
+	}
+	return principalFromClaims(claims)
+}
+
+// principalFromClaims - 本服务签发的 token 将用户ID 写在 aud 中, 没有 sub 时 使用 aud
+func principalFromClaims(claims jwt.MapClaims) (*Principal, error) {
+	p := &Principal{}
+	p.Subject, _ = claims["sub"].(string)
+	if p.Subject == "" {
+		p.Subject, _ = claims["aud"].(string)
+	}
+	if p.Subject == "" {
+		return nil, fmt.Errorf("%w: subject not found", ErrInvalidToken)
+	}
+	p.TokenID, _ = claims["jti"].(string)
+	if scope, ok := claims["scope"].(string); ok {
+		p.Scopes = strings.Fields(scope)
+	}
+	p.Roles = stringSlice(claims["roles"])
+	if exp, ok := claims["exp"].(float64); ok {
+		p.ExpiresAt = time.Unix(int64(exp), 0)
+	}
+	return p, nil
+}
+
+func stringSlice(v interface{}) []string {
+	values, _ := v.([]interface{})
+	var out []string
+	for _, value := range values {
+		if s, ok := value.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// key - 缓存过期 或遇到未知的 kid 时 重新获取 JWKS; 获取期间不持有锁,
+// 同一时间只有一个请求访问签发方, 其他请求等待其结果
+func (v *JWKSVerifier) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	age := time.Since(v.fetchedAt)
+	key, ok := v.keys[kid]
+	if ok && age < v.cacheTTL {
+		v.mu.Unlock()
+		return key, nil
+	}
+	if !ok && v.keys != nil && age < v.minRefresh {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	done := v.refreshing
+	if done == nil {
+		done = make(chan struct{})
+		v.refreshing = done
+		go v.refresh(done)
+	}
+	v.mu.Unlock()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if ok {
+			return key, nil
+		}
+		return nil, ctx.Err()
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// 获取失败时 v.keys 未变, 继续使用已缓存的公钥
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+	if v.fetchErr != nil {
+		return nil, v.fetchErr
+	}
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// refresh - 在锁外获取 JWKS, 成功后在锁内替换公钥; 不使用请求的 ctx,
+// 以免发起获取的请求被取消时 等待的其他请求一同失败
+func (v *JWKSVerifier) refresh(done chan struct{}) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksFetchTimeout)
+	defer cancel()
+	keys, err := v.fetch(ctx)
+	v.mu.Lock()
+	if err == nil {
+		v.keys, v.fetchedAt = keys, time.Now()
+	}
+	v.fetchErr = err
+	v.refreshing = nil
+	v.mu.Unlock()
+	close(done)
+}
+
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (v *JWKSVerifier) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: %s", resp.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		// 用于加密的公钥 以及不支持的类型 直接跳过
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
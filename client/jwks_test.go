@@ -0,0 +1,217 @@
+package client_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/client"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// jwksServer - 提供 JWKS, 可轮换公钥; gate 非 nil 时 请求等待其关闭后才响应
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	fetches int
+	gate    chan struct{}
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.fetches++
+		gate := s.gate
+		s.mu.Unlock()
+		if gate != nil {
+			<-gate
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		var keys []map[string]string
+		for kid, key := range s.keys {
+			keys = append(keys, map[string]string{
+				"kid": kid,
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.keys[kid] = key
+	s.mu.Unlock()
+	return key
+}
+
+func (s *jwksServer) fetchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fetches
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func accessClaims(sub string, exp int64) jwt.MapClaims {
+	return jwt.MapClaims{"sub": sub, "exp": exp, "typ": "access"}
+}
+
+func TestJWKSVerifier(t *testing.T) {
+	srv := newJWKSServer(t)
+	key := srv.addKey(t, "k1")
+	v := client.NewJWKSVerifier(srv.URL, client.WithIssuer("identity"))
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour).Unix()
+
+	token := sign(t, jwt.SigningMethodRS256, "k1", key, jwt.MapClaims{
+		"iss": "identity", "aud": "u1", "jti": "t1++u1", "exp": exp, "typ": "access", "scope": "a b", "roles": []string{"admin"},
+	})
+	p, err := v.Verify(ctx, token)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if p.Subject != "u1" || p.TokenID != "t1++u1" || !p.HasScope("b") || !p.HasRole("admin") || p.ExpiresAt.Unix() != exp {
+		t.Fatalf("principal = %+v", p)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"expired", sign(t, jwt.SigningMethodRS256, "k1", key, jwt.MapClaims{"iss": "identity", "sub": "u1", "exp": time.Now().Add(-time.Minute).Unix(), "typ": "access"})},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, "k1", key, jwt.MapClaims{"iss": "other", "sub": "u1", "exp": exp, "typ": "access"})},
+		{"no subject", sign(t, jwt.SigningMethodRS256, "k1", key, jwt.MapClaims{"iss": "identity", "exp": exp, "typ": "access"})},
+		{"refresh token", sign(t, jwt.SigningMethodRS256, "k1", key, jwt.MapClaims{"iss": "identity", "sub": "u1", "exp": exp, "typ": "refresh"})},
+		{"no type", sign(t, jwt.SigningMethodRS256, "k1", key, jwt.MapClaims{"iss": "identity", "sub": "u1", "exp": exp})},
+		// 以公钥作为 HMAC 密钥伪造的 token
+		{"hmac", sign(t, jwt.SigningMethodHS256, "k1", []byte("secret"), jwt.MapClaims{"iss": "identity", "sub": "u1", "exp": exp, "typ": "access"})},
+		{"malformed", "not.a.token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(ctx, tt.token); !errors.Is(err, client.ErrInvalidToken) {
+				t.Fatalf("Verify err = %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	// 已缓存的公钥 不重复获取
+	if n := srv.fetchCount(); n != 1 {
+		t.Fatalf("JWKS fetched %d times, want 1", n)
+	}
+}
+
+func TestJWKSVerifierKeyRotation(t *testing.T) {
+	srv := newJWKSServer(t)
+	oldKey := srv.addKey(t, "old")
+	v := client.NewJWKSVerifier(srv.URL, client.WithMinRefreshInterval(100*time.Millisecond))
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour).Unix()
+
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, "old", oldKey, accessClaims("u1", exp))); err != nil {
+		t.Fatalf("Verify(old): %v", err)
+	}
+	// 签发方轮换公钥后 遇到新的 kid 时 提前刷新
+	newKey := srv.addKey(t, "new")
+	time.Sleep(150 * time.Millisecond)
+	if _, err := v.Verify(ctx, sign(t, jwt.SigningMethodRS256, "new", newKey, accessClaims("u1", exp))); err != nil {
+		t.Fatalf("Verify(new): %v", err)
+	}
+	// 未知的 kid 不会在短时间内 反复触发获取
+	for i := 0; i < 3; i++ {
+		v.Verify(ctx, sign(t, jwt.SigningMethodRS256, "unknown", newKey, accessClaims("u1", exp)))
+	}
+	if n := srv.fetchCount(); n != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", n)
+	}
+}
+
+func TestJWKSVerifierRefreshDoesNotBlock(t *testing.T) {
+	srv := newJWKSServer(t)
+	oldKey := srv.addKey(t, "old")
+	v := client.NewJWKSVerifier(srv.URL, client.WithMinRefreshInterval(0))
+	ctx := context.Background()
+	exp := time.Now().Add(time.Hour).Unix()
+	oldToken := sign(t, jwt.SigningMethodRS256, "old", oldKey, accessClaims("u1", exp))
+	if _, err := v.Verify(ctx, oldToken); err != nil {
+		t.Fatalf("Verify(old): %v", err)
+	}
+
+	// 签发方响应缓慢时 多个未知 kid 的请求只获取一次 JWKS
+	gate := make(chan struct{})
+	srv.mu.Lock()
+	srv.gate = gate
+	srv.mu.Unlock()
+	newKey := srv.addKey(t, "new")
+	newToken := sign(t, jwt.SigningMethodRS256, "new", newKey, accessClaims("u1", exp))
+	var wg sync.WaitGroup
+	errs := make(chan error, 5)
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := v.Verify(ctx, newToken)
+			errs <- err
+		}()
+	}
+	for srv.fetchCount() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 获取期间 已缓存的公钥仍可使用
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.Verify(ctx, oldToken)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Verify(old) during refresh: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Verify(old) blocked by the JWKS refresh")
+	}
+
+	close(gate)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Verify(new): %v", err)
+		}
+	}
+	if n := srv.fetchCount(); n != 2 {
+		t.Fatalf("JWKS fetched %d times, want 2", n)
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+// Option - 中间件可选配置
+type Option func(*Authenticator)
+
+// WithCookie - Authorization 头中没有 Bearer token 时 从该 cookie 中读取
+func WithCookie(name string) Option {
+	return func(a *Authenticator) {
+		a.cookie = name
+	}
+}
+
+// RequireScopes - 要求 token 包含全部 scope
+func RequireScopes(scopes ...string) Option {
+	return func(a *Authenticator) {
+		a.scopes = append(a.scopes, scopes...)
+	}
+}
+
+// RequireRoles - 要求用户至少拥有其中一个角色; JWKS 校验时 角色取自 roles claim
+func RequireRoles(roles ...string) Option {
+	return func(a *Authenticator) {
+		a.roles = append(a.roles, roles...)
+	}
+}
+
+// WithRestrictedScopes - 带有这些 scope 的受限 token 只能访问 RequireScopes 中要求了该 scope 的路由,
+// 默认为 domain.ScopePasswordChange, 与本服务的 MustLoginInterceptor 一致
+func WithRestrictedScopes(scopes ...string) Option {
+	return func(a *Authenticator) {
+		a.restricted = scopes
+	}
+}
+
+// Authenticator - 从请求中取出 token 校验, 并检查 scope 与角色; 供 net/http 与 gin 中间件共用
+type Authenticator struct {
+	verifier   Verifier
+	cookie     string
+	scopes     []string
+	roles      []string
+	restricted []string
+}
+
+// NewAuthenticator - 一般直接使用 Middleware 或 ginclient.Middleware
+func NewAuthenticator(v Verifier, opts ...Option) *Authenticator {
+	a := &Authenticator{verifier: v, restricted: []string{domain.ScopePasswordChange}}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Authenticate - 返回的错误 可用 errors.Is 与 ErrNoToken 等比较, 其他错误表示校验方不可用
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := a.token(r)
+	if token == "" {
+		return nil, ErrNoToken
+	}
+	p, err := a.verifier.Verify(r.Context(), token)
+	if err != nil {
+		return nil, err
+	}
+	for _, scope := range a.scopes {
+		if !p.HasScope(scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInsufficientScope, scope)
+		}
+	}
+	for _, scope := range a.restricted {
+		if p.HasScope(scope) && !contains(a.scopes, scope) {
+			return nil, fmt.Errorf("%w: token restricted to %s", ErrInsufficientScope, scope)
+		}
+	}
+	if len(a.roles) > 0 && !hasAnyRole(p, a.roles) {
+		return nil, ErrMissingRole
+	}
+	return p, nil
+}
+
+func hasAnyRole(p *Principal, roles []string) bool {
+	for _, role := range roles {
+		if p.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *Authenticator) token(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	if a.cookie != "" {
+		if c, err := r.Cookie(a.cookie); err == nil {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// Middleware - net/http 中间件, 通过校验后 用 PrincipalFrom(r.Context()) 取出 Principal
+func Middleware(v Verifier, opts ...Option) func(http.Handler) http.Handler {
+	a := NewAuthenticator(v, opts...)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			p, err := a.Authenticate(r)
+			if err != nil {
+				WriteError(w, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), p)))
+		})
+	}
+}
+
+// StatusCode - Authenticate 返回的错误 对应的 HTTP 状态码
+func StatusCode(err error) int {
+	switch {
+	case errors.Is(err, ErrNoToken), errors.Is(err, ErrInvalidToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrInsufficientScope), errors.Is(err, ErrMissingRole):
+		return http.StatusForbidden
+	default:
+		return http.StatusServiceUnavailable
+	}
+}
+
+// Challenge - WWW-Authenticate 头 (RFC 6750), 不需要时 返回空字符串
+func Challenge(err error) string {
+	switch {
+	case errors.Is(err, ErrNoToken):
+		return "Bearer"
+	case errors.Is(err, ErrInvalidToken):
+		return `Bearer error="invalid_token"`
+	case errors.Is(err, ErrInsufficientScope):
+		return `Bearer error="insufficient_scope"`
+	default:
+		return ""
+	}
+}
+
+// WriteError - 写入状态码, WWW-Authenticate 与 JSON 错误信息
+func WriteError(w http.ResponseWriter, err error) {
+	if challenge := Challenge(err); challenge != "" {
+		w.Header().Set("WWW-Authenticate", challenge)
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(StatusCode(err))
+	json.NewEncoder(w).Encode(status.ResponseError{Message: ErrorMessage(err)})
+}
+
+// ErrorMessage - 返回给调用方的错误信息, 校验方不可用时 不暴露内部错误
+func ErrorMessage(err error) string {
+	if StatusCode(err) == http.StatusServiceUnavailable {
+		return "token verification unavailable"
+	}
+	return err.Error()
+}
@@ -0,0 +1,85 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/client"
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// staticVerifier - token -> principal, "down" 模拟校验方不可用
+type staticVerifier map[string]*client.Principal
+
+func (s staticVerifier) Verify(ctx context.Context, token string) (*client.Principal, error) {
+	if token == "down" {
+		return nil, errors.New("connection refused")
+	}
+	if p, ok := s[token]; ok {
+		return p, nil
+	}
+	return nil, client.ErrInvalidToken
+}
+
+var verifier = staticVerifier{
+	"user":       {Subject: "u1"},
+	"admin":      {Subject: "u2", Roles: []string{"admin"}},
+	"scoped":     {Subject: "u3", Scopes: []string{"orders:read"}},
+	"restricted": {Subject: "u4", Scopes: []string{domain.ScopePasswordChange}},
+}
+
+func TestMiddleware(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []client.Option
+		header    string
+		cookie    string
+		code      int
+		challenge string
+	}{
+		{"no token", nil, "", "", http.StatusUnauthorized, "Bearer"},
+		{"invalid", nil, "Bearer nope", "", http.StatusUnauthorized, `Bearer error="invalid_token"`},
+		{"valid", nil, "Bearer user", "", http.StatusOK, ""},
+		{"lowercase scheme", nil, "bearer user", "", http.StatusOK, ""},
+		{"cookie ignored by default", nil, "", "user", http.StatusUnauthorized, "Bearer"},
+		{"cookie", []client.Option{client.WithCookie("access_token")}, "", "user", http.StatusOK, ""},
+		{"missing scope", []client.Option{client.RequireScopes("orders:read")}, "Bearer user", "", http.StatusForbidden, `Bearer error="insufficient_scope"`},
+		{"scope", []client.Option{client.RequireScopes("orders:read")}, "Bearer scoped", "", http.StatusOK, ""},
+		{"restricted token", nil, "Bearer restricted", "", http.StatusForbidden, `Bearer error="insufficient_scope"`},
+		{"restricted route", []client.Option{client.RequireScopes(domain.ScopePasswordChange)}, "Bearer restricted", "", http.StatusOK, ""},
+		{"missing role", []client.Option{client.RequireRoles("admin")}, "Bearer user", "", http.StatusForbidden, ""},
+		{"role", []client.Option{client.RequireRoles("admin", "support")}, "Bearer admin", "", http.StatusOK, ""},
+		{"verifier down", nil, "Bearer down", "", http.StatusServiceUnavailable, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *client.Principal
+			handler := client.Middleware(verifier, tt.opts...)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got, _ = client.PrincipalFrom(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.code {
+				t.Fatalf("status = %d, want %d, body %s", w.Code, tt.code, w.Body)
+			}
+			if c := w.Header().Get("WWW-Authenticate"); c != tt.challenge {
+				t.Fatalf("WWW-Authenticate = %q, want %q", c, tt.challenge)
+			}
+			if (tt.code == http.StatusOK) != (got != nil) {
+				t.Fatalf("principal = %+v", got)
+			}
+		})
+	}
+}
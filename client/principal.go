@@ -0,0 +1,68 @@
+// Package client - 供其他服务引入的 access token 校验:
+// 通过 JWKS 或 introspection 校验 token, 并以 net/http 或 gin 中间件的形式 将 Principal 写入请求上下文
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNoToken - 请求中没有 access token
+	ErrNoToken = errors.New("access token not found")
+	// ErrInvalidToken - token 无效, 已过期 或已撤销
+	ErrInvalidToken = errors.New("invalid access token")
+	// ErrInsufficientScope - token 缺少路由要求的 scope
+	ErrInsufficientScope = errors.New("insufficient scope")
+	// ErrMissingRole - 用户缺少路由要求的角色
+	ErrMissingRole = errors.New("missing role")
+)
+
+// Principal - 通过校验的 access token 所代表的用户
+type Principal struct {
+	// Subject - 用户ID
+	Subject string
+	TokenID string
+	Scopes  []string
+	Roles   []string
+	// ExpiresAt - 零值表示未知
+	ExpiresAt time.Time
+}
+
+// HasScope - 是否包含 scope
+func (p *Principal) HasScope(scope string) bool {
+	return contains(p.Scopes, scope)
+}
+
+// HasRole - 是否拥有角色
+func (p *Principal) HasRole(role string) bool {
+	return contains(p.Roles, role)
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal - 将 Principal 写入 ctx
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom - 取出中间件写入的 Principal
+func PrincipalFrom(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Verifier - 校验 access token, token 无效时 返回的错误 wrap ErrInvalidToken
+type Verifier interface {
+	Verify(ctx context.Context, token string) (*Principal, error)
+}
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AuditEventType - 审计事件类型
+type AuditEventType string
+
+const (
+	// AuditLogin - 登录
+	AuditLogin AuditEventType = "login"
+	// AuditLogout - 退出登录
+	AuditLogout AuditEventType = "logout"
+	// AuditRegister - 注册
+	AuditRegister AuditEventType = "register"
+	// AuditPasswordChange - 修改密码
+	AuditPasswordChange AuditEventType = "password_change"
+	// AuditTokenRefresh - 用 RefreshToken 换取新的 Tokens
+	AuditTokenRefresh AuditEventType = "token_refresh"
+	// AuditTokenRevoke - 吊销 Token
+	AuditTokenRevoke AuditEventType = "token_revoke"
+	// AuditAccountDelete - 注销账号
+	AuditAccountDelete AuditEventType = "account_delete"
+	// AuditAccountRestore - 撤销注销
+	AuditAccountRestore AuditEventType = "account_restore"
+)
+
+// AuditOutcome - 审计事件结果
+type AuditOutcome string
+
+const (
+	// AuditSuccess - 操作成功
+	AuditSuccess AuditOutcome = "success"
+	// AuditFailure - 操作失败
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditEvent - 一条审计记录
+type AuditEvent interface {
+	GetType() AuditEventType
+	GetOutcome() AuditOutcome
+	GetUserID() string
+	GetAccount() string
+	GetTimestamp() time.Time
+	SetTimestamp(time.Time)
+}
+
+// AuditFilter - 查询审计记录的条件, 空值表示不过滤
+type AuditFilter struct {
+	UserID  string
+	Account string
+	Type    AuditEventType
+	Outcome AuditOutcome
+	From    *time.Time
+	To      *time.Time
+}
+
+// AuditPage - 一页审计记录, Page 与 Limit 为修正后实际使用的值
+type AuditPage struct {
+	Items []AuditEvent
+	Total int64
+	Page  int64
+	Limit int64
+}
+
+// AuditUsecase - 记录与查询审计事件
+type AuditUsecase interface {
+	// RecordUC - 记录审计事件, 失败不影响业务流程
+	RecordUC(ctx context.Context, event AuditEvent)
+	// QueryUC - 按条件分页查询, 超出范围的 page 与 limit 被修正
+	QueryUC(ctx context.Context, filter AuditFilter, page int64, limit int64) (AuditPage, error)
+}
+
+// AuditRepository - 持久化审计事件
+type AuditRepository interface {
+	Insert(ctx context.Context, event AuditEvent) error
+	Find(ctx context.Context, filter AuditFilter, skip int64, limit int64) ([]AuditEvent, error)
+	Count(ctx context.Context, filter AuditFilter) (int64, error)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// TokenConfig - token 签发配置, 与 go-identity-utils/config.TokenConfig 一致;
+// 在此声明 以免业务代码依赖 config 包的 init (启动时读取配置文件)
+type TokenConfig interface {
+	GetIssuer() string
+	GetAccessTokenSecret() []byte
+	GetRefreshTokenSecret() []byte
+	GetAccessExpirationSeconds() time.Duration
+	GetRefreshExpirationSeconds() time.Duration
+}
+
+// CookieConfig - cookie 配置, 与 go-identity-utils/config.CookieConfig 一致
+type CookieConfig interface {
+	GetAccessTokenMaxAge() int
+	GetRefreshTokenMaxAge() int
+	GetDomain() string
+	GetAccessTokenField() string
+	GetRefreshTokenField() string
+	GetUserIDField() string
+	GetDisplayNameField() string
+	GetSecure() bool
+	GetHTTPOnly() bool
+}
@@ -0,0 +1,117 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode - 返回给客户端的稳定错误码, 客户端应据此判断错误类型, 而不是解析 message
+type ErrorCode string
+
+const (
+	// CodeInvalidRequest - 请求格式错误, 如 JSON 无法解析
+	CodeInvalidRequest ErrorCode = "invalid_request"
+	// CodeValidationFailed - 字段校验失败, details 中列出每个字段
+	CodeValidationFailed ErrorCode = "validation_failed"
+	// CodeInvalidAccount - 账号为空 或混用易混淆的文字
+	CodeInvalidAccount ErrorCode = "invalid_account"
+	// CodeInvalidCredentials - 账号或密码错误
+	CodeInvalidCredentials ErrorCode = "invalid_credentials"
+	// CodeAccountPendingDeletion - 账号已申请注销
+	CodeAccountPendingDeletion ErrorCode = "account_pending_deletion"
+	// CodeAccountExists - 账号已被注册
+	CodeAccountExists ErrorCode = "account_exists"
+	// CodePasswordPolicy - 密码不符合策略, details 中列出每条违反的规则
+	CodePasswordPolicy ErrorCode = "password_policy"
+	// CodePasswordReused - 新密码与最近使用过的密码相同
+	CodePasswordReused ErrorCode = "password_reused"
+	// CodePasswordExpired - 密码已过期, 须先修改密码
+	CodePasswordExpired ErrorCode = "password_expired"
+	// CodeNotLoggedIn - 未携带 token
+	CodeNotLoggedIn ErrorCode = "not_logged_in"
+	// CodeAlreadyLoggedIn - 已登录时 不允许再登录或注册
+	CodeAlreadyLoggedIn ErrorCode = "already_logged_in"
+	// CodeTokenExpired - token 已过期
+	CodeTokenExpired ErrorCode = "token_expired"
+	// CodeTokenNotYetValid - token 尚未生效, 一般是服务器之间时钟不一致
+	CodeTokenNotYetValid ErrorCode = "token_not_yet_valid"
+	// CodeTokenMalformed - token 不是合法的 JWT, 或缺少必需的 claim
+	CodeTokenMalformed ErrorCode = "token_malformed"
+	// CodeTokenBadSignature - token 签名错误 或签名算法不受支持
+	CodeTokenBadSignature ErrorCode = "token_bad_signature"
+	// CodeTokenWrongType - 如将 RefreshToken 当作 AccessToken 使用
+	CodeTokenWrongType ErrorCode = "token_wrong_type"
+	// CodeTokenInvalid - token 与存储中的记录不一致
+	CodeTokenInvalid ErrorCode = "token_invalid"
+	// CodeTokenRevoked - token 已被撤销 或已退出登录
+	CodeTokenRevoked ErrorCode = "token_revoked"
+	// CodeUnauthorized - 其他未认证的错误
+	CodeUnauthorized ErrorCode = "unauthorized"
+	// CodeForbidden - 没有权限
+	CodeForbidden ErrorCode = "forbidden"
+	// CodeNotFound - 资源不存在
+	CodeNotFound ErrorCode = "not_found"
+	// CodeConflict - 资源已存在
+	CodeConflict ErrorCode = "conflict"
+	// CodeInternal - 服务端错误, 不返回具体原因
+	CodeInternal ErrorCode = "internal_error"
+)
+
+// FieldError - 一个字段未通过校验的原因
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// CodedError - 带错误码的错误, Message 会原样返回给客户端, 不应包含内部细节;
+// Unwrap 返回 status 包中的错误, 用于决定 HTTP 状态码
+type CodedError struct {
+	Code    ErrorCode
+	Message string
+	Details []FieldError
+	Err     error
+}
+
+// NewCodedError - err 一般为 status 包中的错误
+func NewCodedError(err error, code ErrorCode, message string) *CodedError {
+	return &CodedError{Code: code, Message: message, Err: err}
+}
+
+// Error - 与 fmt.Errorf("%w: message", err) 的格式相同
+func (e *CodedError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return fmt.Sprintf("%v: %s", e.Err, e.Message)
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode - implement Coded interface
+func (e *CodedError) ErrorCode() ErrorCode {
+	return e.Code
+}
+
+// FieldErrors - implement Coded interface
+func (e *CodedError) FieldErrors() []FieldError {
+	return e.Details
+}
+
+// Coded - 可以提供错误码的错误, 如 CodedError 与 password.PolicyError
+type Coded interface {
+	error
+	ErrorCode() ErrorCode
+	FieldErrors() []FieldError
+}
+
+// AsCoded - 取出错误链中第一个 Coded, 没有时返回 nil
+func AsCoded(err error) Coded {
+	var coded Coded
+	if errors.As(err, &coded) {
+		return coded
+	}
+	return nil
+}
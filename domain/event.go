@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// EventType - 身份相关的领域事件类型
+type EventType string
+
+const (
+	// EventUserRegistered - 用户注册
+	EventUserRegistered EventType = "UserRegistered"
+	// EventUserLoggedIn - 用户登录
+	EventUserLoggedIn EventType = "UserLoggedIn"
+	// EventUserLoggedOut - 用户退出登录
+	EventUserLoggedOut EventType = "UserLoggedOut"
+	// EventPasswordChanged - 用户修改密码
+	EventPasswordChanged EventType = "PasswordChanged"
+	// EventSessionRevoked - 用户所有 Token 被吊销
+	EventSessionRevoked EventType = "SessionRevoked"
+	// EventUserDeletionScheduled - 用户申请注销, 宽限期后删除
+	EventUserDeletionScheduled EventType = "UserDeletionScheduled"
+	// EventUserDeletionCancelled - 用户在宽限期内撤销注销
+	EventUserDeletionCancelled EventType = "UserDeletionCancelled"
+)
+
+// EventTypes - 全部已定义的事件类型, webhook 只能订阅其中的类型; 新增事件类型时 一并加入
+var EventTypes = []EventType{
+	EventUserRegistered,
+	EventUserLoggedIn,
+	EventUserLoggedOut,
+	EventPasswordChanged,
+	EventSessionRevoked,
+	EventUserDeletionScheduled,
+	EventUserDeletionCancelled,
+}
+
+// Known - 是否为 EventTypes 中的事件类型
+func (t EventType) Known() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event - 领域事件
+type Event interface {
+	GetEventID() string
+	GetType() EventType
+	GetUserID() string
+	GetPayload() map[string]string
+	GetOccurredAt() time.Time
+}
+
+// EventPublisher - 事件总线, 由 usecase 调用
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event) error
+}
+
+// EventOutbox - 事件发件箱, 保证事件与业务数据一同持久化, 之后再转发
+type EventOutbox interface {
+	EventPublisher
+	// ListPending - 按发生时间 返回尚未转发的事件
+	ListPending(ctx context.Context, limit int64) ([]Event, error)
+	// MarkPublished - 标记事件已转发
+	MarkPublished(ctx context.Context, eventID string) error
+}
+
+// Transactor - 在同一个事务中执行 fn, fn 内须使用传入的 ctx
+type Transactor interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
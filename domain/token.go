@@ -3,12 +3,59 @@ package domain
 import (
 	"context"
 	"time"
+
+	"github.com/alibug/go-identity-utils/status"
 )
 
+// ScopePasswordChange - 密码已过期时签发的受限 Token, 只允许修改密码
+const ScopePasswordChange = "password_change"
+
 // TokenDetail contain tokenID and userID
 type TokenDetail interface {
 	GetTokenID() string
 	GetUserID() string
+	// GetScopes - 受限 Token 的 scope, 为空表示不受限
+	GetScopes() []string
+	// GetExpiresAt - 为 nil 表示永不过期 或未知
+	GetExpiresAt() *time.Time
+}
+
+// TokenType - token 的类型
+type TokenType string
+
+const (
+	// TokenTypeAccess - AccessToken
+	TokenTypeAccess TokenType = "access"
+	// TokenTypeRefresh - RefreshToken
+	TokenTypeRefresh TokenType = "refresh"
+)
+
+// 校验 token 失败的原因, 均为 status.ErrUnauthorized; 可以用 errors.Is 判断
+var (
+	ErrTokenExpired      = NewCodedError(status.ErrUnauthorized, CodeTokenExpired, "token has expired")
+	ErrTokenNotYetValid  = NewCodedError(status.ErrUnauthorized, CodeTokenNotYetValid, "token is not valid yet")
+	ErrTokenMalformed    = NewCodedError(status.ErrUnauthorized, CodeTokenMalformed, "token is malformed")
+	ErrTokenBadSignature = NewCodedError(status.ErrUnauthorized, CodeTokenBadSignature, "token signature is invalid")
+	ErrTokenWrongType    = NewCodedError(status.ErrUnauthorized, CodeTokenWrongType, "token has the wrong type")
+	ErrTokenInvalid      = NewCodedError(status.ErrUnauthorized, CodeTokenInvalid, "token does not match its record")
+	ErrTokenRevoked      = NewCodedError(status.ErrUnauthorized, CodeTokenRevoked, "token has been revoked")
+)
+
+// ClientInfo - 签发 token 时 客户端的信息
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// Session - 持久化保存的 token 记录, 旧格式的记录 只有 TokenID, UserID 与过期时间
+type Session interface {
+	TokenDetail
+	GetType() TokenType
+	GetIssuedAt() *time.Time
+	GetClient() string
+	GetIP() string
+	// GetParentID - AccessToken 对应的 RefreshToken 的 tokenID
+	GetParentID() string
 }
 
 // Tokens - 包含 AccessToken 和 RefreshToken
@@ -17,16 +64,51 @@ type Tokens interface {
 	GetRefreshToken() string
 }
 
+// JWK - JSON Web Key (RFC 7517) 格式的公钥, 只包含 RSA 与 EC 公钥的成员
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet - /.well-known/jwks.json 的响应
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
 // TokensUseCase - 处理 Tokens
 type TokensUseCase interface {
-	// CreateTokens - 创建 AccessToken 和 RefreshToken
-	CreateTokens(ctx context.Context, userID string) (Tokens, error)
+	// CreateTokens - 创建 AccessToken 和 RefreshToken, 指定 scopes 时为受限 Token; 不发布事件,
+	// 用于修改密码后 为当前设备重新签发等 非登录的场景
+	CreateTokens(ctx context.Context, userID string, client ClientInfo, scopes ...string) (Tokens, error)
+
+	// LoginTokens - 登录时签发 Tokens, 与 CreateTokens 相同 并发布 UserLoggedIn
+	LoginTokens(ctx context.Context, userID string, client ClientInfo, scopes ...string) (Tokens, error)
 
-	// CheckTokensAndLogout - 检查 Tokens
-	CheckTokensAndLogout(ctx context.Context, tokens Tokens) error
+	// RefreshTokens - 用 RefreshToken 换取新的 Tokens, 旧的 Tokens 失效
+	RefreshTokens(ctx context.Context, refreshToken string, client ClientInfo) (Tokens, error)
+
+	// CheckTokensAndLogout - 检查 Tokens 并删除, 返回 Tokens 所属的 userID
+	CheckTokensAndLogout(ctx context.Context, tokens Tokens) (string, error)
 
 	// CheckAccessToken - 用于检查 AccessToken 合法性
-	// CheckAccessToken(ctx context.Context, tokenStr string) (TokenDetail, bool, error)
+	CheckAccessToken(ctx context.Context, tokenStr string) (TokenDetail, bool, error)
+
+	// ListSessions - 列出用户所有未过期的 Token
+	ListSessions(ctx context.Context, userID string) ([]Session, error)
+
+	// RevokeUserTokens - 删除用户所有的 Token
+	RevokeUserTokens(ctx context.Context, userID string) error
+
+	// JWKS - 校验 AccessToken 的公钥; AccessToken 使用 HMAC 签名时 Keys 为空
+	JWKS() JWKSet
+
 	// CheckRefreshToken - 用于检查 RefreshToken 合法性
 	// CheckRefreshToken(ctx context.Context, tokenStr string) (TokenDetail, bool, error)
 	// DeleteToken - 删除指定 的 Token
@@ -35,12 +117,35 @@ type TokensUseCase interface {
 
 // TokensRepository - 持久化处理 Tokens
 type TokensRepository interface {
-	// CreateToken - 创建 指定 TokenDetail
-	CreateTokenID(ctx context.Context, token TokenDetail, expiration time.Duration) error
+	// CreateTokenID - 保存 token 记录, 在 GetExpiresAt 时过期
+	CreateTokenID(ctx context.Context, session Session) error
 	// CheckAccessToken - 检查 某个 TokenDetail 是否在数据库中持久化保存
 	CheckTokenID(ctx context.Context, token TokenDetail) (bool, error)
 	// DeleteToken - 删除指定的 Token
 	DeleteTokenID(ctx context.Context, tokenID string) error
+	// ListUserTokenIDs - 列出 指定用户 所有持久化保存的 Token
+	ListUserTokenIDs(ctx context.Context, userID string) ([]Session, error)
+	// DeleteUserTokenIDs - 删除 指定用户 所有的 Token
+	DeleteUserTokenIDs(ctx context.Context, userID string) error
+}
+
+// RevocationList - 已撤销的 AccessToken 的 tokenID (deny-list);
+// 无状态模式下 AccessToken 只校验签名与过期时间, 不再逐个查询 TokensRepository
+type RevocationList interface {
+	// Revoke - 撤销 tokenID, 记录保留到 expiresAt, 之后 token 已自然过期
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// IsRevoked - tokenID 是否已被撤销
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// RevocationStore - 可列出与订阅的 RevocationList, 供进程内缓存同步
+type RevocationStore interface {
+	RevocationList
+	// ListRevoked - 列出所有未过期的撤销记录
+	ListRevoked(ctx context.Context) ([]string, error)
+	// SubscribeRevoked - 订阅生效后返回, 之后撤销的 tokenID 写入 channel; ctx 结束时关闭,
+	// 通知可能已丢失时 (如连接重建) 也会关闭
+	SubscribeRevoked(ctx context.Context) (<-chan string, error)
 }
 
 // JwtParams - 创建 JWT 要用的参数
@@ -51,4 +156,5 @@ type JwtParams interface {
 	GetAudience() string
 	GetSecret() []byte
 	GetIssueTime() time.Time
+	GetScopes() []string
 }
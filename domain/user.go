@@ -9,15 +9,26 @@ import (
 type Register interface {
 	GetAccount() string
 	GetPassword() string
+	GetDisplayName() string
+	GetCryptPass() []byte
+	GetPasswordChangedAt() *time.Time
+	SetAccount(account string)
 	SetCreatedTime(*time.Time)
-	SetCryptPass() error
+	SetPasswordChangedTime(*time.Time)
+	SetCryptPass(hash []byte)
 }
 
 // User ...
 type User interface {
 	GetUserID() string
+	GetAccount() string
 	GetDisplayName() string
+	GetRoles() []string
 	GetCryptPass() []byte
+	// GetPasswordHistory - 之前使用过的密码哈希, 最近的在前
+	GetPasswordHistory() [][]byte
+	GetPasswordChangedAt() *time.Time
+	GetDeletionScheduledAt() *time.Time
 	SetUpdatedTime(*time.Time)
 }
 
@@ -27,6 +38,16 @@ type UserUsecase interface {
 	GetByIDUC(ctx context.Context, id string) (User, error)
 	GetByAccountUC(ctx context.Context, account string) (User, error)
 	CheckAccountAndPassUC(ctx context.Context, account string, password string) (User, error)
+	// ScheduleDeletionUC - 校验密码后 标记用户待删除, 返回实际删除时间
+	ScheduleDeletionUC(ctx context.Context, id string, password string) (time.Time, error)
+	// CancelDeletionUC - 宽限期内 撤销注销申请
+	CancelDeletionUC(ctx context.Context, id string) error
+	// PurgeDeletedUsersUC - 删除或匿名化 已过宽限期的用户, 返回处理数量
+	PurgeDeletedUsersUC(ctx context.Context) (int, error)
+	// ChangePasswordUC - 校验旧密码, 新密码须符合密码策略 且不能与最近使用过的密码相同
+	ChangePasswordUC(ctx context.Context, id string, oldPassword string, newPassword string) error
+	// PasswordExpiredUC - 用户角色要求定期修改密码, 且已超期
+	PasswordExpiredUC(user User) bool
 }
 
 // UserRepository represent the user's repository contract
@@ -34,4 +55,39 @@ type UserRepository interface {
 	RegisterUser(ctx context.Context, body Register) error
 	GetByID(ctx context.Context, id string) (User, error)
 	GetByAccount(ctx context.Context, account string) (User, error)
+	// ScheduleDeletion - 设置用户的删除时间
+	ScheduleDeletion(ctx context.Context, id string, at time.Time) error
+	// CancelDeletion - 清除用户的删除时间
+	CancelDeletion(ctx context.Context, id string) error
+	// ListDueDeletions - 返回删除时间早于 before 的用户ID
+	ListDueDeletions(ctx context.Context, before time.Time) ([]string, error)
+	// AnonymizeUser - 清除用户的个人信息, 保留用户ID
+	AnonymizeUser(ctx context.Context, id string) error
+	// DeleteUser - 彻底删除用户
+	DeleteUser(ctx context.Context, id string) error
+	// UpdateCryptPass - 更新密码哈希 (重新哈希), 不影响密码历史
+	UpdateCryptPass(ctx context.Context, id string, hash []byte) error
+	// ChangePassword - 设置新密码哈希、密码历史 与 修改时间
+	ChangePassword(ctx context.Context, id string, hash []byte, history [][]byte, changedAt time.Time) error
+}
+
+// PasswordHasher - 密码哈希算法
+type PasswordHasher interface {
+	// Hash - 生成包含算法与参数的哈希值
+	Hash(password string) ([]byte, error)
+	// Verify - 校验密码, 哈希值格式无法识别时返回 error
+	Verify(hash []byte, password string) (bool, error)
+	// NeedsRehash - 哈希值的算法或参数 与当前配置不一致
+	NeedsRehash(hash []byte) bool
+}
+
+// AccountNormalizer - 账号规范化, 注册与登录时使用同一规则
+type AccountNormalizer interface {
+	Normalize(account string) (string, error)
+}
+
+// PasswordPolicy - 密码策略, 用于注册、修改密码 与 重置密码
+type PasswordPolicy interface {
+	// Validate - 不符合策略时返回 error, 其中包含每条违反的规则
+	Validate(password string, account string, displayname string) error
 }
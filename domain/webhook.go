@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Webhook - 已注册的 webhook 接收地址
+type Webhook interface {
+	GetWebhookID() string
+	GetURL() string
+	// GetSecret - 用于 HMAC-SHA256 签名的密钥
+	GetSecret() string
+	// GetEvents - 订阅的事件类型, 为空表示订阅全部
+	GetEvents() []EventType
+	GetCreatedAt() time.Time
+}
+
+// WebhookDeliveryStatus - 投递状态
+type WebhookDeliveryStatus string
+
+const (
+	// DeliveryPending - 等待投递 或 等待重试
+	DeliveryPending WebhookDeliveryStatus = "pending"
+	// DeliveryInFlight - 已被某个实例领取, 正在投递; 租约过期后可被重新领取
+	DeliveryInFlight WebhookDeliveryStatus = "in_flight"
+	// DeliverySucceeded - 投递成功
+	DeliverySucceeded WebhookDeliveryStatus = "succeeded"
+	// DeliveryDead - 重试次数用尽, 进入死信列表
+	DeliveryDead WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery - 一次事件投递
+type WebhookDelivery interface {
+	GetDeliveryID() string
+	GetWebhookID() string
+	GetEventID() string
+	GetEventType() EventType
+	// GetPayload - 发送的 JSON 内容
+	GetPayload() []byte
+	GetAttempts() int
+	GetStatus() WebhookDeliveryStatus
+}
+
+// WebhookUsecase - 管理 webhook 与 投递记录
+type WebhookUsecase interface {
+	// RegisterWebhookUC - 注册 webhook, 返回值中包含生成的签名密钥
+	RegisterWebhookUC(ctx context.Context, url string, events []EventType) (Webhook, error)
+	ListWebhooksUC(ctx context.Context) ([]Webhook, error)
+	DeleteWebhookUC(ctx context.Context, id string) error
+	ListDeadDeliveriesUC(ctx context.Context, limit int64) ([]WebhookDelivery, error)
+	// ReplayDeliveryUC - 将投递记录重置为待投递, 重新开始计算重试次数
+	ReplayDeliveryUC(ctx context.Context, id string) error
+}
+
+// WebhookRepository - 持久化 webhook 与 投递记录
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook Webhook) error
+	GetWebhook(ctx context.Context, id string) (Webhook, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+
+	CreateDelivery(ctx context.Context, delivery WebhookDelivery) error
+	// ClaimDueDelivery - 原子地领取一条 下次投递时间早于 now 的待投递记录 (或租约已过期的投递中记录),
+	// 将其置为 DeliveryInFlight 并持有租约到 leaseUntil; 多个实例不会领取到同一条. 没有时返回 status.ErrNotFound
+	ClaimDueDelivery(ctx context.Context, now time.Time, leaseUntil time.Time) (WebhookDelivery, error)
+	ListDeadDeliveries(ctx context.Context, limit int64) ([]WebhookDelivery, error)
+	MarkDeliverySucceeded(ctx context.Context, id string, attempts int) error
+	// MarkDeliveryFailed - 记录失败, dead 为 true 时进入死信列表
+	MarkDeliveryFailed(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error
+	ReplayDelivery(ctx context.Context, id string) error
+}
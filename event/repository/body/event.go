@@ -0,0 +1,54 @@
+package body
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/google/uuid"
+)
+
+// EventBody - implement domain.Event
+type EventBody struct {
+	ID          string            `bson:"_id" json:"id"`
+	Type        domain.EventType  `bson:"type" json:"type"`
+	UserID      string            `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Payload     map[string]string `bson:"payload,omitempty" json:"payload,omitempty"`
+	OccurredAt  time.Time         `bson:"occurred_at" json:"occurred_at"`
+	PublishedAt *time.Time        `bson:"published_at,omitempty" json:"-"`
+}
+
+// NewEventBody - 创建一个新事件
+func NewEventBody(eventType domain.EventType, userID string, payload map[string]string) *EventBody {
+	return &EventBody{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		UserID:     userID,
+		Payload:    payload,
+		OccurredAt: time.Now(),
+	}
+}
+
+// GetEventID - implement domain.Event
+func (e *EventBody) GetEventID() string {
+	return e.ID
+}
+
+// GetType - implement domain.Event
+func (e *EventBody) GetType() domain.EventType {
+	return e.Type
+}
+
+// GetUserID - implement domain.Event
+func (e *EventBody) GetUserID() string {
+	return e.UserID
+}
+
+// GetPayload - implement domain.Event
+func (e *EventBody) GetPayload() map[string]string {
+	return e.Payload
+}
+
+// GetOccurredAt - implement domain.Event
+func (e *EventBody) GetOccurredAt() time.Time {
+	return e.OccurredAt
+}
@@ -0,0 +1,57 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/event/repository/body"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoEventOutbox struct {
+	outboxColl *mongo.Collection
+}
+
+// NewMongoEventOutbox will create an object that represent the domain.EventOutbox interface
+func NewMongoEventOutbox(coll *mongo.Collection) domain.EventOutbox {
+	return &mongoEventOutbox{coll}
+}
+
+// Publish - 写入发件箱, 在事务 ctx 中调用时 与业务数据一同提交
+func (m *mongoEventOutbox) Publish(ctx context.Context, event domain.Event) error {
+	_, err := m.outboxColl.InsertOne(ctx, &body.EventBody{
+		ID:         event.GetEventID(),
+		Type:       event.GetType(),
+		UserID:     event.GetUserID(),
+		Payload:    event.GetPayload(),
+		OccurredAt: event.GetOccurredAt(),
+	})
+	return err
+}
+
+func (m *mongoEventOutbox) ListPending(ctx context.Context, limit int64) ([]domain.Event, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "occurred_at", Value: 1}}).SetLimit(limit)
+	cur, err := m.outboxColl.Find(ctx, bson.M{"published_at": bson.M{"$exists": false}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var events []domain.Event
+	for cur.Next(ctx) {
+		var e body.EventBody
+		if err := cur.Decode(&e); err != nil {
+			return nil, err
+		}
+		events = append(events, &e)
+	}
+	return events, cur.Err()
+}
+
+func (m *mongoEventOutbox) MarkPublished(ctx context.Context, eventID string) error {
+	_, err := m.outboxColl.UpdateOne(ctx, bson.M{"_id": eventID}, bson.M{"$set": bson.M{"published_at": time.Now()}})
+	return err
+}
@@ -0,0 +1,121 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/event/repository/body"
+	mongorepo "github.com/alibug/go-identity-entry/event/repository/mongodb"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testDB - 需要 MONGO_TEST_URI 指向一个可用的 MongoDB, 否则跳过
+func testDB(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database(fmt.Sprintf("identity_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = db.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return db
+}
+
+func TestOutboxListAndMarkPublished(t *testing.T) {
+	outbox := mongorepo.NewMongoEventOutbox(testDB(t).Collection("event_outbox"))
+	ctx := context.Background()
+
+	// 写入顺序与发生顺序相反, ListPending 按发生时间返回
+	base := time.Now().Truncate(time.Millisecond)
+	var events []*body.EventBody
+	for i := 0; i < 3; i++ {
+		e := body.NewEventBody(domain.EventUserRegistered, "u1", map[string]string{"n": fmt.Sprint(i)})
+		e.OccurredAt = base.Add(time.Duration(i) * time.Second)
+		events = append(events, e)
+	}
+	for i := len(events) - 1; i >= 0; i-- {
+		if err := outbox.Publish(ctx, events[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pending, err := outbox.ListPending(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].GetEventID() != events[0].GetEventID() || pending[1].GetEventID() != events[1].GetEventID() {
+		t.Fatalf("ListPending(2) = %v", pending)
+	}
+	if p := pending[0]; p.GetType() != domain.EventUserRegistered || p.GetUserID() != "u1" || p.GetPayload()["n"] != "0" || !p.GetOccurredAt().Equal(base) {
+		t.Fatalf("decoded event = %+v", p)
+	}
+
+	// 标记后 不再返回
+	if err := outbox.MarkPublished(ctx, events[0].GetEventID()); err != nil {
+		t.Fatal(err)
+	}
+	pending, err = outbox.ListPending(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 2 || pending[0].GetEventID() != events[1].GetEventID() || pending[1].GetEventID() != events[2].GetEventID() {
+		t.Fatalf("ListPending after MarkPublished = %v", pending)
+	}
+}
+
+func TestOutboxTransaction(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	if err := mongorepo.CheckTransactions(ctx, db.Client()); errors.Is(err, mongorepo.ErrTransactionsUnsupported) {
+		t.Skip("MONGO_TEST_URI is not a replica set")
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	// 事务中不能隐式创建集合 (4.4 以前)
+	if err := db.CreateCollection(ctx, "event_outbox"); err != nil {
+		t.Fatal(err)
+	}
+	outbox := mongorepo.NewMongoEventOutbox(db.Collection("event_outbox"))
+	tx := mongorepo.NewMongoTransactor(db.Client())
+
+	// 事务回滚时 事件一同丢弃
+	rollback := errors.New("rollback")
+	err := tx.WithTransaction(ctx, func(ctx context.Context) error {
+		if err := outbox.Publish(ctx, body.NewEventBody(domain.EventUserRegistered, "u1", nil)); err != nil {
+			return err
+		}
+		return rollback
+	})
+	if !errors.Is(err, rollback) {
+		t.Fatalf("WithTransaction = %v, want rollback", err)
+	}
+	if pending, _ := outbox.ListPending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("pending after rollback = %d, want 0", len(pending))
+	}
+
+	committed := body.NewEventBody(domain.EventUserRegistered, "u2", nil)
+	if err := tx.WithTransaction(ctx, func(ctx context.Context) error { return outbox.Publish(ctx, committed) }); err != nil {
+		t.Fatal(err)
+	}
+	if pending, _ := outbox.ListPending(ctx, 10); len(pending) != 1 || pending[0].GetEventID() != committed.GetEventID() {
+		t.Fatalf("pending after commit = %v", pending)
+	}
+}
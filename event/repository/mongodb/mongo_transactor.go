@@ -0,0 +1,50 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrTransactionsUnsupported - 单机部署的 MongoDB 不支持多文档事务
+var ErrTransactionsUnsupported = errors.New("mongo deployment does not support transactions, a replica set or sharded cluster is required")
+
+type mongoTransactor struct {
+	client *mongo.Client
+}
+
+// NewMongoTransactor - 使用 MongoDB 多文档事务, 需要副本集或分片集群
+func NewMongoTransactor(client *mongo.Client) domain.Transactor {
+	return &mongoTransactor{client}
+}
+
+func (m *mongoTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := m.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+// CheckTransactions - 启动时确认部署为副本集或分片集群, 避免每次写入时才因不支持事务而失败
+func CheckTransactions(ctx context.Context, client *mongo.Client) error {
+	var reply struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "isMaster", Value: 1}}).Decode(&reply); err != nil {
+		return err
+	}
+	if reply.SetName == "" && reply.Msg != "isdbgrid" {
+		return ErrTransactionsUnsupported
+	}
+	return nil
+}
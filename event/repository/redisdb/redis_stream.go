@@ -0,0 +1,41 @@
+package redisdb
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/go-redis/redis/v8"
+)
+
+// streamMaxLen - Stream 保留的大致事件条数
+const streamMaxLen = 100000
+
+type redisStreamPublisher struct {
+	client redis.UniversalClient
+	stream string
+}
+
+// NewRedisStreamPublisher - 将事件 XADD 到指定的 Redis Stream
+func NewRedisStreamPublisher(client redis.UniversalClient, stream string) domain.EventPublisher {
+	return &redisStreamPublisher{client, stream}
+}
+
+func (r *redisStreamPublisher) Publish(ctx context.Context, event domain.Event) error {
+	payload, err := json.Marshal(event.GetPayload())
+	if err != nil {
+		return err
+	}
+	return r.client.XAdd(ctx, &redis.XAddArgs{
+		Stream:       r.stream,
+		MaxLenApprox: streamMaxLen,
+		Values: map[string]interface{}{
+			"id":          event.GetEventID(),
+			"type":        string(event.GetType()),
+			"user_id":     event.GetUserID(),
+			"occurred_at": event.GetOccurredAt().Format(time.RFC3339Nano),
+			"payload":     string(payload),
+		},
+	}).Err()
+}
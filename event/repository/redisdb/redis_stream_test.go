@@ -0,0 +1,67 @@
+package redisdb_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/event/repository/body"
+	"github.com/alibug/go-identity-entry/event/repository/redisdb"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestRedisStreamPublisher(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	publisher := redisdb.NewRedisStreamPublisher(client, "identity-events")
+
+	registered := body.NewEventBody(domain.EventUserRegistered, "u1", map[string]string{"account": "alice"})
+	loggedIn := body.NewEventBody(domain.EventUserLoggedIn, "u1", nil)
+	for _, e := range []domain.Event{registered, loggedIn} {
+		if err := publisher.Publish(ctx, e); err != nil {
+			t.Fatalf("Publish(%s): %v", e.GetType(), err)
+		}
+	}
+
+	msgs, err := client.XRange(ctx, "identity-events", "-", "+").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("stream length = %d, want 2", len(msgs))
+	}
+	// 按发布顺序追加
+	first, second := msgs[0].Values, msgs[1].Values
+	if first["id"] != registered.GetEventID() || second["id"] != loggedIn.GetEventID() {
+		t.Fatalf("stream ids = %v, %v", first["id"], second["id"])
+	}
+	if first["type"] != string(domain.EventUserRegistered) || first["user_id"] != "u1" {
+		t.Fatalf("first entry = %v", first)
+	}
+	occurredAt, err := time.Parse(time.RFC3339Nano, first["occurred_at"].(string))
+	if err != nil || !occurredAt.Equal(registered.GetOccurredAt()) {
+		t.Fatalf("occurred_at = %v, %v, want %v", first["occurred_at"], err, registered.GetOccurredAt())
+	}
+	var payload map[string]string
+	if err := json.Unmarshal([]byte(first["payload"].(string)), &payload); err != nil || payload["account"] != "alice" {
+		t.Fatalf("payload = %v, %v", first["payload"], err)
+	}
+}
+
+func TestRedisStreamPublisherError(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+	defer client.Close()
+	publisher := redisdb.NewRedisStreamPublisher(client, "identity-events")
+
+	// Redis 不可用时 返回 error, 事件留在发件箱中 由 relay 重试
+	mr.Close()
+	if err := publisher.Publish(context.Background(), body.NewEventBody(domain.EventUserLoggedOut, "u1", nil)); err == nil {
+		t.Fatal("Publish with redis down = nil, want an error")
+	}
+}
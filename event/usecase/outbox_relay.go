@@ -0,0 +1,79 @@
+package usecase
+
+import (
+	"context"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"go.uber.org/zap"
+)
+
+// batchSize - 每次从发件箱读取的事件条数
+const batchSize = 100
+
+// OutboxRelay - 将发件箱中的事件 转发给各个 EventPublisher
+type OutboxRelay struct {
+	outbox         domain.EventOutbox
+	publishers     []domain.EventPublisher
+	contextTimeout time.Duration
+}
+
+// NewOutboxRelay will create new an OutboxRelay
+func NewOutboxRelay(outbox domain.EventOutbox, timeout time.Duration, publishers ...domain.EventPublisher) *OutboxRelay {
+	return &OutboxRelay{
+		outbox:         outbox,
+		publishers:     publishers,
+		contextTimeout: timeout,
+	}
+}
+
+// RelayOnce - 转发一批待发送事件, 返回成功转发的条数
+// 任一 publisher 失败时 事件保留在发件箱中 下次重试, 因此投递语义为 at-least-once
+func (r *OutboxRelay) RelayOnce(c context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
+	defer cancel()
+
+	events, err := r.outbox.ListPending(ctx, batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	relayed := 0
+	for _, event := range events {
+		for _, p := range r.publishers {
+			if err := p.Publish(ctx, event); err != nil {
+				return relayed, err
+			}
+		}
+		if err := r.outbox.MarkPublished(ctx, event.GetEventID()); err != nil {
+			return relayed, err
+		}
+		relayed++
+	}
+	return relayed, nil
+}
+
+// Run - 按 interval 轮询发件箱, 直到 ctx 结束
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// 一次读满 说明还有积压, 继续转发
+			for {
+				n, err := r.RelayOnce(ctx)
+				if err != nil {
+					logging.FromContext(ctx).Error("relay domain events failed", zap.Error(err))
+					break
+				}
+				if n < batchSize {
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/event/repository/body"
+	"github.com/alibug/go-identity-entry/event/usecase"
+)
+
+// memoryOutbox - 按写入顺序保存事件
+type memoryOutbox struct {
+	events    []domain.Event
+	published map[string]bool
+}
+
+func newMemoryOutbox(events ...domain.Event) *memoryOutbox {
+	return &memoryOutbox{events: events, published: map[string]bool{}}
+}
+
+func (m *memoryOutbox) Publish(ctx context.Context, event domain.Event) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *memoryOutbox) ListPending(ctx context.Context, limit int64) ([]domain.Event, error) {
+	var pending []domain.Event
+	for _, e := range m.events {
+		if !m.published[e.GetEventID()] && int64(len(pending)) < limit {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (m *memoryOutbox) MarkPublished(ctx context.Context, eventID string) error {
+	m.published[eventID] = true
+	return nil
+}
+
+// recordingPublisher - 记录收到的 eventID; failOn 中的事件 在失败次数用完之前返回 error
+type recordingPublisher struct {
+	received []string
+	failOn   map[string]int
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event domain.Event) error {
+	if p.failOn[event.GetEventID()] > 0 {
+		p.failOn[event.GetEventID()]--
+		return errors.New("publisher unavailable")
+	}
+	p.received = append(p.received, event.GetEventID())
+	return nil
+}
+
+func newEvents(n int) []domain.Event {
+	events := make([]domain.Event, n)
+	for i := range events {
+		events[i] = body.NewEventBody(domain.EventUserRegistered, "u1", nil)
+	}
+	return events
+}
+
+func eventIDs(events []domain.Event) []string {
+	ids := make([]string, len(events))
+	for i, e := range events {
+		ids[i] = e.GetEventID()
+	}
+	return ids
+}
+
+func TestRelayOnce(t *testing.T) {
+	ctx := context.Background()
+	events := newEvents(3)
+	outbox := newMemoryOutbox(events...)
+	stream, webhooks := &recordingPublisher{}, &recordingPublisher{}
+	relay := usecase.NewOutboxRelay(outbox, time.Second, stream, webhooks)
+
+	n, err := relay.RelayOnce(ctx)
+	if err != nil || n != 3 {
+		t.Fatalf("RelayOnce = %d, %v, want 3, nil", n, err)
+	}
+	// 每个 publisher 都按发生顺序收到全部事件
+	for _, p := range []*recordingPublisher{stream, webhooks} {
+		if !reflect.DeepEqual(p.received, eventIDs(events)) {
+			t.Fatalf("received = %v, want %v", p.received, eventIDs(events))
+		}
+	}
+	if pending, _ := outbox.ListPending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("pending after relay = %d, want 0", len(pending))
+	}
+
+	// 已转发的事件 不再转发
+	if n, err := relay.RelayOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("second RelayOnce = %d, %v, want 0, nil", n, err)
+	}
+}
+
+func TestRelayOnceRetriesFailedEvent(t *testing.T) {
+	ctx := context.Background()
+	events := newEvents(3)
+	outbox := newMemoryOutbox(events...)
+	second := events[1].GetEventID()
+	stream := &recordingPublisher{}
+	webhooks := &recordingPublisher{failOn: map[string]int{second: 1}}
+	relay := usecase.NewOutboxRelay(outbox, time.Second, stream, webhooks)
+
+	// 第二个事件在 webhooks 失败: 转发停止, 之后的事件不会越过它
+	n, err := relay.RelayOnce(ctx)
+	if err == nil || n != 1 {
+		t.Fatalf("RelayOnce = %d, %v, want 1 and an error", n, err)
+	}
+	pending, _ := outbox.ListPending(ctx, 10)
+	if !reflect.DeepEqual(eventIDs(pending), eventIDs(events[1:])) {
+		t.Fatalf("pending = %v, want %v", eventIDs(pending), eventIDs(events[1:]))
+	}
+	if !reflect.DeepEqual(webhooks.received, eventIDs(events[:1])) {
+		t.Fatalf("webhooks received = %v, want %v", webhooks.received, eventIDs(events[:1]))
+	}
+
+	// 重试时 从失败的事件继续; 已成功的 publisher 会再次收到它 (at-least-once)
+	n, err = relay.RelayOnce(ctx)
+	if err != nil || n != 2 {
+		t.Fatalf("retry RelayOnce = %d, %v, want 2, nil", n, err)
+	}
+	if !reflect.DeepEqual(webhooks.received, eventIDs(events)) {
+		t.Fatalf("webhooks received = %v, want %v", webhooks.received, eventIDs(events))
+	}
+	want := []string{events[0].GetEventID(), second, second, events[2].GetEventID()}
+	if !reflect.DeepEqual(stream.received, want) {
+		t.Fatalf("stream received = %v, want %v", stream.received, want)
+	}
+}
+
+// notifyingPublisher - 收到 want 个事件后 关闭 done
+type notifyingPublisher struct {
+	recordingPublisher
+	want int
+	done chan struct{}
+}
+
+func (p *notifyingPublisher) Publish(ctx context.Context, event domain.Event) error {
+	p.recordingPublisher.Publish(ctx, event)
+	if len(p.received) == p.want {
+		close(p.done)
+	}
+	return nil
+}
+
+func TestRunRelaysBacklog(t *testing.T) {
+	// 超过一批的积压 依次全部转发, ctx 结束后 Run 返回
+	events := newEvents(250)
+	outbox := newMemoryOutbox(events...)
+	stream := &notifyingPublisher{want: len(events), done: make(chan struct{})}
+	relay := usecase.NewOutboxRelay(outbox, time.Second, stream)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		relay.Run(ctx, 10*time.Millisecond)
+		close(stopped)
+	}()
+	select {
+	case <-stream.done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("backlog not relayed")
+	}
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after ctx was cancelled")
+	}
+	if !reflect.DeepEqual(stream.received, eventIDs(events)) {
+		t.Fatal("events relayed out of order")
+	}
+}
@@ -4,12 +4,30 @@ go 1.16
 
 require (
 	github.com/alibug/go-identity-utils v0.1.12
+	github.com/alicebob/miniredis/v2 v2.17.0
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
-	github.com/gin-gonic/gin v1.7.2
-	github.com/go-redis/redis/v8 v8.10.0
-	github.com/google/uuid v1.2.0
-	go.mongodb.org/mongo-driver v1.5.3
+	github.com/gin-gonic/gin v1.7.7
+	github.com/go-playground/validator/v10 v10.4.1
+	github.com/go-redis/redis/v8 v8.11.5
+	github.com/google/uuid v1.3.0
+	github.com/lib/pq v1.10.9
+	github.com/prometheus/client_golang v1.12.2
+	github.com/spf13/viper v1.7.1
+	go.mongodb.org/mongo-driver v1.9.0
+	go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin v0.32.0
+	go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo v0.32.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.7.0
+	go.opentelemetry.io/otel/exporters/stdout/stdouttrace v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+	go.uber.org/zap v1.21.0
 	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
+	golang.org/x/text v0.3.6
+	google.golang.org/genproto v0.0.0-20211118181313-81c1377c94b1
+	google.golang.org/grpc v1.46.2
+	google.golang.org/protobuf v1.28.0
+	modernc.org/sqlite v1.14.6
 )
 
 // replace github.com/alibug/go-identity-utils => ../go-identity-utils
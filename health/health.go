@@ -0,0 +1,172 @@
+// Package health - Kubernetes 探针
+//
+// /healthz 只表示进程存活; /readyz 在超时时间内并发执行所有依赖检查 (MongoDB、Redis、签名密钥),
+// 全部通过时返回 200, 否则返回 503. 开始优雅退出后 /readyz 总是返回 503, 使流量不再转发到本实例.
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// 检查结果的 status
+const (
+	StatusOK           = "ok"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+)
+
+// Check - 依赖可用时返回 nil
+type Check func(ctx context.Context) error
+
+// Result - 单个依赖的检查结果; 错误原因只写入日志, 响应中不包含连接地址等细节
+type Result struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report - /readyz 的响应
+type Report struct {
+	Status string             `json:"status"`
+	Checks map[string]*Result `json:"checks"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker - 就绪检查
+type Checker struct {
+	checks       []namedCheck
+	timeout      time.Duration
+	shuttingDown int32
+}
+
+// Option - NewChecker 可选配置
+type Option func(*Checker)
+
+// WithCheck - 添加一个依赖检查, name 为响应中 checks 的键
+func WithCheck(name string, check Check) Option {
+	return func(c *Checker) {
+		c.checks = append(c.checks, namedCheck{name: name, check: check})
+	}
+}
+
+// WithTimeout - 所有检查共同的超时时间, 默认 2 秒; 应小于探针的 timeoutSeconds
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Checker) {
+		c.timeout = timeout
+	}
+}
+
+// NewChecker - 创建 Checker
+func NewChecker(opts ...Option) *Checker {
+	c := &Checker{timeout: 2 * time.Second}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetShuttingDown - 开始优雅退出, 此后 Ready 总是失败
+func (h *Checker) SetShuttingDown() {
+	atomic.StoreInt32(&h.shuttingDown, 1)
+}
+
+// ShuttingDown - 是否已开始优雅退出
+func (h *Checker) ShuttingDown() bool {
+	return atomic.LoadInt32(&h.shuttingDown) == 1
+}
+
+// Ready - 并发执行所有检查; 优雅退出期间 不再执行检查
+func (h *Checker) Ready(ctx context.Context) (bool, *Report) {
+	report := &Report{Status: StatusOK, Checks: make(map[string]*Result, len(h.checks))}
+	if h.ShuttingDown() {
+		report.Status = StatusShuttingDown
+		return false, report
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, nc := range h.checks {
+		wg.Add(1)
+		go func(nc namedCheck) {
+			defer wg.Done()
+			result := h.run(ctx, nc)
+			mu.Lock()
+			report.Checks[nc.name] = result
+			mu.Unlock()
+		}(nc)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusUnavailable
+			return false, report
+		}
+	}
+	return true, report
+}
+
+// run - 检查未在超时前返回时 按超时处理, 不等待其结束
+func (h *Checker) run(ctx context.Context, nc namedCheck) *Result {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- nc.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	result := &Result{Status: StatusOK, LatencyMS: float64(time.Since(start).Microseconds()) / 1000}
+	if err != nil {
+		result.Status = StatusUnavailable
+		result.Error = "unavailable"
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = "timeout"
+		}
+		logging.FromContext(ctx).Warn("readiness check failed", zap.String("check", nc.name), zap.Error(err))
+	}
+	return result
+}
+
+// NewHandler - 注册 /healthz 与 /readyz
+func NewHandler(route gin.IRoutes, h *Checker) {
+	route.GET("/healthz", Live)
+	route.GET("/readyz", h.Readyz)
+}
+
+// Live - 进程能够处理请求 即为存活; 不检查依赖, 以免依赖故障时 Kubernetes 反复重启实例
+func Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+}
+
+// Readyz - 就绪时返回 200, 否则返回 503
+func (h *Checker) Readyz(c *gin.Context) {
+	ready, report := h.Ready(c.Request.Context())
+	code := http.StatusOK
+	if !ready {
+		code = http.StatusServiceUnavailable
+	}
+	c.JSON(code, report)
+}
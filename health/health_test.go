@@ -0,0 +1,83 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/health"
+	"github.com/gin-gonic/gin"
+)
+
+func ok(context.Context) error { return nil }
+
+func get(t *testing.T, checker *health.Checker, path string) (int, *health.Report) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	health.NewHandler(engine, checker)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var report health.Report
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return w.Code, &report
+}
+
+func TestReady(t *testing.T) {
+	checker := health.NewChecker(health.WithCheck("mongo", ok), health.WithCheck("redis", ok))
+	code, report := get(t, checker, "/readyz")
+	if code != http.StatusOK || report.Status != health.StatusOK || len(report.Checks) != 2 {
+		t.Fatalf("/readyz = %d %+v", code, report)
+	}
+	for name, result := range report.Checks {
+		if result.Status != health.StatusOK || result.Error != "" {
+			t.Errorf("%s = %+v", name, result)
+		}
+	}
+}
+
+func TestNotReady(t *testing.T) {
+	checker := health.NewChecker(
+		health.WithTimeout(50*time.Millisecond),
+		health.WithCheck("signing_keys", ok),
+		health.WithCheck("mongo", func(context.Context) error { return errors.New("dial tcp 10.0.0.5:27017: connection refused") }),
+		// 忽略 ctx 的检查 也会在超时后返回
+		health.WithCheck("redis", func(context.Context) error { time.Sleep(time.Second); return nil }),
+	)
+	start := time.Now()
+	code, report := get(t, checker, "/readyz")
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("/readyz took %v, want it bounded by the timeout", elapsed)
+	}
+	if code != http.StatusServiceUnavailable || report.Status != health.StatusUnavailable {
+		t.Fatalf("/readyz = %d %+v", code, report)
+	}
+	want := map[string]string{"signing_keys": "", "mongo": "unavailable", "redis": "timeout"}
+	for name, wantErr := range want {
+		result := report.Checks[name]
+		if result == nil || result.Error != wantErr {
+			t.Errorf("%s = %+v, want error %q", name, result, wantErr)
+		}
+	}
+}
+
+func TestShuttingDown(t *testing.T) {
+	checker := health.NewChecker(health.WithCheck("mongo", ok))
+	checker.SetShuttingDown()
+
+	code, report := get(t, checker, "/readyz")
+	if code != http.StatusServiceUnavailable || report.Status != health.StatusShuttingDown {
+		t.Fatalf("/readyz = %d %+v", code, report)
+	}
+	// 退出期间 仍然存活, 以免被强制重启
+	if code, report := get(t, checker, "/healthz"); code != http.StatusOK || report.Status != health.StatusOK {
+		t.Fatalf("/healthz = %d %+v", code, report)
+	}
+}
@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/requestid"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Middleware - 为每个请求创建带 request_id 的 logger 并写入 request context, 请求结束后记录一条访问日志;
+// 须在 requestid.Middleware 之后使用, 在 otelgin.Middleware 之后使用时 同时带有 trace_id.
+// 只记录 path, 不记录 query、header 与 body
+func Middleware(base *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		fields := []zap.Field{zap.String("request_id", requestid.FromContext(c.Request.Context()))}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			fields = append(fields, zap.String("trace_id", sc.TraceID().String()))
+		}
+		logger := base.With(fields...)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), logger))
+
+		c.Next()
+
+		code := c.Writer.Status()
+		level := zapcore.InfoLevel
+		if code >= 500 {
+			level = zapcore.ErrorLevel
+		}
+		fields = []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.String("route", c.FullPath()),
+			zap.Int("status", code),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+			zap.String("user_agent", c.Request.UserAgent()),
+		}
+		if len(c.Errors) > 0 {
+			fields = append(fields, zap.String("errors", c.Errors.String()))
+		}
+		if ce := logger.Check(level, "request"); ce != nil {
+			ce.Write(fields...)
+		}
+	}
+}
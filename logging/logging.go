@@ -0,0 +1,70 @@
+// Package logging - JSON 结构化日志
+//
+// logger 通过 context 在 handler、usecase 与 repository 之间传递, 请求的 logger 带有 request_id;
+// 密码、token、cookie 等字段在写出之前 统一脱敏, 见 redact.go.
+package logging
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type ctxKey struct{}
+
+type config struct {
+	level  string
+	output io.Writer
+}
+
+// Option - New 可选配置
+type Option func(*config)
+
+// WithLevel - debug, info, warn, error, 默认 info
+func WithLevel(level string) Option {
+	return func(c *config) {
+		c.level = level
+	}
+}
+
+// WithOutput - 默认写入 stdout
+func WithOutput(w io.Writer) Option {
+	return func(c *config) {
+		c.output = w
+	}
+}
+
+// New - 创建 JSON 格式的 logger, 所有输出都经过脱敏
+func New(opts ...Option) (*zap.Logger, error) {
+	c := &config{level: "info", output: os.Stdout}
+	for _, opt := range opts {
+		opt(c)
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(c.level)); err != nil {
+		return nil, err
+	}
+
+	encoderConfig := zap.NewProductionEncoderConfig()
+	encoderConfig.TimeKey = "time"
+	encoderConfig.MessageKey = "msg"
+	encoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.AddSync(c.output), level)
+	return zap.New(NewRedactingCore(core), zap.AddCaller(), zap.AddStacktrace(zapcore.ErrorLevel)), nil
+}
+
+// NewContext - 返回带有 logger 的 context
+func NewContext(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, logger)
+}
+
+// FromContext - 没有时 返回全局 logger (zap.L), 由 main 通过 zap.ReplaceGlobals 设置
+func FromContext(ctx context.Context) *zap.Logger {
+	if logger, ok := ctx.Value(ctxKey{}).(*zap.Logger); ok {
+		return logger
+	}
+	return zap.L()
+}
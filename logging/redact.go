@@ -0,0 +1,128 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Redacted - 替换敏感内容的占位符
+const Redacted = "[REDACTED]"
+
+// sensitiveWords - 字段名包含其中之一时 整个值被替换
+var sensitiveWords = []string{"password", "passwd", "secret", "cookie", "authorization", "credential"}
+
+// secretPattern - 出现在任意字符串中的 JWT 与 Bearer token
+var secretPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*|(?i:bearer\s+)[A-Za-z0-9._~+/=-]+`)
+
+// SensitiveKey - 字段名是否需要脱敏; token_id 等标识 不视为敏感
+func SensitiveKey(key string) bool {
+	k := strings.ToLower(key)
+	for _, w := range sensitiveWords {
+		if strings.Contains(k, w) {
+			return true
+		}
+	}
+	if strings.Contains(k, "token") {
+		return !strings.HasSuffix(k, "id") && !strings.HasSuffix(k, "type")
+	}
+	return false
+}
+
+// RedactString - 替换字符串中的 JWT 与 Bearer token
+func RedactString(s string) string {
+	return secretPattern.ReplaceAllString(s, Redacted)
+}
+
+// redactingCore - 在写出之前 脱敏 message 与所有字段
+type redactingCore struct {
+	zapcore.Core
+}
+
+// NewRedactingCore - 包装 core, 使其所有输出经过脱敏
+func NewRedactingCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *redactingCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = RedactString(ent.Message)
+	return c.Core.Write(ent, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	out := make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		out[i] = redactField(f)
+	}
+	return out
+}
+
+func redactField(f zapcore.Field) zapcore.Field {
+	if SensitiveKey(f.Key) {
+		return zap.String(f.Key, Redacted)
+	}
+	switch f.Type {
+	case zapcore.StringType:
+		return zap.String(f.Key, RedactString(f.String))
+	case zapcore.ErrorType:
+		if err, ok := f.Interface.(error); ok {
+			return zap.String(f.Key, RedactString(err.Error()))
+		}
+	case zapcore.StringerType:
+		if s, ok := f.Interface.(fmt.Stringer); ok {
+			return zap.String(f.Key, RedactString(s.String()))
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.ReflectType:
+		// 嵌套的值 先按 JSON 展开, 再逐层脱敏
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		raw, err := json.Marshal(enc.Fields[f.Key])
+		if err != nil {
+			return zap.String(f.Key, Redacted)
+		}
+		var v interface{}
+		if err := json.Unmarshal(raw, &v); err != nil {
+			return zap.String(f.Key, Redacted)
+		}
+		return zap.Any(f.Key, scrub(v))
+	}
+	return f
+}
+
+func scrub(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			if SensitiveKey(k) {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = scrub(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scrub(item)
+		}
+		return v
+	case string:
+		return RedactString(v)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute - 未匹配路由的请求 统一记为一个 route, 避免任意 path 成为标签
+const unmatchedRoute = "unmatched"
+
+// Middleware - 记录 HTTP handler 耗时, route 为路由模板 如 /users/:id
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		HTTPRequestDuration.
+			WithLabelValues(c.Request.Method, route, strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,125 @@
+// Package metrics - Prometheus 指标, 通过 Handler 在 /metrics 暴露
+//
+// 业务计数 (登录、注册、退出、token 签发与校验) 在 usecase 中记录,
+// 密码哈希耗时在 password 包中记录, MongoDB 与 Redis 的调用耗时 由客户端的 monitor/hook 记录.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "identity"
+
+// 结果标签的取值; 失败时 reason 为 domain.ErrorCode
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// ReasonError - 未带错误码的错误
+const ReasonError = "error"
+
+var (
+	// LoginsTotal - 账号密码校验次数, reason 为失败原因
+	LoginsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	// RegistrationsTotal - 注册次数
+	RegistrationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Registrations by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	// LogoutsTotal - 退出登录次数
+	LogoutsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logouts_total",
+		Help:      "Logouts by result and failure reason.",
+	}, []string{"result", "reason"})
+
+	// TokensIssuedTotal - 签发的 token 数, type 为 access 或 refresh
+	TokensIssuedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "tokens_issued_total",
+		Help:      "Tokens issued by type.",
+	}, []string{"type"})
+
+	// TokenVerificationsTotal - token 校验次数
+	TokenVerificationsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_verifications_total",
+		Help:      "Token verifications by type, result and failure reason.",
+	}, []string{"type", "result", "reason"})
+
+	// HTTPRequestDuration - HTTP handler 耗时, route 为路由模板
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// PasswordHashDuration - 密码哈希与校验的耗时, operation 为 hash 或 verify
+	PasswordHashDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Password hashing latency by algorithm and operation.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"algorithm", "operation"})
+
+	// StoreCallDuration - MongoDB 与 Redis 的调用耗时, store 为 mongo 或 redis
+	StoreCallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "store_call_duration_seconds",
+		Help:      "MongoDB and Redis call latency by store, command and result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"store", "command", "result"})
+)
+
+// registry - 不使用 prometheus.DefaultRegisterer, 只暴露本服务注册的指标
+var registry = prometheus.NewRegistry()
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		LoginsTotal,
+		RegistrationsTotal,
+		LogoutsTotal,
+		TokensIssuedTotal,
+		TokenVerificationsTotal,
+		HTTPRequestDuration,
+		PasswordHashDuration,
+		StoreCallDuration,
+	)
+}
+
+// Handler - /metrics 的 handler
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Outcome - err 为 nil 时返回 success 与空 reason, 否则返回 failure 与错误码
+func Outcome(err error) (result string, reason string) {
+	if err == nil {
+		return ResultSuccess, ""
+	}
+	return ResultFailure, Reason(err)
+}
+
+// Reason - 带错误码的错误 返回错误码, 其他返回 error; 错误码是有限集合, 不会造成标签基数膨胀
+func Reason(err error) string {
+	if coded := domain.AsCoded(err); coded != nil {
+		return string(coded.ErrorCode())
+	}
+	return ReasonError
+}
@@ -0,0 +1,119 @@
+package metrics_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/metrics"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// scrape - 读取 Handler 的输出
+func scrape(t *testing.T) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("/metrics status = %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func assertContains(t *testing.T, body string, lines ...string) {
+	t.Helper()
+	for _, line := range lines {
+		if !strings.Contains(body, line) {
+			t.Errorf("/metrics missing %q", line)
+		}
+	}
+}
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		err    error
+		result string
+		reason string
+	}{
+		{nil, metrics.ResultSuccess, ""},
+		{fmt.Errorf("login: %w", domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidCredentials, "invalid")), metrics.ResultFailure, "invalid_credentials"},
+		{domain.ErrTokenExpired, metrics.ResultFailure, "token_expired"},
+		{status.ErrInternalServerError, metrics.ResultFailure, metrics.ReasonError},
+	}
+	for _, tt := range tests {
+		if result, reason := metrics.Outcome(tt.err); result != tt.result || reason != tt.reason {
+			t.Errorf("Outcome(%v) = %s %s, want %s %s", tt.err, result, reason, tt.result, tt.reason)
+		}
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(metrics.Middleware())
+	engine.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/42", nil))
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/no/such/path", nil))
+
+	body := scrape(t)
+	assertContains(t, body,
+		`identity_http_request_duration_seconds_count{method="GET",route="/users/:id",status="200"} 1`,
+		`identity_http_request_duration_seconds_count{method="GET",route="unmatched",status="404"} 1`,
+		"go_goroutines",
+	)
+	// 只记录路由模板, 不记录实际 path
+	if strings.Contains(body, "/users/42") || strings.Contains(body, "/no/such/path") {
+		t.Error("/metrics contains a raw path")
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	client.AddHook(metrics.RedisHook())
+	ctx := context.Background()
+
+	client.Set(ctx, "k", "v", 0)
+	// 键不存在 不视为失败
+	client.Get(ctx, "missing")
+	if _, err := client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Get(ctx, "k")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := scrape(t)
+	assertContains(t, body,
+		`identity_store_call_duration_seconds_count{command="set",result="success",store="redis"} 1`,
+		`identity_store_call_duration_seconds_count{command="get",result="success",store="redis"} 1`,
+		`identity_store_call_duration_seconds_count{command="pipeline",result="success",store="redis"} 1`,
+	)
+	if strings.Contains(body, `result="failure",store="redis"`) {
+		t.Error("redis call recorded as failure")
+	}
+}
+
+func TestMongoMonitor(t *testing.T) {
+	monitor := metrics.MongoMonitor()
+	monitor.Succeeded(context.Background(), &event.CommandSucceededEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "find", DurationNanos: 2e6},
+	})
+	monitor.Failed(context.Background(), &event.CommandFailedEvent{
+		CommandFinishedEvent: event.CommandFinishedEvent{CommandName: "insert", DurationNanos: 1e6},
+	})
+
+	assertContains(t, scrape(t),
+		`identity_store_call_duration_seconds_sum{command="find",result="success",store="mongo"} 0.002`,
+		`identity_store_call_duration_seconds_count{command="insert",result="failure",store="mongo"} 1`,
+	)
+}
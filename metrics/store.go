@@ -0,0 +1,75 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+// MongoMonitor - 设置到 options.Client().SetMonitor, 记录每个命令的耗时
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			observeMongo(e.CommandFinishedEvent, ResultSuccess)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			observeMongo(e.CommandFinishedEvent, ResultFailure)
+		},
+	}
+}
+
+func observeMongo(e event.CommandFinishedEvent, result string) {
+	StoreCallDuration.
+		WithLabelValues("mongo", e.CommandName, result).
+		Observe(time.Duration(e.DurationNanos).Seconds())
+}
+
+type startKey struct{}
+
+// redisHook - implement redis.Hook
+type redisHook struct{}
+
+// RedisHook - 通过 client.AddHook 添加, 记录每个命令的耗时; pipeline 记为一次 pipeline 调用
+func RedisHook() redis.Hook {
+	return redisHook{}
+}
+
+func (redisHook) BeforeProcess(ctx context.Context, _ redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (redisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	observeRedis(ctx, cmd.Name(), cmd.Err())
+	return nil
+}
+
+func (redisHook) BeforeProcessPipeline(ctx context.Context, _ []redis.Cmder) (context.Context, error) {
+	return context.WithValue(ctx, startKey{}, time.Now()), nil
+}
+
+func (redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	observeRedis(ctx, "pipeline", err)
+	return nil
+}
+
+// observeRedis - redis.Nil 表示键不存在, 不视为失败
+func observeRedis(ctx context.Context, command string, err error) {
+	start, ok := ctx.Value(startKey{}).(time.Time)
+	if !ok {
+		return
+	}
+	result := ResultSuccess
+	if err != nil && err != redis.Nil {
+		result = ResultFailure
+	}
+	StoreCallDuration.WithLabelValues("redis", command, result).Observe(time.Since(start).Seconds())
+}
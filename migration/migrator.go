@@ -0,0 +1,169 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/alibug/go-identity-entry/logging"
+	"go.uber.org/zap"
+)
+
+// ErrLocked - 等待迁移锁超时
+var ErrLocked = errors.New("migration: lock is held by another process")
+
+// ErrLockLost - 执行期间 迁移锁过期 或被其他进程接管, 剩余的迁移没有执行
+var ErrLockLost = errors.New("migration: lock lost while migrating")
+
+// Migration - 一个版本化的迁移
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context) error
+	// Down - 为 nil 表示不可回滚
+	Down func(ctx context.Context) error
+}
+
+// Store - 记录已执行的迁移, 并提供跨进程的迁移锁
+type Store interface {
+	// Lock - 阻塞直到获得锁 或 ctx 结束, 返回持锁期间使用的 ctx 与释放锁的函数;
+	// 锁丢失时 返回的 ctx 被取消
+	Lock(ctx context.Context) (lockCtx context.Context, unlock func(), err error)
+	Applied(ctx context.Context) (map[int]time.Time, error)
+	Record(ctx context.Context, m Migration, at time.Time) error
+	Remove(ctx context.Context, version int) error
+}
+
+// transactional - 支持事务的 Store, 迁移与其记录在同一事务中提交,
+// 避免进程在两者之间崩溃后 结构已变更 却没有记录版本
+type transactional interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Status - 迁移及其执行状态
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrator - 按版本顺序执行迁移, 并记录在 Store 中
+type Migrator struct {
+	store      Store
+	migrations []Migration
+}
+
+// NewMigrator - migrations 会按 Version 排序, 版本号不可重复
+func NewMigrator(store Store, migrations []Migration) *Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			panic(fmt.Sprintf("migration: duplicate version %d", sorted[i].Version))
+		}
+	}
+	return &Migrator{store: store, migrations: sorted}
+}
+
+// Up - 执行所有未执行的迁移, 返回本次执行的版本
+func (m *Migrator) Up(ctx context.Context) ([]int, error) {
+	var done []int
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.store.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			logging.FromContext(ctx).Info("migration: up", zap.Int("version", mig.Version), zap.String("description", mig.Description))
+			err := m.inTransaction(ctx, func(ctx context.Context) error {
+				if err := mig.Up(ctx); err != nil {
+					return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Description, err)
+				}
+				return m.store.Record(ctx, mig, time.Now())
+			})
+			if err != nil {
+				return err
+			}
+			done = append(done, mig.Version)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down - 按版本倒序回滚最近执行的 steps 个迁移
+func (m *Migrator) Down(ctx context.Context, steps int) ([]int, error) {
+	var done []int
+	err := m.withLock(ctx, func(ctx context.Context) error {
+		applied, err := m.store.Applied(ctx)
+		if err != nil {
+			return err
+		}
+		for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == nil {
+				return fmt.Errorf("migration %d (%s) is irreversible", mig.Version, mig.Description)
+			}
+			logging.FromContext(ctx).Info("migration: down", zap.Int("version", mig.Version), zap.String("description", mig.Description))
+			err := m.inTransaction(ctx, func(ctx context.Context) error {
+				if err := mig.Down(ctx); err != nil {
+					return fmt.Errorf("migration %d (%s): %w", mig.Version, mig.Description, err)
+				}
+				return m.store.Remove(ctx, mig.Version)
+			})
+			if err != nil {
+				return err
+			}
+			done = append(done, mig.Version)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status - 所有已知迁移的执行状态
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.store.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, mig := range m.migrations {
+		s := Status{Migration: mig}
+		if at, ok := applied[mig.Version]; ok {
+			at := at
+			s.AppliedAt = &at
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// withLock - 持有迁移锁期间执行 fn; 锁丢失时 fn 的 ctx 被取消, 返回 ErrLockLost
+func (m *Migrator) withLock(ctx context.Context, fn func(ctx context.Context) error) error {
+	lockCtx, unlock, err := m.store.Lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	err = fn(lockCtx)
+	if lockCtx.Err() != nil && ctx.Err() == nil {
+		return ErrLockLost
+	}
+	return err
+}
+
+// inTransaction - Store 不支持事务时 直接执行 fn
+func (m *Migrator) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if t, ok := m.store.(transactional); ok {
+		return t.WithTransaction(ctx, fn)
+	}
+	return fn(ctx)
+}
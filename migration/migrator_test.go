@@ -0,0 +1,215 @@
+package migration_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/alibug/go-identity-entry/migration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	_ "modernc.org/sqlite"
+)
+
+// testDB - 需要 MONGO_TEST_URI 指向一个可用的 MongoDB, 否则跳过
+func testDB(t *testing.T) *mongo.Database {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := client.Database(fmt.Sprintf("migration_test_%d", time.Now().UnixNano()))
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = db.Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+	return db
+}
+
+// counting - 记录每个版本 up/down 被执行的次数
+func counting(versions ...int) ([]migration.Migration, map[int]*int32) {
+	calls := map[int]*int32{}
+	var migrations []migration.Migration
+	for _, v := range versions {
+		n := new(int32)
+		calls[v] = n
+		migrations = append(migrations, migration.Migration{
+			Version: v,
+			Up: func(context.Context) error {
+				atomic.AddInt32(n, 1)
+				return nil
+			},
+			Down: func(context.Context) error {
+				atomic.AddInt32(n, -1)
+				return nil
+			},
+		})
+	}
+	return migrations, calls
+}
+
+func TestNewMigratorDuplicateVersion(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on duplicate version")
+		}
+	}()
+	migrations, _ := counting(1, 2, 1)
+	migration.NewMigrator(nil, migrations)
+}
+
+func TestUpDown(t *testing.T) {
+	db := testDB(t)
+	ctx := context.Background()
+	migrations, calls := counting(3, 1, 2)
+	m := migration.NewMigrator(migration.NewMongoStore(db, time.Minute), migrations)
+
+	done, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(done, []int{1, 2, 3}) {
+		t.Fatalf("Up = %v, want [1 2 3]", done)
+	}
+	if done, _ := m.Up(ctx); len(done) != 0 {
+		t.Fatalf("second Up = %v, want none", done)
+	}
+
+	done, err = m.Down(ctx, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(done, []int{3, 2}) {
+		t.Fatalf("Down = %v, want [3 2]", done)
+	}
+	if *calls[1] != 1 || *calls[2] != 0 || *calls[3] != 0 {
+		t.Fatalf("calls = %d %d %d, want 1 0 0", *calls[1], *calls[2], *calls[3])
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].AppliedAt == nil || statuses[1].AppliedAt != nil || statuses[2].AppliedAt != nil {
+		t.Fatalf("unexpected status %+v", statuses)
+	}
+}
+
+func TestUpConcurrent(t *testing.T) {
+	db := testDB(t)
+	migrations, calls := counting(1, 2, 3)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if _, err := migration.NewMigrator(migration.NewMongoStore(db, time.Minute), migrations).Up(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for v, n := range calls {
+		if *n != 1 {
+			t.Errorf("migration %d ran %d times, want 1", v, *n)
+		}
+	}
+}
+
+// 不需要 MongoDB: 续期时锁已被其他进程接管, 正在执行的迁移被取消, 且不会记录
+func TestUpStopsWhenLockLost(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("lock lost", func(mt *mtest.T) {
+		mt.AddMockResponses(
+			// acquire: upsert 成功
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 0}),
+			// Applied: 没有已执行的迁移
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".schema_migrations", mtest.FirstBatch),
+			// heartbeat: 没有匹配 owner 的锁
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+			// release
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+		)
+
+		started := make(chan struct{})
+		migrations := []migration.Migration{{
+			Version: 1,
+			Up: func(ctx context.Context) error {
+				close(started)
+				<-ctx.Done()
+				return ctx.Err()
+			},
+		}}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		done, err := migration.NewMigrator(migration.NewMongoStore(mt.DB, 30*time.Millisecond), migrations).Up(ctx)
+		if !errors.Is(err, migration.ErrLockLost) {
+			t.Fatalf("Up err = %v, want ErrLockLost", err)
+		}
+		if len(done) != 0 {
+			t.Fatalf("Up = %v, want none recorded", done)
+		}
+		select {
+		case <-started:
+		default:
+			t.Fatal("migration never started")
+		}
+	})
+}
+
+// 版本记录失败时 迁移的结构变更随之回滚
+func TestSQLUpRollsBackWithRecord(t *testing.T) {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "migrate.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+
+	// 迁移自己写入了版本 1, 随后的 Record 因主键冲突失败
+	files := fstest.MapFS{
+		"migrations/0001_create_t.up.sql": {Data: []byte(`CREATE TABLE t (id INTEGER);
+			INSERT INTO schema_migrations (version, description, applied_at) VALUES (1, 'x', CURRENT_TIMESTAMP);`)},
+	}
+	m := migration.NewMigrator(migration.NewSQLiteStore(db), migration.SQLFiles(db, files, "migrations"))
+	if _, err := m.Up(ctx); err == nil {
+		t.Fatal("Up succeeded, want the record to fail")
+	}
+
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 't'`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatal("table t exists although its version was not recorded")
+	}
+	if err := db.QueryRowContext(ctx, `SELECT count(*) FROM schema_migrations`).Scan(&n); err != nil || n != 0 {
+		t.Fatalf("schema_migrations rows = %d, %v, want 0", n, err)
+	}
+}
@@ -0,0 +1,163 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+const (
+	// recordColl - 已执行的迁移, _id 为版本号
+	recordColl = "schema_migrations"
+	// lockColl - 迁移锁, 保证多副本同时启动时 只有一个在执行迁移
+	lockColl = "schema_migrations_lock"
+	lockID   = "lock"
+)
+
+type record struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+}
+
+type mongoStore struct {
+	db      *mongo.Database
+	owner   string
+	lockTTL time.Duration
+	poll    time.Duration
+}
+
+// NewMongoStore - 迁移记录保存在 schema_migrations 集合,
+// 持锁进程崩溃后, 锁在 lockTTL 后可被其他进程接管
+func NewMongoStore(db *mongo.Database, lockTTL time.Duration) Store {
+	hostname, _ := os.Hostname()
+	return &mongoStore{
+		db:      db,
+		owner:   fmt.Sprintf("%s:%s", hostname, uuid.New().String()),
+		lockTTL: lockTTL,
+		poll:    time.Second,
+	}
+}
+
+func (m *mongoStore) Applied(ctx context.Context) (map[int]time.Time, error) {
+	cur, err := m.db.Collection(recordColl).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var records []record
+	if err := cur.All(ctx, &records); err != nil {
+		return nil, err
+	}
+	applied := make(map[int]time.Time, len(records))
+	for _, r := range records {
+		applied[r.Version] = r.AppliedAt
+	}
+	return applied, nil
+}
+
+func (m *mongoStore) Record(ctx context.Context, mig Migration, at time.Time) error {
+	_, err := m.db.Collection(recordColl).InsertOne(ctx, record{
+		Version:     mig.Version,
+		Description: mig.Description,
+		AppliedAt:   at,
+	})
+	return err
+}
+
+func (m *mongoStore) Remove(ctx context.Context, version int) error {
+	_, err := m.db.Collection(recordColl).DeleteOne(ctx, bson.M{"_id": version})
+	return err
+}
+
+func (m *mongoStore) Lock(ctx context.Context) (context.Context, func(), error) {
+	if err := m.acquire(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	// 执行期间定期续期, 续期失败则取消 lockCtx, 停止迁移
+	lockCtx, lost := context.WithCancel(ctx)
+	heartbeatCtx, stop := context.WithCancel(context.Background())
+	go m.heartbeat(heartbeatCtx, lost)
+	return lockCtx, func() {
+		stop()
+		lost()
+		m.release()
+	}, nil
+}
+
+func (m *mongoStore) acquire(ctx context.Context) error {
+	coll := m.db.Collection(lockColl)
+	for {
+		now := time.Now()
+		// 锁不存在 或 已过期 时接管, 否则 upsert 因 _id 冲突失败
+		_, err := coll.UpdateOne(ctx,
+			bson.M{"_id": lockID, "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": m.owner, "expires_at": now.Add(m.lockTTL)}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			return nil
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ErrLocked
+		case <-time.After(m.poll):
+		}
+	}
+}
+
+// heartbeat - 锁已被其他进程接管 (没有匹配的文档), 或超过 lockTTL 未能续期时, 调用 lost
+func (m *mongoStore) heartbeat(ctx context.Context, lost context.CancelFunc) {
+	ticker := time.NewTicker(m.lockTTL / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			res, err := m.db.Collection(lockColl).UpdateOne(ctx,
+				bson.M{"_id": lockID, "owner": m.owner},
+				bson.M{"$set": bson.M{"expires_at": time.Now().Add(m.lockTTL)}},
+			)
+			if ctx.Err() != nil {
+				return
+			}
+			switch {
+			case err == nil && res.MatchedCount == 0:
+				logging.FromContext(ctx).Error("migration: lock taken over by another process, stopping")
+				lost()
+				return
+			case err != nil && time.Since(renewed) >= m.lockTTL:
+				logging.FromContext(ctx).Error("migration: lock expired without renewal, stopping", zap.Error(err))
+				lost()
+				return
+			case err != nil:
+				logging.FromContext(ctx).Warn("migration: renew lock failed", zap.Error(err))
+			default:
+				renewed = time.Now()
+			}
+		}
+	}
+}
+
+func (m *mongoStore) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := m.db.Collection(lockColl).DeleteOne(ctx, bson.M{"_id": lockID, "owner": m.owner})
+	if err != nil {
+		logging.FromContext(ctx).Warn("migration: release lock failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,84 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SQLFiles - 读取 fsys 中 dir 目录下的迁移文件, 文件名为 <版本>_<描述>.up.sql / .down.sql;
+// 每个文件在一个事务中执行, 由 sqlStore 执行时 与版本记录共用同一事务. 文件通常由 go:embed 嵌入, 格式错误视为编程错误 直接 panic
+func SQLFiles(db *sql.DB, fsys fs.FS, dir string) []Migration {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, e := range entries {
+		name := e.Name()
+		base := strings.TrimSuffix(name, ".sql")
+		direction := path.Ext(base)
+		base = strings.TrimSuffix(base, direction)
+
+		sep := strings.IndexByte(base, '_')
+		if sep < 0 {
+			panic(fmt.Sprintf("migration: bad file name %q", name))
+		}
+		version, err := strconv.Atoi(base[:sep])
+		if err != nil {
+			panic(fmt.Sprintf("migration: bad file name %q", name))
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			panic(err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Description: strings.ReplaceAll(base[sep+1:], "_", " ")}
+			byVersion[version] = m
+		}
+		switch direction {
+		case ".up":
+			m.Up = execSQL(db, string(content))
+		case ".down":
+			m.Down = execSQL(db, string(content))
+		default:
+			panic(fmt.Sprintf("migration: bad file name %q", name))
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for v, m := range byVersion {
+		if m.Up == nil {
+			panic(fmt.Sprintf("migration: %d has no up file", v))
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations
+}
+
+func execSQL(db *sql.DB, query string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if tx := txFrom(ctx); tx != nil {
+			_, err := tx.ExecContext(ctx, query)
+			return err
+		}
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, query); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit()
+	}
+}
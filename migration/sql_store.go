@@ -0,0 +1,156 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/alibug/go-identity-entry/logging"
+	"go.uber.org/zap"
+)
+
+// advisoryLockKey - schema_migrations 的 Postgres advisory lock 键, 任意取值 各服务不同即可
+const advisoryLockKey = 7340104917
+
+// sqlStore - 迁移记录保存在 schema_migrations 表
+type sqlStore struct {
+	db *sql.DB
+	// createTable / insert / remove - 各数据库的列类型与占位符不同
+	createTable string
+	insert      string
+	remove      string
+	lock        func(ctx context.Context) (func(), error)
+}
+
+// txKey - WithTransaction 将事务保存在 ctx 中, 供 Record / Remove 与 SQLFiles 的迁移使用
+type txKey struct{}
+
+// execer - sql.DB 与 sql.Tx 共同的方法
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// txFrom - ctx 中的事务, 没有时返回 nil
+func txFrom(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+// NewPostgresStore - 用 advisory lock 互斥, 多个副本可同时执行迁移
+func NewPostgresStore(db *sql.DB) Store {
+	s := &sqlStore{
+		db: db,
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version     INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at  TIMESTAMPTZ NOT NULL
+		)`,
+		insert: `INSERT INTO schema_migrations (version, description, applied_at) VALUES ($1, $2, $3)`,
+		remove: `DELETE FROM schema_migrations WHERE version = $1`,
+	}
+	s.lock = s.advisoryLock
+	return s
+}
+
+// NewSQLiteStore - 嵌入式数据库只在当前进程内使用, 用进程内的锁即可
+func NewSQLiteStore(db *sql.DB) Store {
+	var mu sync.Mutex
+	return &sqlStore{
+		db: db,
+		createTable: `CREATE TABLE IF NOT EXISTS schema_migrations (
+			version     INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at  DATETIME NOT NULL
+		)`,
+		insert: `INSERT INTO schema_migrations (version, description, applied_at) VALUES (?, ?, ?)`,
+		remove: `DELETE FROM schema_migrations WHERE version = ?`,
+		lock: func(context.Context) (func(), error) {
+			mu.Lock()
+			return mu.Unlock, nil
+		},
+	}
+}
+
+func (s *sqlStore) Applied(ctx context.Context) (map[int]time.Time, error) {
+	if _, err := s.db.ExecContext(ctx, s.createTable); err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func (s *sqlStore) Record(ctx context.Context, mig Migration, at time.Time) error {
+	_, err := s.execer(ctx).ExecContext(ctx, s.insert, mig.Version, mig.Description, at.UTC())
+	return err
+}
+
+func (s *sqlStore) Remove(ctx context.Context, version int) error {
+	_, err := s.execer(ctx).ExecContext(ctx, s.remove, version)
+	return err
+}
+
+// WithTransaction - 迁移与其记录在同一事务中提交; Postgres 与 SQLite 的 DDL 均可回滚
+func (s *sqlStore) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqlStore) execer(ctx context.Context) execer {
+	if tx := txFrom(ctx); tx != nil {
+		return tx
+	}
+	return s.db
+}
+
+// Lock - advisory lock 与进程内的锁 持有期间不会过期, 返回的 ctx 即为 ctx
+func (s *sqlStore) Lock(ctx context.Context) (context.Context, func(), error) {
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ctx, unlock, nil
+}
+
+// advisoryLock - advisory lock 绑定在连接上, 连接断开时自动释放
+func (s *sqlStore) advisoryLock(ctx context.Context) (func(), error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ErrLocked
+		}
+		return nil, fmt.Errorf("migration: %w", err)
+	}
+	return func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, advisoryLockKey); err != nil {
+			logging.FromContext(ctx).Warn("migration: release lock failed", zap.Error(err))
+		}
+		conn.Close()
+	}, nil
+}
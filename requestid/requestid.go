@@ -0,0 +1,60 @@
+// Package requestid - 为每个请求分配 ID, 写入响应头与错误响应, 便于按 ID 定位日志
+package requestid
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Header - 请求与响应中 携带请求 ID 的头
+const Header = "X-Request-ID"
+
+// maxLength - 客户端传入的 ID 过长 或包含不可见字符时 重新生成
+const maxLength = 128
+
+type ctxKey struct{}
+
+// Middleware - 沿用客户端传入的 X-Request-ID, 没有时生成一个;
+// 写入响应头, 并保存到 request context
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := Ensure(c.GetHeader(Header))
+		c.Header(Header, id)
+		c.Request = c.Request.WithContext(NewContext(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// Ensure - candidate 为有效的 ID 时原样返回, 否则生成一个新的
+func Ensure(candidate string) string {
+	if valid(candidate) {
+		return candidate
+	}
+	return uuid.NewString()
+}
+
+// NewContext - 返回带有请求 ID 的 context
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, id)
+}
+
+// FromContext - 未经过 Middleware 时 返回空字符串
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(ctxKey{}).(string)
+	return id
+}
+
+// valid - 只接受可打印的 ASCII 字符, 避免日志注入
+func valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,170 @@
+// Package bloomrepo - 在进程内用 bloom filter 缓存已撤销的 tokenID,
+// 绝大多数未撤销的 AccessToken 无需访问 Redis 即可校验
+package bloomrepo
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"go.uber.org/zap"
+)
+
+var errSubscriptionClosed = errors.New("revocation subscription closed")
+
+// BloomRevocationList - implement domain.RevocationList interface;
+// filter 判定不存在 即未撤销, 判定可能存在时 再查询 store.
+// 须调用 Run 同步 filter, 同步完成之前 所有查询都转发给 store
+type BloomRevocationList struct {
+	store domain.RevocationStore
+
+	mu     sync.RWMutex
+	filter *filter
+
+	expected int
+	fpRate   float64
+	rebuild  time.Duration
+	retry    time.Duration
+}
+
+// Option - BloomRevocationList 可选配置
+type Option func(*BloomRevocationList)
+
+// WithExpectedItems - 预计同时存在的撤销记录数, 默认 100000; 超出时按实际数量的两倍重建
+func WithExpectedItems(n int) Option {
+	return func(b *BloomRevocationList) {
+		b.expected = n
+	}
+}
+
+// WithFalsePositiveRate - 误判率, 误判时多一次 store 查询, 默认 0.001
+func WithFalsePositiveRate(p float64) Option {
+	return func(b *BloomRevocationList) {
+		b.fpRate = p
+	}
+}
+
+// WithRebuildInterval - 定期从 store 重建 filter, 去除已过期的记录 并补上订阅断开期间漏掉的通知, 默认 5 分钟
+func WithRebuildInterval(d time.Duration) Option {
+	return func(b *BloomRevocationList) {
+		b.rebuild = d
+	}
+}
+
+// NewBloomRevocationList will create an object that represent the domain.RevocationList interface
+func NewBloomRevocationList(store domain.RevocationStore, opts ...Option) *BloomRevocationList {
+	b := &BloomRevocationList{
+		store:    store,
+		expected: 100000,
+		fpRate:   0.001,
+		rebuild:  5 * time.Minute,
+		retry:    time.Second,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Revoke - 写入 store 后 立即加入本地 filter, 不必等待通知
+func (b *BloomRevocationList) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if err := b.store.Revoke(ctx, tokenID, expiresAt); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	if b.filter != nil {
+		b.filter.add(tokenID)
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// IsRevoked - implement domain.RevocationList interface
+func (b *BloomRevocationList) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	b.mu.RLock()
+	f := b.filter
+	maybe := f == nil || f.test(tokenID)
+	b.mu.RUnlock()
+	if !maybe {
+		return false, nil
+	}
+	return b.store.IsRevoked(ctx, tokenID)
+}
+
+// Run - 订阅撤销通知并加载全部记录, 之后 filter 才开始生效; 订阅中断时 停用 filter 并重新订阅,
+// 直到 ctx 结束
+func (b *BloomRevocationList) Run(ctx context.Context) {
+	for {
+		err := b.sync(ctx)
+		b.setFilter(nil)
+		if ctx.Err() != nil {
+			return
+		}
+		logging.FromContext(ctx).Warn("sync revoked tokens failed", zap.Duration("retry_in", b.retry), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(b.retry):
+		}
+	}
+}
+
+// sync - 先订阅再加载, 加载期间的撤销 会在之后从 channel 中读到
+func (b *BloomRevocationList) sync(ctx context.Context) error {
+	subCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	tokenIDs, err := b.store.SubscribeRevoked(subCtx)
+	if err != nil {
+		return err
+	}
+	if err := b.load(ctx); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(b.rebuild)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case tokenID, ok := <-tokenIDs:
+			if !ok {
+				return errSubscriptionClosed
+			}
+			b.mu.Lock()
+			b.filter.add(tokenID)
+			b.mu.Unlock()
+		case <-ticker.C:
+			if err := b.load(ctx); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// load - 用 store 中的全部记录 重建 filter
+func (b *BloomRevocationList) load(ctx context.Context) error {
+	tokenIDs, err := b.store.ListRevoked(ctx)
+	if err != nil {
+		return err
+	}
+	n := b.expected
+	if len(tokenIDs) > n {
+		n = 2 * len(tokenIDs)
+	}
+	f := newFilter(n, b.fpRate)
+	for _, tokenID := range tokenIDs {
+		f.add(tokenID)
+	}
+	b.setFilter(f)
+	return nil
+}
+
+func (b *BloomRevocationList) setFilter(f *filter) {
+	b.mu.Lock()
+	b.filter = f
+	b.mu.Unlock()
+}
@@ -0,0 +1,85 @@
+package bloomrepo_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	bloomrepo "github.com/alibug/go-identity-entry/token/repository/bloom"
+	memrepo "github.com/alibug/go-identity-entry/token/repository/memory"
+)
+
+// countingStore - 统计 IsRevoked 访问 store 的次数
+type countingStore struct {
+	domain.RevocationStore
+	lookups int64
+}
+
+func (c *countingStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	atomic.AddInt64(&c.lookups, 1)
+	return c.RevocationStore.IsRevoked(ctx, tokenID)
+}
+
+func TestBloomRevocationList(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := &countingStore{RevocationStore: memrepo.NewMemoryRevocationList()}
+	expiresAt := time.Now().Add(time.Hour)
+
+	// 启动前已撤销的 token
+	if err := store.Revoke(ctx, "before", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	b := bloomrepo.NewBloomRevocationList(store, bloomrepo.WithExpectedItems(1000))
+	// 同步完成之前 查询转发给 store
+	if revoked, err := b.IsRevoked(ctx, "before"); err != nil || !revoked {
+		t.Fatalf("IsRevoked(before, not synced) = %v, %v", revoked, err)
+	}
+	go b.Run(ctx)
+	waitFor(t, func() bool {
+		atomic.StoreInt64(&store.lookups, 0)
+		revoked, _ := b.IsRevoked(ctx, "unknown")
+		return !revoked && atomic.LoadInt64(&store.lookups) == 0
+	})
+
+	// 其他实例撤销的 token 通过订阅同步
+	if err := store.Revoke(ctx, "other-instance", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		revoked, _ := b.IsRevoked(ctx, "other-instance")
+		return revoked
+	})
+	// 本实例撤销的 token 立即生效
+	if err := b.Revoke(ctx, "local", expiresAt); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"before", "local"} {
+		if revoked, err := b.IsRevoked(ctx, id); err != nil || !revoked {
+			t.Fatalf("IsRevoked(%s) = %v, %v, want true", id, revoked, err)
+		}
+	}
+
+	atomic.StoreInt64(&store.lookups, 0)
+	for i := 0; i < 100; i++ {
+		if revoked, err := b.IsRevoked(ctx, "valid"); err != nil || revoked {
+			t.Fatalf("IsRevoked(valid) = %v, %v, want false", revoked, err)
+		}
+	}
+	if n := atomic.LoadInt64(&store.lookups); n != 0 {
+		t.Fatalf("store looked up %d times for an unrevoked token", n)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
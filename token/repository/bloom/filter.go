@@ -0,0 +1,58 @@
+package bloomrepo
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// filter - 只增不删的 bloom filter, 删除过期记录 须重建
+type filter struct {
+	bits []uint64
+	m    uint64
+	k    uint64
+}
+
+// newFilter - 按预计元素数 n 与误判率 p 计算位数与哈希次数
+func newFilter(n int, p float64) *filter {
+	if n < 1 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &filter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// locations - double hashing: h1 + i*h2, 两个哈希分别取自 FNV-1a 与 FNV-1
+func (f *filter) locations(s string) (uint64, uint64) {
+	a, b := fnv.New64a(), fnv.New64()
+	a.Write([]byte(s))
+	b.Write([]byte(s))
+	// h2 为偶数时 可能只覆盖部分位置
+	return a.Sum64(), b.Sum64() | 1
+}
+
+func (f *filter) add(s string) {
+	h1, h2 := f.locations(s)
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		f.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// test - 返回 false 时 s 一定不在集合中
+func (f *filter) test(s string) bool {
+	h1, h2 := f.locations(s)
+	for i := uint64(0); i < f.k; i++ {
+		pos := (h1 + i*h2) % f.m
+		if f.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,30 @@
+package bloomrepo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFilter(t *testing.T) {
+	const n, p = 10000, 0.01
+	f := newFilter(n, p)
+	for i := 0; i < n; i++ {
+		f.add(fmt.Sprintf("member-%d", i))
+	}
+	for i := 0; i < n; i++ {
+		if !f.test(fmt.Sprintf("member-%d", i)) {
+			t.Fatalf("member-%d: false negative", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if f.test(fmt.Sprintf("other-%d", i)) {
+			falsePositives++
+		}
+	}
+	// 允许一定的统计波动
+	if rate := float64(falsePositives) / n; rate > 2*p {
+		t.Fatalf("false positive rate = %v, want <= %v", rate, 2*p)
+	}
+}
@@ -1,5 +1,11 @@
 package body
 
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
 // TokenBody - implement domain.Token interface
 type TokenBody struct {
 	AccessToken  string `json:"accessToken"`
@@ -36,3 +42,80 @@ func (t *TokenDetailBody) GetTokenID() string {
 func (t *TokenDetailBody) GetUserID() string {
 	return t.userID
 }
+
+// GetExpiresAt - implement domain.TokenDetail interface, 仓库中查询用的 TokenDetail 不含过期时间
+func (t *TokenDetailBody) GetExpiresAt() *time.Time {
+	return nil
+}
+
+// GetScopes - implement domain.TokenDetail interface
+func (t *TokenDetailBody) GetScopes() []string {
+	return nil
+}
+
+// SessionBody - implement domain.Session interface
+type SessionBody struct {
+	TokenID   string           `json:"tokenID"`
+	UserID    string           `json:"userID"`
+	Type      domain.TokenType `json:"type,omitempty"`
+	IssuedAt  *time.Time       `json:"issuedAt,omitempty"`
+	ExpiresAt *time.Time       `json:"expiresAt,omitempty"`
+	Client    string           `json:"client,omitempty"`
+	IP        string           `json:"ip,omitempty"`
+	ParentID  string           `json:"parentID,omitempty"`
+}
+
+// GetTokenID - implement domain.Session interface
+func (s *SessionBody) GetTokenID() string {
+	return s.TokenID
+}
+
+// GetUserID - implement domain.Session interface
+func (s *SessionBody) GetUserID() string {
+	return s.UserID
+}
+
+// GetScopes - implement domain.Session interface, scope 只保存在 JWT 中
+func (s *SessionBody) GetScopes() []string {
+	return nil
+}
+
+// GetType - implement domain.Session interface
+func (s *SessionBody) GetType() domain.TokenType {
+	return s.Type
+}
+
+// GetIssuedAt - implement domain.Session interface
+func (s *SessionBody) GetIssuedAt() *time.Time {
+	return s.IssuedAt
+}
+
+// GetExpiresAt - implement domain.Session interface
+func (s *SessionBody) GetExpiresAt() *time.Time {
+	return s.ExpiresAt
+}
+
+// GetClient - implement domain.Session interface
+func (s *SessionBody) GetClient() string {
+	return s.Client
+}
+
+// GetIP - implement domain.Session interface
+func (s *SessionBody) GetIP() string {
+	return s.IP
+}
+
+// GetParentID - implement domain.Session interface
+func (s *SessionBody) GetParentID() string {
+	return s.ParentID
+}
+
+// IntrospectionBody - token introspection (RFC 7662) 的响应, roles 为本服务的扩展
+type IntrospectionBody struct {
+	Active bool     `json:"active"`
+	Sub    string   `json:"sub,omitempty"`
+	Scope  string   `json:"scope,omitempty"`
+	Jti    string   `json:"jti,omitempty"`
+	Exp    int64    `json:"exp,omitempty"`
+	Roles  []string `json:"roles,omitempty"`
+}
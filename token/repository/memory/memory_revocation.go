@@ -0,0 +1,108 @@
+package memrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+type subscriber struct {
+	ch   chan string
+	done <-chan struct{}
+}
+
+// memoryRevocationList - 仅用于本地开发和测试
+type memoryRevocationList struct {
+	mu          sync.Mutex
+	revoked     map[string]time.Time
+	subscribers map[*subscriber]struct{}
+}
+
+// NewMemoryRevocationList will create an object that represent the domain.RevocationStore interface
+func NewMemoryRevocationList() domain.RevocationStore {
+	return &memoryRevocationList{
+		revoked:     map[string]time.Time{},
+		subscribers: map[*subscriber]struct{}{},
+	}
+}
+
+func (m *memoryRevocationList) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	m.mu.Lock()
+	now := time.Now()
+	for id, at := range m.revoked {
+		if !now.Before(at) {
+			delete(m.revoked, id)
+		}
+	}
+	m.revoked[tokenID] = expiresAt
+	subs := make([]*subscriber, 0, len(m.subscribers))
+	for s := range m.subscribers {
+		subs = append(subs, s)
+	}
+	m.mu.Unlock()
+
+	// 与 Redis 不同, 通知不会丢失; 订阅者退出后不再等待
+	for _, s := range subs {
+		select {
+		case s.ch <- tokenID:
+		case <-s.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (m *memoryRevocationList) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at, ok := m.revoked[tokenID]
+	return ok && time.Now().Before(at), nil
+}
+
+func (m *memoryRevocationList) ListRevoked(ctx context.Context) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	tokenIDs := make([]string, 0, len(m.revoked))
+	for id, at := range m.revoked {
+		if now.Before(at) {
+			tokenIDs = append(tokenIDs, id)
+		}
+	}
+	return tokenIDs, nil
+}
+
+func (m *memoryRevocationList) SubscribeRevoked(ctx context.Context) (<-chan string, error) {
+	s := &subscriber{ch: make(chan string), done: ctx.Done()}
+	m.mu.Lock()
+	m.subscribers[s] = struct{}{}
+	m.mu.Unlock()
+
+	out := make(chan string)
+	go func() {
+		defer close(out)
+		defer func() {
+			m.mu.Lock()
+			delete(m.subscribers, s)
+			m.mu.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case id := <-s.ch:
+				select {
+				case out <- id:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,157 @@
+package memrepo
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/token/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+// entry - session 的 ExpiresAt 为 nil 表示永不过期
+type entry struct {
+	session domain.Session
+}
+
+func (e entry) expired(now time.Time) bool {
+	at := e.session.GetExpiresAt()
+	return at != nil && !now.Before(*at)
+}
+
+// memoryTokensRepository - 仅用于本地开发和测试, 过期的 token 在访问时清理
+type memoryTokensRepository struct {
+	mu      sync.Mutex
+	tokens  map[string]entry
+	now     func() time.Time
+	swept   time.Time
+	sweepAt time.Duration
+}
+
+// Option - memoryTokensRepository 可选配置
+type Option func(*memoryTokensRepository)
+
+// WithClock - 替换当前时间, 用于测试过期
+func WithClock(now func() time.Time) Option {
+	return func(m *memoryTokensRepository) {
+		m.now = now
+	}
+}
+
+// NewMemoryTokensRepository will create an object that represent the user.Repository interface
+func NewMemoryTokensRepository(opts ...Option) domain.TokensRepository {
+	m := &memoryTokensRepository{
+		tokens:  map[string]entry{},
+		now:     time.Now,
+		sweepAt: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *memoryTokensRepository) CreateTokenID(ctx context.Context, session domain.Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sweep(m.now())
+	m.tokens[session.GetTokenID()] = entry{session: copySession(session)}
+	return nil
+}
+
+// CheckTokenID - 与 Redis 一致, token 不存在时返回错误
+func (m *memoryTokensRepository) CheckTokenID(ctx context.Context, token domain.TokenDetail) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.get(token.GetTokenID())
+	if !ok {
+		return false, status.ErrNotFound
+	}
+	return e.session.GetUserID() == token.GetUserID(), nil
+}
+
+func (m *memoryTokensRepository) DeleteTokenID(ctx context.Context, tokenID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.tokens, tokenID)
+	return nil
+}
+
+func (m *memoryTokensRepository) ListUserTokenIDs(ctx context.Context, userID string) ([]domain.Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	var sessions []domain.Session
+	for _, e := range m.tokens {
+		if e.session.GetUserID() != userID || e.expired(now) {
+			continue
+		}
+		sessions = append(sessions, copySession(e.session))
+	}
+	return sessions, nil
+}
+
+func (m *memoryTokensRepository) DeleteUserTokenIDs(ctx context.Context, userID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for tokenID, e := range m.tokens {
+		if e.session.GetUserID() == userID {
+			delete(m.tokens, tokenID)
+		}
+	}
+	return nil
+}
+
+// get - 已过期的 token 视为不存在, 并顺便删除
+func (m *memoryTokensRepository) get(tokenID string) (entry, bool) {
+	e, ok := m.tokens[tokenID]
+	if !ok {
+		return entry{}, false
+	}
+	if e.expired(m.now()) {
+		delete(m.tokens, tokenID)
+		return entry{}, false
+	}
+	return e, true
+}
+
+// sweep - 每隔 sweepAt 清理一次从未再被访问的过期 token, 避免内存无限增长
+func (m *memoryTokensRepository) sweep(now time.Time) {
+	if now.Sub(m.swept) < m.sweepAt {
+		return
+	}
+	m.swept = now
+	for tokenID, e := range m.tokens {
+		if e.expired(now) {
+			delete(m.tokens, tokenID)
+		}
+	}
+}
+
+// copySession - 保存与返回副本, 调用方修改记录不影响仓库中的数据
+func copySession(s domain.Session) *body.SessionBody {
+	return &body.SessionBody{
+		TokenID:   s.GetTokenID(),
+		UserID:    s.GetUserID(),
+		Type:      s.GetType(),
+		IssuedAt:  copyTime(s.GetIssuedAt()),
+		ExpiresAt: copyTime(s.GetExpiresAt()),
+		Client:    s.GetClient(),
+		IP:        s.GetIP(),
+		ParentID:  s.GetParentID(),
+	}
+}
+
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
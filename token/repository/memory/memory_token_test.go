@@ -0,0 +1,24 @@
+package memrepo_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	memrepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	"github.com/alibug/go-identity-entry/token/repository/repotest"
+)
+
+func TestContract(t *testing.T) {
+	repotest.TokensRepositoryContract(t, func(t *testing.T) (domain.TokensRepository, func(time.Duration)) {
+		now := time.Now()
+		repo := memrepo.NewMemoryTokensRepository(memrepo.WithClock(func() time.Time { return now }))
+		return repo, func(d time.Duration) { now = now.Add(d) }
+	})
+}
+
+func TestRevocationContract(t *testing.T) {
+	repotest.RevocationStoreContract(t, func(t *testing.T) domain.RevocationStore {
+		return memrepo.NewMemoryRevocationList()
+	})
+}
@@ -0,0 +1,167 @@
+package redisdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/token/repository/body"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// hashTag - Redis Cluster 计算 slot 时 只使用第一个 {...} 中的内容
+func hashTag(key string) string {
+	start := strings.IndexByte(key, '{')
+	if start < 0 {
+		return key
+	}
+	end := strings.IndexByte(key[start+1:], '}')
+	if end <= 0 {
+		return key
+	}
+	return key[start+1 : start+1+end]
+}
+
+func TestKeysShareSlot(t *testing.T) {
+	userID := "60d5ec49f1a4c2a1b8e4d3c2"
+	r := &redisTokensRepository{prefix: DefaultKeyPrefix}
+	keys := []string{
+		r.tokenKey("0b8f6c1e-1f6e-4c1b-9a57-7d0c2d3e4f50" + tokenIDSeparator + userID),
+		r.tokenKey("7a1d2c3b-4e5f-4a6b-8c7d-9e0f1a2b3c4d" + tokenIDSeparator + userID),
+		r.indexKey(userID),
+		legacyTokenKey("0b8f6c1e-1f6e-4c1b-9a57-7d0c2d3e4f50" + tokenIDSeparator + userID),
+		legacyIndexKey(userID),
+	}
+	for _, key := range keys {
+		if tag := hashTag(key); tag != userID {
+			t.Errorf("hash tag of %q = %q, want %q", key, tag, userID)
+		}
+	}
+}
+
+func TestKeyPrefix(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(time.Hour)
+	session := &body.SessionBody{TokenID: "abc++u1", UserID: "u1", Type: domain.TokenTypeRefresh, IP: "203.0.113.7", ExpiresAt: &expiresAt}
+	if err := NewRedisTokensRepository(client, WithKeyPrefix("svc:")).CreateTokenID(ctx, session); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := mr.Keys()
+	want := []string{"svc:token:{u1}:abc", "svc:tokens:{u1}"}
+	if len(keys) != len(want) || keys[0] != want[0] || keys[1] != want[1] {
+		t.Fatalf("keys = %v, want %v", keys, want)
+	}
+	if got := mr.HGet(want[0], fieldIP); got != session.IP {
+		t.Fatalf("%s = %q, want %q", fieldIP, got, session.IP)
+	}
+	if ttl := mr.TTL(want[0]); ttl <= 0 {
+		t.Fatalf("TTL = %v, want > 0", ttl)
+	}
+	if ttl := mr.TTL(want[1]); ttl <= 0 || ttl > time.Hour {
+		t.Fatalf("index TTL = %v, want (0, 1h]", ttl)
+	}
+
+	// 其他前缀下 看不到该 token
+	if _, err := NewRedisTokensRepository(client).CheckTokenID(ctx, session); err != redis.Nil {
+		t.Fatalf("CheckTokenID(default prefix) err = %v, want redis.Nil", err)
+	}
+}
+
+func TestIndexTTL(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	repo := NewRedisTokensRepository(client)
+	index := repo.(*redisTokensRepository).indexKey("u1")
+
+	create := func(tokenID string, expiresIn time.Duration) {
+		t.Helper()
+		session := &body.SessionBody{TokenID: tokenID + tokenIDSeparator + "u1", UserID: "u1", Type: domain.TokenTypeRefresh}
+		if expiresIn > 0 {
+			expiresAt := time.Now().Add(expiresIn)
+			session.ExpiresAt = &expiresAt
+		}
+		if err := repo.CreateTokenID(ctx, session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 旧版本写入的索引 没有过期时间, 其中的 token 最晚 2 小时后过期
+	create("a", 2*time.Hour)
+	client.Persist(ctx, index)
+	create("b", time.Hour)
+	if ttl := mr.TTL(index); ttl <= time.Hour || ttl > 2*time.Hour {
+		t.Fatalf("index TTL = %v, want the latest token expiry", ttl)
+	}
+	create("c", 3*time.Hour)
+	if ttl := mr.TTL(index); ttl <= 2*time.Hour {
+		t.Fatalf("index TTL = %v, want extended to 3h", ttl)
+	}
+
+	// 永不过期的 token 使索引不过期, 之后加入的 token 不会再为索引设置过期时间
+	create("d", 0)
+	create("e", time.Hour)
+	if ttl := mr.TTL(index); ttl != 0 {
+		t.Fatalf("index TTL = %v, want no expiry", ttl)
+	}
+}
+
+func TestLegacyKeys(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	// 旧版本写入的 token: 以 tokenID 为键 与 无前缀的 token:{userID}:uuid
+	bareID := "0b8f6c1e-1f6e-4c1b-9a57-7d0c2d3e4f50++u1"
+	taggedID := "7a1d2c3b-4e5f-4a6b-8c7d-9e0f1a2b3c4d++u1"
+	mr.Set(bareID, "u1")
+	mr.SetTTL(bareID, time.Hour)
+	mr.Set(legacyTokenKey(taggedID), "u1")
+	mr.SetTTL(legacyTokenKey(taggedID), time.Hour)
+	if _, err := mr.SAdd(legacyIndexKey("u1"), taggedID); err != nil {
+		t.Fatal(err)
+	}
+
+	repo := NewRedisTokensRepository(client)
+	for _, tokenID := range []string{bareID, taggedID} {
+		if ok, err := repo.CheckTokenID(ctx, body.NewTokenDetailBody(tokenID, "u1")); err != nil || !ok {
+			t.Fatalf("CheckTokenID(%s) = %v, %v", tokenID, ok, err)
+		}
+	}
+	sessions, err := repo.ListUserTokenIDs(ctx, "u1")
+	if err != nil || len(sessions) != 2 {
+		t.Fatalf("ListUserTokenIDs = %v, %v", sessions, err)
+	}
+	for _, s := range sessions {
+		if s.GetExpiresAt() == nil {
+			t.Fatalf("%s: ExpiresAt not derived from TTL", s.GetTokenID())
+		}
+	}
+
+	if _, err := NewRedisTokensRepository(client, WithLegacyKeys(false)).CheckTokenID(ctx, body.NewTokenDetailBody(bareID, "u1")); err != redis.Nil {
+		t.Fatalf("CheckTokenID(legacy disabled) err = %v, want redis.Nil", err)
+	}
+
+	if err := repo.DeleteTokenID(ctx, taggedID); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(legacyTokenKey(taggedID)) {
+		t.Fatal("legacy token not deleted")
+	}
+	if err := repo.DeleteUserTokenIDs(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	if keys := mr.Keys(); len(keys) != 0 {
+		t.Fatalf("keys left after DeleteUserTokenIDs: %v", keys)
+	}
+}
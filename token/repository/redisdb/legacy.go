@@ -0,0 +1,142 @@
+package redisdb
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/token/repository/body"
+	"github.com/go-redis/redis/v8"
+)
+
+// 旧格式的键 只读取与删除, 不再写入; 均为 string, 值为 userID, 没有其他元数据
+//
+//	uuid++userID            -> userID   最早的格式, 只能通过 SCAN 按用户查找
+//	token:{userID}:uuid     -> userID   无前缀的格式
+//	tokens:{userID}         -> set(tokenID)  无前缀格式的用户索引
+
+func legacyTokenKey(tokenID string) string {
+	id, userID := splitTokenID(tokenID)
+	return fmt.Sprintf("%s{%s}:%s", tokenKeyPrefix, userID, id)
+}
+
+func legacyIndexKey(userID string) string {
+	return fmt.Sprintf("%s{%s}", indexKeyPrefix, userID)
+}
+
+// getLegacy - 依次读取 无前缀格式 与 最早格式 的键
+func (r *redisTokensRepository) getLegacy(ctx context.Context, tokenID string) (string, error) {
+	userID, err := r.client.Get(ctx, legacyTokenKey(tokenID)).Result()
+	if err == redis.Nil && r.bareKeys {
+		return r.client.Get(ctx, tokenID).Result()
+	}
+	return userID, err
+}
+
+func (r *redisTokensRepository) deleteLegacy(ctx context.Context, tokenID string) error {
+	if !r.legacy {
+		return nil
+	}
+	_, userID := splitTokenID(tokenID)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, legacyTokenKey(tokenID))
+		pipe.SRem(ctx, legacyIndexKey(userID), tokenID)
+		return nil
+	})
+	if err != nil || !r.bareKeys {
+		return err
+	}
+	return r.client.Del(ctx, tokenID).Err()
+}
+
+func (r *redisTokensRepository) listLegacy(ctx context.Context, userID string) ([]domain.Session, error) {
+	if !r.legacy {
+		return nil, nil
+	}
+	tokenIDs, err := r.client.SMembers(ctx, legacyIndexKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+	var sessions []domain.Session
+	for _, tokenID := range tokenIDs {
+		session, err := r.legacySession(ctx, legacyTokenKey(tokenID), tokenID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil {
+			sessions = append(sessions, session)
+		}
+	}
+
+	bare, err := r.scanBare(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, tokenID := range bare {
+		session, err := r.legacySession(ctx, tokenID, tokenID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if session != nil {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+// legacySession - 旧格式只能从 TTL 推算过期时间, 键不存在时返回 nil
+func (r *redisTokensRepository) legacySession(ctx context.Context, key string, tokenID string, userID string) (domain.Session, error) {
+	ttl, err := r.client.TTL(ctx, key).Result()
+	if err != nil || ttl == -2 {
+		return nil, err
+	}
+	session := &body.SessionBody{TokenID: tokenID, UserID: userID}
+	if ttl > 0 {
+		expiresAt := time.Now().Add(ttl)
+		session.ExpiresAt = &expiresAt
+	}
+	return session, nil
+}
+
+func (r *redisTokensRepository) deleteUserLegacy(ctx context.Context, userID string) error {
+	if !r.legacy {
+		return nil
+	}
+	index := legacyIndexKey(userID)
+	tokenIDs, err := r.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{index}
+	for _, tokenID := range tokenIDs {
+		keys = append(keys, legacyTokenKey(tokenID))
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+
+	bare, err := r.scanBare(ctx, userID)
+	if err != nil || len(bare) == 0 {
+		return err
+	}
+	return r.client.Del(ctx, bare...).Err()
+}
+
+// scanBare - 最早格式的键 只能通过 tokenID 的后缀扫描
+func (r *redisTokensRepository) scanBare(ctx context.Context, userID string) ([]string, error) {
+	if !r.bareKeys {
+		return nil, nil
+	}
+	var keys []string
+	iter := r.client.Scan(ctx, 0, fmt.Sprintf("*%s%s", tokenIDSeparator, userID), 0).Iterator()
+	for iter.Next(ctx) {
+		// 其他格式的键 不会以 ++userID 结尾, 此处仍排除以防万一
+		key := iter.Val()
+		if !strings.HasPrefix(key, tokenKeyPrefix) && !strings.HasPrefix(key, r.prefix) {
+			keys = append(keys, key)
+		}
+	}
+	return keys, iter.Err()
+}
@@ -2,37 +2,308 @@ package redisdb
 
 import (
 	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/alibug/go-identity-entry/domain"
-	"github.com/alibug/go-identity-utils/config"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/token/repository/body"
 	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+)
+
+// tokenIDSeparator - tokenID 的格式为 uuid++userID
+const tokenIDSeparator = "++"
+
+// DefaultKeyPrefix - 所有键的默认前缀, 与共用同一 Redis 的其他服务区分
+const DefaultKeyPrefix = "identity:"
+
+// 键的布局: 同一用户的键 用 {userID} 作为 hash tag, 在 Cluster 中落在同一个 slot
+//
+//	<prefix>token:{userID}:uuid  -> hash   单个 token 的记录, 在 expires_at 过期
+//	<prefix>tokens:{userID}      -> set(tokenID)  用户所有 token 的索引, 代替 SCAN
+//
+// 旧格式的键 见 legacy.go
+const (
+	tokenKeyPrefix = "token:"
+	indexKeyPrefix = "tokens:"
+)
+
+// token 记录 hash 中的字段, 时间为 unix 秒
+const (
+	fieldUserID    = "user_id"
+	fieldType      = "type"
+	fieldIssuedAt  = "issued_at"
+	fieldExpiresAt = "expires_at"
+	fieldClient    = "client"
+	fieldIP        = "ip"
+	fieldParent    = "parent"
 )
 
 type redisTokensRepository struct {
-	client *redis.Client
+	client redis.UniversalClient
+	prefix string
+	legacy bool
+	// bareKeys - 是否读取最早的 以 tokenID 为键的格式, 需要 SCAN, Cluster 中总是关闭
+	bareKeys bool
+}
+
+// Option - redisTokensRepository 可选配置
+type Option func(*redisTokensRepository)
+
+// WithLegacyKeys - 是否读取旧格式的键, 默认读取; 升级后超过 RefreshToken 有效期 即可关闭,
+// 以免 列出/删除用户 token 时 SCAN 整个库
+func WithLegacyKeys(enabled bool) Option {
+	return func(r *redisTokensRepository) {
+		r.legacy = enabled
+	}
+}
+
+// WithKeyPrefix - 替换键的前缀, 默认为 DefaultKeyPrefix; 前缀不能为空,
+// 否则会与旧格式的键冲突
+func WithKeyPrefix(prefix string) Option {
+	return func(r *redisTokensRepository) {
+		r.prefix = prefix
+	}
 }
 
 // NewRedisTokensRepository will create an object that represent the user.Repository interface
-func NewRedisTokensRepository(client *redis.Client, config config.TokenConfig) domain.TokensRepository {
-	return &redisTokensRepository{client}
+func NewRedisTokensRepository(client redis.UniversalClient, opts ...Option) domain.TokensRepository {
+	r := &redisTokensRepository{client: client, prefix: DefaultKeyPrefix, legacy: true}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.prefix == "" {
+		panic("redisdb: token key prefix must not be empty")
+	}
+	// 最早的版本不支持 Cluster, Cluster 中不会有以 tokenID 为键的旧格式
+	_, cluster := client.(*redis.ClusterClient)
+	r.bareKeys = r.legacy && !cluster
+	return r
+}
+
+// splitTokenID - 从 tokenID 中取出 uuid 与 userID
+func splitTokenID(tokenID string) (string, string) {
+	i := strings.Index(tokenID, tokenIDSeparator)
+	if i < 0 {
+		return tokenID, ""
+	}
+	return tokenID[:i], tokenID[i+len(tokenIDSeparator):]
 }
 
-// CreateToken - 将指定 tokenID 与 userID 组成键值对 存入数据库
-func (r *redisTokensRepository) CreateTokenID(ctx context.Context, token domain.TokenDetail, expiration time.Duration) error {
-	return r.client.Set(ctx, token.GetTokenID(), token.GetUserID(), expiration).Err()
+func (r *redisTokensRepository) tokenKey(tokenID string) string {
+	id, userID := splitTokenID(tokenID)
+	return fmt.Sprintf("%s%s{%s}:%s", r.prefix, tokenKeyPrefix, userID, id)
+}
+
+func (r *redisTokensRepository) indexKey(userID string) string {
+	return fmt.Sprintf("%s%s{%s}", r.prefix, indexKeyPrefix, userID)
+}
+
+// CreateTokenID - 将 token 记录存为 hash, 并加入用户索引; 已过期的记录不再保存
+func (r *redisTokensRepository) CreateTokenID(ctx context.Context, session domain.Session) error {
+	var expiration time.Duration
+	if at := session.GetExpiresAt(); at != nil {
+		expiration = time.Until(*at)
+		if expiration <= 0 {
+			return nil
+		}
+	}
+
+	key := r.tokenKey(session.GetTokenID())
+	index := r.indexKey(session.GetUserID())
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, encodeSession(session))
+		if expiration > 0 {
+			pipe.ExpireAt(ctx, key, *session.GetExpiresAt())
+		}
+		pipe.SAdd(ctx, index, session.GetTokenID())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// 索引的过期时间 不短于其中最晚过期的 token
+	if expiration <= 0 {
+		return r.client.Persist(ctx, index).Err()
+	}
+	ttl, err := r.client.TTL(ctx, index).Result()
+	if err != nil {
+		return err
+	}
+	switch {
+	case ttl >= 0 && ttl < expiration:
+		return r.client.Expire(ctx, index, expiration).Err()
+	case ttl < 0:
+		// 新建的索引 (或旧版本写入的索引) 尚未设置过期时间
+		return r.expireIndex(ctx, index, expiration)
+	}
+	return nil
+}
+
+// expireIndex - 按索引中最晚过期的 token 设置过期时间; 其中有永不过期的 token 时 保持不过期
+func (r *redisTokensRepository) expireIndex(ctx context.Context, index string, expiration time.Duration) error {
+	tokenIDs, err := r.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return err
+	}
+	cmds := make([]*redis.DurationCmd, len(tokenIDs))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tokenID := range tokenIDs {
+			cmds[i] = pipe.TTL(ctx, r.tokenKey(tokenID))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, cmd := range cmds {
+		ttl := cmd.Val()
+		// -1 为没有过期时间, -2 为已过期或已删除
+		if ttl == -1 {
+			return nil
+		}
+		if ttl > expiration {
+			expiration = ttl
+		}
+	}
+	return r.client.Expire(ctx, index, expiration).Err()
 }
 
-// CheckToken - 用给定的 tokenID 对应 userID， 看是否匹配
+// CheckTokenID - 用给定的 tokenID 对应 userID， 看是否匹配
 func (r *redisTokensRepository) CheckTokenID(ctx context.Context, token domain.TokenDetail) (bool, error) {
-	userID, err := r.client.Get(ctx, token.GetTokenID()).Result()
+	userID, err := r.client.HGet(ctx, r.tokenKey(token.GetTokenID()), fieldUserID).Result()
+	if err == redis.Nil && r.legacy {
+		userID, err = r.getLegacy(ctx, token.GetTokenID())
+		if err == nil {
+			// 仍有旧格式的 token 在使用时 不能关闭 redis.legacyTokenKeys
+			logging.FromContext(ctx).Debug("token found under a legacy key", zap.String("user_id", token.GetUserID()))
+		}
+	}
 	if err != nil {
 		return false, err
 	}
 	return token.GetUserID() == userID, nil
 }
 
-// DeleteToken - 删除指定 的 tokenID
+// DeleteTokenID - 删除指定 的 tokenID
 func (r *redisTokensRepository) DeleteTokenID(ctx context.Context, tokenID string) error {
-	return r.client.Del(ctx, tokenID).Err()
+	_, userID := splitTokenID(tokenID)
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, r.tokenKey(tokenID))
+		pipe.SRem(ctx, r.indexKey(userID), tokenID)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return r.deleteLegacy(ctx, tokenID)
+}
+
+// ListUserTokenIDs - 通过用户索引 列出用户所有的 token, 并清理索引中已过期的
+func (r *redisTokensRepository) ListUserTokenIDs(ctx context.Context, userID string) ([]domain.Session, error) {
+	index := r.indexKey(userID)
+	tokenIDs, err := r.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(tokenIDs))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, tokenID := range tokenIDs {
+			cmds[i] = pipe.HGetAll(ctx, r.tokenKey(tokenID))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make([]domain.Session, 0, len(tokenIDs))
+	var stale []interface{}
+	for i, tokenID := range tokenIDs {
+		fields := cmds[i].Val()
+		// 已过期 或 已被删除
+		if len(fields) == 0 {
+			stale = append(stale, tokenID)
+			continue
+		}
+		sessions = append(sessions, decodeSession(tokenID, fields))
+	}
+	// 清理失败不影响列出结果, 下次列出时 会再次清理
+	if len(stale) > 0 {
+		if err := r.client.SRem(ctx, index, stale...).Err(); err != nil {
+			logging.FromContext(ctx).Warn("remove stale token ids from index failed",
+				zap.String("user_id", userID), zap.Int("stale", len(stale)), zap.Error(err))
+		}
+	}
+
+	legacy, err := r.listLegacy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(legacy) > 0 {
+		logging.FromContext(ctx).Debug("tokens found under legacy keys", zap.String("user_id", userID), zap.Int("count", len(legacy)))
+	}
+	return append(sessions, legacy...), nil
+}
+
+// DeleteUserTokenIDs - 删除用户所有的 token
+func (r *redisTokensRepository) DeleteUserTokenIDs(ctx context.Context, userID string) error {
+	index := r.indexKey(userID)
+	tokenIDs, err := r.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return err
+	}
+	keys := []string{index}
+	for _, tokenID := range tokenIDs {
+		keys = append(keys, r.tokenKey(tokenID))
+	}
+	if err := r.client.Del(ctx, keys...).Err(); err != nil {
+		return err
+	}
+	return r.deleteUserLegacy(ctx, userID)
+}
+
+func encodeSession(s domain.Session) map[string]interface{} {
+	fields := map[string]interface{}{
+		fieldUserID: s.GetUserID(),
+		fieldType:   string(s.GetType()),
+		fieldClient: s.GetClient(),
+		fieldIP:     s.GetIP(),
+		fieldParent: s.GetParentID(),
+	}
+	if at := s.GetIssuedAt(); at != nil {
+		fields[fieldIssuedAt] = at.Unix()
+	}
+	if at := s.GetExpiresAt(); at != nil {
+		fields[fieldExpiresAt] = at.Unix()
+	}
+	return fields
+}
+
+func decodeSession(tokenID string, fields map[string]string) domain.Session {
+	return &body.SessionBody{
+		TokenID:   tokenID,
+		UserID:    fields[fieldUserID],
+		Type:      domain.TokenType(fields[fieldType]),
+		IssuedAt:  parseUnix(fields[fieldIssuedAt]),
+		ExpiresAt: parseUnix(fields[fieldExpiresAt]),
+		Client:    fields[fieldClient],
+		IP:        fields[fieldIP],
+		ParentID:  fields[fieldParent],
+	}
+}
+
+// parseUnix - 字段缺失或无法解析时 返回 nil
+func parseUnix(s string) *time.Time {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	t := time.Unix(sec, 0)
+	return &t
 }
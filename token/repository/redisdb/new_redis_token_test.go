@@ -0,0 +1,113 @@
+package redisdb_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/token/repository/body"
+	"github.com/alibug/go-identity-entry/token/repository/redisdb"
+	"github.com/alibug/go-identity-entry/token/repository/repotest"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestContract(t *testing.T) {
+	repotest.TokensRepositoryContract(t, func(t *testing.T) (domain.TokensRepository, func(time.Duration)) {
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return redisdb.NewRedisTokensRepository(client), mr.FastForward
+	})
+}
+
+func TestRevocationContract(t *testing.T) {
+	repotest.RevocationStoreContract(t, func(t *testing.T) domain.RevocationStore {
+		mr := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return redisdb.NewRedisRevocationList(client, redisdb.DefaultKeyPrefix)
+	})
+}
+
+// go-redis 重连后 会自动重新订阅; 期间的通知已丢失, channel 须关闭 以便订阅者重新加载
+func TestSubscribeRevokedClosesOnReconnect(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	store := redisdb.NewRedisRevocationList(client, redisdb.DefaultKeyPrefix)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tokenIDs, err := store.SubscribeRevoked(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Revoke(ctx, "t1", time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if id := <-tokenIDs; id != "t1" {
+		t.Fatalf("got %q, want t1", id)
+	}
+
+	mr.Close()
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case id, ok := <-tokenIDs:
+		if ok {
+			t.Fatalf("got %q, want channel closed after reconnect", id)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("channel not closed after reconnect")
+	}
+}
+
+// failSRem - 使 SREM 命令失败
+type failSRem struct{}
+
+func (failSRem) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if cmd.Name() == "srem" {
+		return ctx, errors.New("READONLY You can't write against a read only replica")
+	}
+	return ctx, nil
+}
+func (failSRem) AfterProcess(ctx context.Context, cmd redis.Cmder) error { return nil }
+func (failSRem) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+func (failSRem) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error { return nil }
+
+// 清理索引中已过期的 tokenID 失败时 仍返回结果, 并记录日志
+func TestListUserTokenIDsStaleCleanupFails(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	repo := redisdb.NewRedisTokensRepository(client, redisdb.WithLegacyKeys(false))
+
+	core, logs := observer.New(zapcore.WarnLevel)
+	ctx := logging.NewContext(context.Background(), zap.New(core))
+	expiresAt := time.Now().Add(time.Hour)
+	for _, tokenID := range []string{"live++u1", "stale++u1"} {
+		if err := repo.CreateTokenID(ctx, &body.SessionBody{TokenID: tokenID, UserID: "u1", ExpiresAt: &expiresAt}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mr.Del(redisdb.DefaultKeyPrefix + "token:{u1}:stale")
+
+	client.AddHook(failSRem{})
+	sessions, err := repo.ListUserTokenIDs(ctx, "u1")
+	if err != nil || len(sessions) != 1 || sessions[0].GetTokenID() != "live++u1" {
+		t.Fatalf("ListUserTokenIDs = %v, %v, want only live++u1", sessions, err)
+	}
+	if n := logs.FilterMessage("remove stale token ids from index failed").Len(); n != 1 {
+		t.Fatalf("cleanup failure logged %d times, want 1", n)
+	}
+}
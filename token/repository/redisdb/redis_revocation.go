@@ -0,0 +1,100 @@
+package redisdb
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/go-redis/redis/v8"
+)
+
+// revokedKey - 已撤销的 tokenID 存于一个 sorted set, score 为 token 的过期时间 (unix 秒),
+// 每次撤销时顺便清理已过期的成员; 同名 channel 用于通知其他实例
+const revokedKey = "revoked"
+
+type redisRevocationList struct {
+	client  redis.UniversalClient
+	key     string
+	channel string
+}
+
+// NewRedisRevocationList will create an object that represent the domain.RevocationStore interface,
+// prefix 与 WithKeyPrefix 一致
+func NewRedisRevocationList(client redis.UniversalClient, prefix string) domain.RevocationStore {
+	return &redisRevocationList{
+		client:  client,
+		key:     prefix + revokedKey,
+		channel: prefix + revokedKey,
+	}
+}
+
+// Revoke - 加入已撤销列表 并通知订阅者
+func (r *redisRevocationList) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAdd(ctx, r.key, &redis.Z{Score: float64(expiresAt.Unix()), Member: tokenID})
+		pipe.ZRemRangeByScore(ctx, r.key, "-inf", strconv.FormatInt(time.Now().Unix(), 10))
+		pipe.Publish(ctx, r.channel, tokenID)
+		return nil
+	})
+	return err
+}
+
+// IsRevoked - 已过期的记录 视为不存在
+func (r *redisRevocationList) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	score, err := r.client.ZScore(ctx, r.key, tokenID).Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return int64(score) > time.Now().Unix(), nil
+}
+
+// ListRevoked - 列出所有未过期的撤销记录
+func (r *redisRevocationList) ListRevoked(ctx context.Context) ([]string, error) {
+	return r.client.ZRangeByScore(ctx, r.key, &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(time.Now().Unix(), 10),
+		Max: "+inf",
+	}).Result()
+}
+
+// SubscribeRevoked - go-redis 断线后会自动重连并重新订阅, 但不会关闭 channel, 期间的通知会丢失;
+// 因此收到重新订阅的确认时 关闭 channel, 使订阅者丢弃本地缓存 并重新订阅、重新加载
+func (r *redisRevocationList) SubscribeRevoked(ctx context.Context) (<-chan string, error) {
+	ps := r.client.Subscribe(ctx, r.channel)
+	// 等待订阅确认, 之后的撤销一定能收到
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	tokenIDs := make(chan string)
+	go func() {
+		defer close(tokenIDs)
+		defer ps.Close()
+		msgs := ps.ChannelWithSubscriptions(ctx, 100)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				m, isMessage := msg.(*redis.Message)
+				if !isMessage {
+					// *redis.Subscription: 连接已重建
+					return
+				}
+				select {
+				case tokenIDs <- m.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return tokenIDs, nil
+}
@@ -0,0 +1,68 @@
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// RevocationStoreContract - newStore 每次调用须返回一个空的存储
+func RevocationStoreContract(t *testing.T, newStore func(t *testing.T) domain.RevocationStore) {
+	t.Run("RevokeAndCheck", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		if err := store.Revoke(ctx, tokenID("t1", "u1"), time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+
+		if revoked, err := store.IsRevoked(ctx, tokenID("t1", "u1")); err != nil || !revoked {
+			t.Fatalf("IsRevoked = %v, %v, want true", revoked, err)
+		}
+		if revoked, err := store.IsRevoked(ctx, tokenID("t2", "u1")); err != nil || revoked {
+			t.Fatalf("IsRevoked(other) = %v, %v, want false", revoked, err)
+		}
+		if ids, err := store.ListRevoked(ctx); err != nil || len(ids) != 1 || ids[0] != tokenID("t1", "u1") {
+			t.Fatalf("ListRevoked = %v, %v", ids, err)
+		}
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		store := newStore(t)
+		ctx := context.Background()
+		// token 已过期 无需再记录
+		if err := store.Revoke(ctx, tokenID("old", "u1"), time.Now().Add(-time.Minute)); err != nil {
+			t.Fatalf("Revoke: %v", err)
+		}
+		if revoked, err := store.IsRevoked(ctx, tokenID("old", "u1")); err != nil || revoked {
+			t.Fatalf("IsRevoked(expired) = %v, %v, want false", revoked, err)
+		}
+		if ids, err := store.ListRevoked(ctx); err != nil || len(ids) != 0 {
+			t.Fatalf("ListRevoked = %v, %v, want empty", ids, err)
+		}
+	})
+
+	t.Run("Subscribe", func(t *testing.T) {
+		store := newStore(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		tokenIDs, err := store.SubscribeRevoked(ctx)
+		if err != nil {
+			t.Fatalf("SubscribeRevoked: %v", err)
+		}
+
+		go store.Revoke(context.Background(), tokenID("t1", "u1"), time.Now().Add(time.Hour))
+		select {
+		case id := <-tokenIDs:
+			if id != tokenID("t1", "u1") {
+				t.Fatalf("received %q, want %q", id, tokenID("t1", "u1"))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("revocation not received")
+		}
+
+		cancel()
+		for range tokenIDs {
+		}
+	})
+}
@@ -0,0 +1,168 @@
+// Package repotest - domain.TokensRepository 各实现共用的契约测试
+package repotest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/token/repository/body"
+)
+
+// TokensRepositoryContract - newRepo 每次调用须返回一个空的仓库,
+// 以及让该仓库的时钟前进 d 的函数, 用于验证过期
+func TokensRepositoryContract(t *testing.T, newRepo func(t *testing.T) (repo domain.TokensRepository, advance func(d time.Duration))) {
+	t.Run("CreateAndCheck", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		create(t, repo, "t1", "u1", time.Hour)
+
+		if ok, err := repo.CheckTokenID(ctx, token("t1", "u1")); err != nil || !ok {
+			t.Fatalf("CheckTokenID = %v, %v, want true", ok, err)
+		}
+		if ok, err := repo.CheckTokenID(ctx, body.NewTokenDetailBody(tokenID("t1", "u1"), "u2")); err != nil || ok {
+			t.Fatalf("CheckTokenID(other user) = %v, %v, want false", ok, err)
+		}
+		// 不存在的 token 返回错误, usecase 据此视为未登录
+		if _, err := repo.CheckTokenID(ctx, token("missing", "u1")); err == nil {
+			t.Fatal("CheckTokenID(missing): want error")
+		}
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		// 存储可只保留到秒
+		issuedAt := time.Now().Truncate(time.Second)
+		expiresAt := issuedAt.Add(time.Hour)
+		want := &body.SessionBody{
+			TokenID:   tokenID("at", "u1"),
+			UserID:    "u1",
+			Type:      domain.TokenTypeAccess,
+			IssuedAt:  &issuedAt,
+			ExpiresAt: &expiresAt,
+			Client:    "Mozilla/5.0",
+			IP:        "203.0.113.7",
+			ParentID:  tokenID("rt", "u1"),
+		}
+		if err := repo.CreateTokenID(ctx, want); err != nil {
+			t.Fatalf("CreateTokenID: %v", err)
+		}
+
+		got := list(t, repo, "u1")["at"]
+		if got == nil {
+			t.Fatal("ListUserTokenIDs: session not found")
+		}
+		if got.GetType() != want.Type || got.GetClient() != want.Client || got.GetIP() != want.IP || got.GetParentID() != want.ParentID {
+			t.Fatalf("session = %+v, want %+v", got, want)
+		}
+		if at := got.GetIssuedAt(); at == nil || !at.Equal(issuedAt) {
+			t.Fatalf("IssuedAt = %v, want %v", at, issuedAt)
+		}
+		if at := got.GetExpiresAt(); at == nil || !at.Equal(expiresAt) {
+			t.Fatalf("ExpiresAt = %v, want %v", at, expiresAt)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		repo, advance := newRepo(t)
+		ctx := context.Background()
+		create(t, repo, "short", "u1", time.Minute)
+		create(t, repo, "long", "u1", time.Hour)
+		create(t, repo, "forever", "u1", 0)
+
+		advance(2 * time.Minute)
+		if _, err := repo.CheckTokenID(ctx, token("short", "u1")); err == nil {
+			t.Fatal("CheckTokenID(expired): want error")
+		}
+		if ok, err := repo.CheckTokenID(ctx, token("long", "u1")); err != nil || !ok {
+			t.Fatalf("CheckTokenID(long) = %v, %v, want true", ok, err)
+		}
+
+		sessions := list(t, repo, "u1")
+		if len(sessions) != 2 {
+			t.Fatalf("ListUserTokenIDs = %d sessions, want 2", len(sessions))
+		}
+		if at := sessions["long"].GetExpiresAt(); at == nil {
+			t.Fatal("long: ExpiresAt not set")
+		}
+		if at := sessions["forever"].GetExpiresAt(); at != nil {
+			t.Fatalf("forever: ExpiresAt = %v, want nil", at)
+		}
+	})
+
+	t.Run("DeleteIdempotent", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		create(t, repo, "t1", "u1", time.Hour)
+
+		for i := 0; i < 2; i++ {
+			if err := repo.DeleteTokenID(ctx, tokenID("t1", "u1")); err != nil {
+				t.Fatalf("DeleteTokenID #%d: %v", i+1, err)
+			}
+		}
+		if _, err := repo.CheckTokenID(ctx, token("t1", "u1")); err == nil {
+			t.Fatal("CheckTokenID(deleted): want error")
+		}
+	})
+
+	t.Run("DeleteUserTokenIDs", func(t *testing.T) {
+		repo, _ := newRepo(t)
+		ctx := context.Background()
+		create(t, repo, "a", "u1", time.Hour)
+		create(t, repo, "b", "u1", time.Hour)
+		create(t, repo, "c", "u2", time.Hour)
+
+		for i := 0; i < 2; i++ {
+			if err := repo.DeleteUserTokenIDs(ctx, "u1"); err != nil {
+				t.Fatalf("DeleteUserTokenIDs #%d: %v", i+1, err)
+			}
+		}
+		if sessions := list(t, repo, "u1"); len(sessions) != 0 {
+			t.Fatalf("u1 has %d sessions after delete", len(sessions))
+		}
+		if sessions := list(t, repo, "u2"); len(sessions) != 1 {
+			t.Fatalf("u2 has %d sessions, want 1", len(sessions))
+		}
+	})
+}
+
+// tokenID - 格式为 uuid++userID
+func tokenID(id string, userID string) string {
+	return id + "++" + userID
+}
+
+func token(id string, userID string) domain.TokenDetail {
+	return body.NewTokenDetailBody(tokenID(id, userID), userID)
+}
+
+// create - expiration 为 0 表示永不过期
+func create(t *testing.T, repo domain.TokensRepository, id string, userID string, expiration time.Duration) {
+	t.Helper()
+	session := &body.SessionBody{TokenID: tokenID(id, userID), UserID: userID, Type: domain.TokenTypeRefresh}
+	if expiration > 0 {
+		expiresAt := time.Now().Add(expiration)
+		session.ExpiresAt = &expiresAt
+	}
+	if err := repo.CreateTokenID(context.Background(), session); err != nil {
+		t.Fatalf("CreateTokenID(%s): %v", id, err)
+	}
+}
+
+// list - tokenID 前缀 -> session
+func list(t *testing.T, repo domain.TokensRepository, userID string) map[string]domain.Session {
+	t.Helper()
+	sessions, err := repo.ListUserTokenIDs(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("ListUserTokenIDs: %v", err)
+	}
+	byID := map[string]domain.Session{}
+	for _, s := range sessions {
+		if s.GetUserID() != userID {
+			t.Fatalf("session %s belongs to %s, want %s", s.GetTokenID(), s.GetUserID(), userID)
+		}
+		byID[s.GetTokenID()[:len(s.GetTokenID())-len("++"+userID)]] = s
+	}
+	return byID
+}
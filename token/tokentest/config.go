@@ -0,0 +1,13 @@
+// Package tokentest - 各测试共用的 domain.TokenConfig
+package tokentest
+
+import "time"
+
+// Config - 固定的签名密钥与有效期
+type Config struct{}
+
+func (Config) GetIssuer() string                          { return "test" }
+func (Config) GetAccessTokenSecret() []byte               { return []byte("access-secret") }
+func (Config) GetRefreshTokenSecret() []byte              { return []byte("refresh-secret") }
+func (Config) GetAccessExpirationSeconds() time.Duration  { return 15 * time.Minute }
+func (Config) GetRefreshExpirationSeconds() time.Duration { return 24 * time.Hour }
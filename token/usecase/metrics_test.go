@@ -0,0 +1,56 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/metrics"
+	memrepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	"github.com/alibug/go-identity-entry/token/tokentest"
+	"github.com/alibug/go-identity-entry/token/usecase"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// delta - 执行 fn 前后 counter 的差值
+func delta(c prometheus.Counter, fn func()) float64 {
+	before := testutil.ToFloat64(c)
+	fn()
+	return testutil.ToFloat64(c) - before
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	tuc := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), tokentest.Config{})
+
+	var tokens domain.Tokens
+	issued := delta(metrics.TokensIssuedTotal.WithLabelValues("access"), func() {
+		var err error
+		if tokens, err = tuc.CreateTokens(ctx, "u1", domain.ClientInfo{}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if issued != 1 {
+		t.Errorf("access tokens issued = %v, want 1", issued)
+	}
+
+	valid := metrics.TokenVerificationsTotal.WithLabelValues("access", metrics.ResultSuccess, "")
+	if d := delta(valid, func() { tuc.CheckAccessToken(ctx, tokens.GetAccessToken()) }); d != 1 {
+		t.Errorf("valid access verifications = %v, want 1", d)
+	}
+	malformed := metrics.TokenVerificationsTotal.WithLabelValues("access", metrics.ResultFailure, "token_malformed")
+	if d := delta(malformed, func() { tuc.CheckAccessToken(ctx, "garbage") }); d != 1 {
+		t.Errorf("malformed access verifications = %v, want 1", d)
+	}
+
+	logouts := metrics.LogoutsTotal.WithLabelValues(metrics.ResultSuccess, "")
+	if d := delta(logouts, func() { tuc.CheckTokensAndLogout(ctx, tokens) }); d != 1 {
+		t.Errorf("logouts = %v, want 1", d)
+	}
+	// 已退出的 RefreshToken 记为 token_revoked
+	revoked := metrics.TokenVerificationsTotal.WithLabelValues("refresh", metrics.ResultFailure, "token_revoked")
+	if d := delta(revoked, func() { tuc.CheckRefreshToken(ctx, tokens.GetRefreshToken()) }); d != 1 {
+		t.Errorf("revoked refresh verifications = %v, want 1", d)
+	}
+}
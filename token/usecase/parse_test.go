@@ -0,0 +1,145 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	memrepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	"github.com/alibug/go-identity-entry/token/tokentest"
+	"github.com/alibug/go-identity-entry/token/usecase"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// sign - 按给定的 claims 签发 token, 用于构造各种无效的 token
+func sign(t *testing.T, method jwt.SigningMethod, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	s, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func claims(overrides map[string]interface{}) jwt.MapClaims {
+	c := jwt.MapClaims{
+		"aud": "u1",
+		"iss": "test",
+		"jti": "id++u1",
+		"typ": "access",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func TestCheckAccessTokenErrors(t *testing.T) {
+	ctx := context.Background()
+	access := tokentest.Config{}.GetAccessTokenSecret()
+	refresh := tokentest.Config{}.GetRefreshTokenSecret()
+	past := time.Now().Add(-time.Minute).Unix()
+	future := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"Expired", sign(t, jwt.SigningMethodHS256, access, claims(map[string]interface{}{"exp": past})), domain.ErrTokenExpired},
+		{"NotYetValid", sign(t, jwt.SigningMethodHS256, access, claims(map[string]interface{}{"nbf": future})), domain.ErrTokenNotYetValid},
+		{"IssuedInFuture", sign(t, jwt.SigningMethodHS256, access, claims(map[string]interface{}{"iat": future})), domain.ErrTokenNotYetValid},
+		{"BadSignature", sign(t, jwt.SigningMethodHS256, []byte("other-secret"), claims(nil)), domain.ErrTokenBadSignature},
+		// 签名错误时 不报告为过期
+		{"BadSignatureAndExpired", sign(t, jwt.SigningMethodHS256, []byte("other-secret"), claims(map[string]interface{}{"exp": past})), domain.ErrTokenBadSignature},
+		{"AlgNone", sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, claims(nil)), domain.ErrTokenBadSignature},
+		{"Garbage", "garbage", domain.ErrTokenMalformed},
+		{"Empty", "", domain.ErrTokenMalformed},
+		{"MissingJti", sign(t, jwt.SigningMethodHS256, access, claims(map[string]interface{}{"jti": nil})), domain.ErrTokenMalformed},
+		{"MissingAud", sign(t, jwt.SigningMethodHS256, access, claims(map[string]interface{}{"aud": nil})), domain.ErrTokenMalformed},
+		{"NonStringAud", sign(t, jwt.SigningMethodHS256, access, claims(map[string]interface{}{"aud": 42})), domain.ErrTokenMalformed},
+		// RefreshToken 用 refresh 密钥签名, 当作 AccessToken 使用
+		{"RefreshAsAccess", sign(t, jwt.SigningMethodHS256, refresh, claims(map[string]interface{}{"typ": "refresh"})), domain.ErrTokenWrongType},
+		// 两种 token 使用相同密钥时 依靠 typ 区分
+		{"WrongTypClaim", sign(t, jwt.SigningMethodHS256, access, claims(map[string]interface{}{"typ": "refresh"})), domain.ErrTokenWrongType},
+	}
+	modes := map[string][]usecase.Option{
+		"Stored":    nil,
+		"Stateless": {usecase.WithRevocationList(memrepo.NewMemoryRevocationList())},
+	}
+	for mode, opts := range modes {
+		tuc := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), tokentest.Config{}, opts...)
+		for _, tt := range tests {
+			t.Run(mode+"/"+tt.name, func(t *testing.T) {
+				_, exist, err := tuc.CheckAccessToken(ctx, tt.token)
+				if exist || !errors.Is(err, tt.want) {
+					t.Fatalf("CheckAccessToken = %v, %v, want %v", exist, err, tt.want)
+				}
+				// 均为 401
+				if !errors.Is(err, status.ErrUnauthorized) {
+					t.Fatalf("%v is not ErrUnauthorized", err)
+				}
+			})
+		}
+	}
+}
+
+func TestCheckTokenRevoked(t *testing.T) {
+	ctx := context.Background()
+
+	// 无状态模式 查询已撤销列表
+	tuc := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), tokentest.Config{}, usecase.WithRevocationList(memrepo.NewMemoryRevocationList()))
+	tokens, err := tuc.CreateTokens(ctx, "u1", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tuc.CheckTokensAndLogout(ctx, tokens); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tuc.CheckAccessToken(ctx, tokens.GetAccessToken()); !errors.Is(err, domain.ErrTokenRevoked) {
+		t.Fatalf("CheckAccessToken after logout err = %v, want ErrTokenRevoked", err)
+	}
+	if _, err := tuc.RefreshTokens(ctx, tokens.GetRefreshToken(), domain.ClientInfo{}); !errors.Is(err, domain.ErrTokenRevoked) {
+		t.Fatalf("RefreshTokens after logout err = %v, want ErrTokenRevoked", err)
+	}
+
+	// AccessToken 不能用于刷新
+	tokens, err = tuc.CreateTokens(ctx, "u1", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tuc.RefreshTokens(ctx, tokens.GetAccessToken(), domain.ClientInfo{}); !errors.Is(err, domain.ErrTokenWrongType) {
+		t.Fatalf("RefreshTokens(access token) err = %v, want ErrTokenWrongType", err)
+	}
+}
+
+func TestLogoutWithExpiredAccessToken(t *testing.T) {
+	ctx := context.Background()
+	tuc := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), tokentest.Config{})
+	tokens, err := tuc.CreateTokens(ctx, "u1", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired := sign(t, jwt.SigningMethodHS256, tokentest.Config{}.GetAccessTokenSecret(), claims(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()}))
+
+	// 只有过期的 AccessToken 时 返回具体原因
+	if _, err := tuc.CheckTokensAndLogout(ctx, &usecase.TokensBody{AccessToken: expired}); !errors.Is(err, domain.ErrTokenExpired) {
+		t.Fatalf("CheckTokensAndLogout(expired) err = %v, want ErrTokenExpired", err)
+	}
+	// 同时带有效的 RefreshToken 时 仍可退出登录
+	userID, err := tuc.CheckTokensAndLogout(ctx, &usecase.TokensBody{AccessToken: expired, RefreshToken: tokens.GetRefreshToken()})
+	if err != nil || userID != "u1" {
+		t.Fatalf("CheckTokensAndLogout = %q, %v", userID, err)
+	}
+	if _, exist, _ := tuc.CheckRefreshToken(ctx, tokens.GetRefreshToken()); exist {
+		t.Fatal("refresh token still exists after logout")
+	}
+}
@@ -0,0 +1,141 @@
+package usecase
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// SigningKey - AccessToken 的非对称签名密钥, 公钥通过 JWKS 公开, 其他服务可在本地校验 AccessToken;
+// kid 为公钥的 JWK 指纹 (RFC 7638), 无需另行配置
+type SigningKey struct {
+	kid    string
+	method jwt.SigningMethod
+	key    crypto.Signer
+	jwk    domain.JWK
+}
+
+// NewSigningKey - 支持 *rsa.PrivateKey (RS256) 与 P-256 / P-384 / P-521 的 *ecdsa.PrivateKey (ES256 / ES384 / ES512)
+func NewSigningKey(key crypto.Signer) (*SigningKey, error) {
+	k := &SigningKey{key: key}
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("rsa signing key must be at least 2048 bits")
+		}
+		k.method = jwt.SigningMethodRS256
+		k.jwk = domain.JWK{
+			Kty: "RSA",
+			N:   encodeBigInt(key.N, 0),
+			E:   encodeBigInt(big.NewInt(int64(key.E)), 0),
+		}
+	case *ecdsa.PrivateKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		switch key.Curve {
+		case elliptic.P256():
+			k.method = jwt.SigningMethodES256
+		case elliptic.P384():
+			k.method = jwt.SigningMethodES384
+		case elliptic.P521():
+			k.method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported ecdsa curve %s", key.Curve.Params().Name)
+		}
+		k.jwk = domain.JWK{
+			Kty: "EC",
+			Crv: key.Curve.Params().Name,
+			X:   encodeBigInt(key.X, size),
+			Y:   encodeBigInt(key.Y, size),
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+	k.kid = thumbprint(k.jwk)
+	k.jwk.Kid = k.kid
+	k.jwk.Use = "sig"
+	k.jwk.Alg = k.method.Alg()
+	return k, nil
+}
+
+// ParseSigningKeyPEM - 读取 PEM 格式的私钥: PKCS#1 (RSA PRIVATE KEY), SEC 1 (EC PRIVATE KEY) 或 PKCS#8 (PRIVATE KEY)
+func ParseSigningKeyPEM(data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+	var (
+		key interface{}
+		err error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported signing key type %T", key)
+	}
+	return NewSigningKey(signer)
+}
+
+// KeyID - JWT 头部的 kid
+func (k *SigningKey) KeyID() string {
+	return k.kid
+}
+
+// JWK - 公钥
+func (k *SigningKey) JWK() domain.JWK {
+	return k.jwk
+}
+
+// thumbprint - RFC 7638: 只包含必需成员, 按字典序排列, 不含空白
+func thumbprint(k domain.JWK) string {
+	var members interface{}
+	if k.Kty == "RSA" {
+		members = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{k.E, k.Kty, k.N}
+	} else {
+		members = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+		}{k.Crv, k.Kty, k.X, k.Y}
+	}
+	b, _ := json.Marshal(members)
+	sum := sha256.Sum256(b)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// encodeBigInt - size 大于 0 时 左侧补零到 size 字节, EC 坐标须为定长
+func encodeBigInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
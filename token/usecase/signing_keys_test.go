@@ -0,0 +1,70 @@
+package usecase_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/token/usecase"
+)
+
+func TestParseSigningKeyPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(rsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		block *pem.Block
+		alg   string
+	}{
+		{"PKCS1", &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}, "RS256"},
+		{"SEC1", &pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER}, "ES384"},
+		{"PKCS8", &pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}, "RS256"},
+	}
+	kids := map[string]string{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := usecase.ParseSigningKeyPEM(pem.EncodeToMemory(tt.block))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if jwk := key.JWK(); jwk.Alg != tt.alg || jwk.Kid != key.KeyID() || key.KeyID() == "" {
+				t.Fatalf("JWK = %+v", jwk)
+			}
+			kids[tt.name] = key.KeyID()
+		})
+	}
+	// kid 只取决于公钥
+	if kids["PKCS1"] != kids["PKCS8"] || kids["PKCS1"] == kids["SEC1"] {
+		t.Fatalf("kids = %v", kids)
+	}
+
+	small, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := usecase.NewSigningKey(small); err == nil {
+		t.Fatal("want error for a 1024-bit RSA key")
+	}
+	if _, err := usecase.ParseSigningKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte{1}})); err == nil {
+		t.Fatal("want error for a public key")
+	}
+}
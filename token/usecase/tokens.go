@@ -3,8 +3,8 @@ package usecase
 import "time"
 
 // NewTokenDetailBody - new a TokenDetailBody
-func NewTokenDetailBody(tokenID string, userID string) *TokenDetailBody {
-	return &TokenDetailBody{tokenID, userID}
+func NewTokenDetailBody(tokenID string, userID string, scopes ...string) *TokenDetailBody {
+	return &TokenDetailBody{tokenID: tokenID, userID: userID, scopes: scopes}
 }
 
 // GetTokenID - implement domain.TokenDetail interface
@@ -17,10 +17,22 @@ func (t *TokenDetailBody) GetUserID() string {
 	return t.userID
 }
 
+// GetScopes - implement domain.TokenDetail interface
+func (t *TokenDetailBody) GetScopes() []string {
+	return t.scopes
+}
+
+// GetExpiresAt - implement domain.TokenDetail interface
+func (t *TokenDetailBody) GetExpiresAt() *time.Time {
+	return t.expiresAt
+}
+
 // TokenDetailBody - implement domain.TokenDetail interface
 type TokenDetailBody struct {
-	tokenID string
-	userID  string
+	tokenID   string
+	userID    string
+	scopes    []string
+	expiresAt *time.Time
 }
 
 // JwtParams -
@@ -31,6 +43,7 @@ type JwtParams struct {
 	jwtID             string
 	audience          string
 	issuer            string
+	scopes            []string
 }
 
 // GetExpirationSeconds -
@@ -63,8 +76,13 @@ func (j *JwtParams) GetIssueTime() time.Time {
 	return j.issueTime
 }
 
+// GetScopes -
+func (j *JwtParams) GetScopes() []string {
+	return j.scopes
+}
+
 // NewJwtParams - Create New jwtParams
-func NewJwtParams(issueTime time.Time, expirationSeconds time.Duration, secret []byte, issuer string, jwtID string, audience string) *JwtParams {
+func NewJwtParams(issueTime time.Time, expirationSeconds time.Duration, secret []byte, issuer string, jwtID string, audience string, scopes ...string) *JwtParams {
 	return &JwtParams{
 		issueTime:         issueTime,
 		expirationSeconds: expirationSeconds,
@@ -72,6 +90,7 @@ func NewJwtParams(issueTime time.Time, expirationSeconds time.Duration, secret [
 		jwtID:             jwtID,
 		audience:          audience,
 		issuer:            issuer,
+		scopes:            scopes,
 	}
 }
 
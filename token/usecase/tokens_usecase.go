@@ -2,42 +2,103 @@ package usecase
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/alibug/go-identity-entry/domain"
-	"github.com/alibug/go-identity-utils/config"
+	eventBody "github.com/alibug/go-identity-entry/event/repository/body"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/metrics"
+	tokenBody "github.com/alibug/go-identity-entry/token/repository/body"
+	"github.com/alibug/go-identity-entry/tracing"
 	"github.com/alibug/go-identity-utils/status"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 )
 
 // TokensUsecase - 用于操作 token
 type TokensUsecase struct {
 	tokensRepo  domain.TokensRepository
-	tokenConfig config.TokenConfig
+	tokenConfig domain.TokenConfig
+	publisher   domain.EventPublisher
+	revocations domain.RevocationList
+	signingKeys []*SigningKey
+	users       domain.UserRepository
+}
+
+// Option - 用于配置 TokensUsecase 的可选参数
+type Option func(*TokensUsecase)
+
+// WithEventPublisher - 发布 UserLoggedIn 等领域事件
+func WithEventPublisher(p domain.EventPublisher) Option {
+	return func(t *TokensUsecase) {
+		t.publisher = p
+	}
+}
+
+// WithRevocationList - 无状态校验 AccessToken: 只校验签名与过期时间, 并查询已撤销列表,
+// 不再逐个查询 TokensRepository; RefreshToken 仍在 TokensRepository 中校验
+func WithRevocationList(rl domain.RevocationList) Option {
+	return func(t *TokensUsecase) {
+		t.revocations = rl
+	}
+}
+
+// WithAccessSigningKeys - AccessToken 改用非对称签名, 第一个密钥用于签发, 全部密钥用于校验 并通过 JWKS 公开;
+// 轮换密钥时 将新密钥放在第一个, 旧密钥保留到其签发的 AccessToken 全部过期. RefreshToken 仍使用 HMAC
+func WithAccessSigningKeys(keys ...*SigningKey) Option {
+	return func(t *TokensUsecase) {
+		t.signingKeys = keys
+	}
+}
+
+// WithUserRepository - AccessToken 中写入用户的 roles claim, 供 client 包的 RequireRoles 校验;
+// 每次签发(包括刷新)都重新读取, 角色变更在下一次刷新后生效
+func WithUserRepository(repo domain.UserRepository) Option {
+	return func(t *TokensUsecase) {
+		t.users = repo
+	}
 }
 
 // NewTokensUsecase will create new an tokenUsecase object representation of domain.TokenUsecase interface
-func NewTokensUsecase(repo domain.TokensRepository, tc config.TokenConfig) *TokensUsecase {
-	return &TokensUsecase{
+func NewTokensUsecase(repo domain.TokensRepository, tc domain.TokenConfig, opts ...Option) *TokensUsecase {
+	t := &TokensUsecase{
 		tokensRepo:  repo,
 		tokenConfig: tc,
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 // CheckTokensAndLogout - 用于检查 AccessToken 与 RefreshToken 并在存储中删除
-func (t *TokensUsecase) CheckTokensAndLogout(ctx context.Context, tokens domain.Tokens) error {
+func (t *TokensUsecase) CheckTokensAndLogout(ctx context.Context, tokens domain.Tokens) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.CheckTokensAndLogout")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		metrics.LogoutsTotal.WithLabelValues(metrics.Outcome(err)).Inc()
+	}()
+	var userID string
 	// 1、CheckTokens
 	if tokens.GetAccessToken() != "" {
-		// 1.1、检查 AccessToken
+		// 1.1、检查 AccessToken, 已过期的 AccessToken 无需删除, 仍可凭 RefreshToken 退出登录
 		atd, atdExist, err := t.CheckAccessToken(ctx, tokens.GetAccessToken())
-		if err != nil {
-			return err
+		if err != nil && !(errors.Is(err, domain.ErrTokenExpired) && tokens.GetRefreshToken() != "") {
+			return "", err
 		}
 		// 1.3、删除 atd
+		if err == nil {
+			userID = atd.GetUserID()
+		}
 		if atdExist {
 			t.deleteTokenID(ctx, atd.GetTokenID())
+			if err := t.revoke(ctx, atd.GetTokenID(), atd.GetExpiresAt()); err != nil {
+				return "", err
+			}
 		}
 	}
 
@@ -45,14 +106,19 @@ func (t *TokensUsecase) CheckTokensAndLogout(ctx context.Context, tokens domain.
 		// 1.2、检查 RefreshToken
 		rtd, rtdExist, err := t.CheckRefreshToken(ctx, tokens.GetRefreshToken())
 		if err != nil {
-			return err
+			return "", err
 		}
+		userID = rtd.GetUserID()
 		// 1.4、删除 rtd
 		if rtdExist {
 			t.deleteTokenID(ctx, rtd.GetTokenID())
 		}
 	}
-	return nil
+	// Token 已删除, 事件写入失败不影响退出登录
+	if err := t.publish(ctx, domain.EventUserLoggedOut, userID); err != nil {
+		logging.FromContext(ctx).Warn("publish event failed", zap.String("event", string(domain.EventUserLoggedOut)), zap.Error(err))
+	}
+	return userID, nil
 }
 
 // deleteTokenID - 删除指定 的 Token
@@ -60,22 +126,155 @@ func (t *TokensUsecase) deleteTokenID(ctx context.Context, tokenID string) error
 	return t.tokensRepo.DeleteTokenID(ctx, tokenID)
 }
 
-// CreateTokens - 同时创建 AccessToken 与 RefreshToken
-func (t *TokensUsecase) CreateTokens(ctx context.Context, userID string) (domain.Tokens, error) {
+// ListSessions - 列出用户所有未过期的 Token
+func (t *TokensUsecase) ListSessions(ctx context.Context, userID string) (_ []domain.Session, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.ListSessions")
+	defer func() { tracing.End(span, err) }()
+	return t.tokensRepo.ListUserTokenIDs(ctx, userID)
+}
+
+// RevokeUserTokens - 删除用户所有的 Token, 使其在所有设备上退出登录
+func (t *TokensUsecase) RevokeUserTokens(ctx context.Context, userID string) (err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.RevokeUserTokens")
+	defer func() { tracing.End(span, err) }()
+	// 无状态模式下 删除记录不能使 AccessToken 失效, 须先逐个撤销
+	if t.revocations != nil {
+		sessions, err := t.tokensRepo.ListUserTokenIDs(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, s := range sessions {
+			// 旧格式的记录没有类型, 一并撤销
+			if s.GetType() == domain.TokenTypeRefresh {
+				continue
+			}
+			if err := t.revoke(ctx, s.GetTokenID(), s.GetExpiresAt()); err != nil {
+				return err
+			}
+		}
+	}
+	err = t.tokensRepo.DeleteUserTokenIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if err := t.publish(ctx, domain.EventSessionRevoked, userID); err != nil {
+		logging.FromContext(ctx).Warn("publish event failed", zap.String("event", string(domain.EventSessionRevoked)), zap.Error(err))
+	}
+	return nil
+}
+
+// revoke - 无状态模式下 将 AccessToken 加入已撤销列表; expiresAt 未知时 按 AccessToken 的最长有效期保留
+func (t *TokensUsecase) revoke(ctx context.Context, tokenID string, expiresAt *time.Time) error {
+	if t.revocations == nil {
+		return nil
+	}
+	until := time.Now().Add(t.tokenConfig.GetAccessExpirationSeconds())
+	if expiresAt != nil {
+		until = *expiresAt
+	}
+	return t.revocations.Revoke(ctx, tokenID, until)
+}
+
+// publish - 未配置 publisher 时 忽略事件
+func (t *TokensUsecase) publish(ctx context.Context, eventType domain.EventType, userID string) error {
+	if t.publisher == nil || userID == "" {
+		return nil
+	}
+	return t.publisher.Publish(ctx, eventBody.NewEventBody(eventType, userID, nil))
+}
+
+// CreateTokens - 同时创建 AccessToken 与 RefreshToken, AccessToken 的记录指向其 RefreshToken
+func (t *TokensUsecase) CreateTokens(ctx context.Context, userID string, client domain.ClientInfo, scopes ...string) (_ domain.Tokens, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.CreateTokens")
+	defer func() { tracing.End(span, err) }()
+	return t.issueTokens(ctx, userID, client, scopes...)
+}
+
+// LoginTokens - 登录时签发 Tokens, 并发布 UserLoggedIn
+func (t *TokensUsecase) LoginTokens(ctx context.Context, userID string, client domain.ClientInfo, scopes ...string) (_ domain.Tokens, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.LoginTokens")
+	defer func() { tracing.End(span, err) }()
+	tokens, err := t.issueTokens(ctx, userID, client, scopes...)
+	if err != nil {
+		return nil, err
+	}
+	// Token 已写入, 事件写入失败不影响登录
+	if err := t.publish(ctx, domain.EventUserLoggedIn, userID); err != nil {
+		logging.FromContext(ctx).Warn("publish event failed", zap.String("event", string(domain.EventUserLoggedIn)), zap.Error(err))
+	}
+	return tokens, nil
+}
+
+// RefreshTokens - 用 RefreshToken 换取新的 Tokens, scope 不变;
+// 旧的 RefreshToken 及由它签发的 AccessToken 随即失效
+func (t *TokensUsecase) RefreshTokens(ctx context.Context, refreshToken string, client domain.ClientInfo) (_ domain.Tokens, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.RefreshTokens")
+	defer func() { tracing.End(span, err) }()
+	// 1、检查 RefreshToken
+	rtd, exist, err := t.CheckRefreshToken(ctx, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, domain.ErrTokenRevoked
+	}
+
+	// 2、删除旧的 RefreshToken 与其 AccessToken
+	sessions, err := t.tokensRepo.ListUserTokenIDs(ctx, rtd.GetUserID())
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sessions {
+		if s.GetParentID() != rtd.GetTokenID() {
+			continue
+		}
+		t.deleteTokenID(ctx, s.GetTokenID())
+		if err := t.revoke(ctx, s.GetTokenID(), s.GetExpiresAt()); err != nil {
+			return nil, err
+		}
+	}
+	if err := t.deleteTokenID(ctx, rtd.GetTokenID()); err != nil {
+		return nil, err
+	}
+
+	// 3、签发新的 Tokens
+	return t.issueTokens(ctx, rtd.GetUserID(), client, rtd.GetScopes()...)
+}
+
+// issueTokens - 先创建 RefreshToken, AccessToken 的记录指向它
+func (t *TokensUsecase) issueTokens(ctx context.Context, userID string, client domain.ClientInfo, scopes ...string) (domain.Tokens, error) {
+	roles, err := t.userRoles(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now()
-	at, err := t.CreateAccessToken(ctx, userID, now)
+	rt, rtID, err := t.CreateRefreshToken(ctx, userID, now, client, scopes...)
 	if err != nil {
 		return nil, err
 	}
-	rt, err := t.CreateRefreshToken(ctx, userID, now)
+	at, err := t.CreateAccessToken(ctx, userID, now, client, rtID, roles, scopes...)
 	if err != nil {
 		return nil, err
 	}
 	return &TokensBody{AccessToken: at, RefreshToken: rt}, nil
 }
 
-// CreateAccessToken - 创建 AccessToken
-func (t *TokensUsecase) CreateAccessToken(ctx context.Context, userID string, now time.Time) (string, error) {
+// userRoles - 未配置 UserRepository 时 AccessToken 不含 roles
+func (t *TokensUsecase) userRoles(ctx context.Context, userID string) ([]string, error) {
+	if t.users == nil {
+		return nil, nil
+	}
+	user, err := t.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return user.GetRoles(), nil
+}
+
+// CreateAccessToken - 创建 AccessToken, parentID 为同时签发的 RefreshToken 的 tokenID, roles 写入 roles claim
+func (t *TokensUsecase) CreateAccessToken(ctx context.Context, userID string, now time.Time, client domain.ClientInfo, parentID string, roles []string, scopes ...string) (_ string, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.CreateAccessToken")
+	defer func() { tracing.End(span, err) }()
 	atUUID := fmt.Sprintf("%s%s%s", uuid.NewString(), "++", userID)
 	atParams := NewJwtParams(
 		now,
@@ -84,12 +283,20 @@ func (t *TokensUsecase) CreateAccessToken(ctx context.Context, userID string, no
 		t.tokenConfig.GetIssuer(),
 		atUUID,
 		userID,
+		scopes...,
 	)
-	return t.createToken(ctx, atParams)
+	return t.createToken(ctx, atParams, roles, &tokenBody.SessionBody{
+		Type:     domain.TokenTypeAccess,
+		Client:   client.UserAgent,
+		IP:       client.IP,
+		ParentID: parentID,
+	})
 }
 
-// CreateRefreshToken - 创建 RefreshToken
-func (t *TokensUsecase) CreateRefreshToken(ctx context.Context, userID string, now time.Time) (string, error) {
+// CreateRefreshToken - 创建 RefreshToken, 同时返回其 tokenID
+func (t *TokensUsecase) CreateRefreshToken(ctx context.Context, userID string, now time.Time, client domain.ClientInfo, scopes ...string) (_ string, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.CreateRefreshToken")
+	defer func() { tracing.End(span, err) }()
 	rtUUID := fmt.Sprintf("%s%s%s", uuid.NewString(), "++", userID)
 	rtParams := NewJwtParams(
 		now,
@@ -98,47 +305,117 @@ func (t *TokensUsecase) CreateRefreshToken(ctx context.Context, userID string, n
 		t.tokenConfig.GetIssuer(),
 		rtUUID,
 		userID,
+		scopes...,
 	)
-	return t.createToken(ctx, rtParams)
+	rt, err := t.createToken(ctx, rtParams, nil, &tokenBody.SessionBody{
+		Type:   domain.TokenTypeRefresh,
+		Client: client.UserAgent,
+		IP:     client.IP,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return rt, rtUUID, nil
 }
 
-// CreateToken - 实现创建 Token
-func (t *TokensUsecase) createToken(ctx context.Context, params domain.JwtParams) (string, error) {
+// CreateToken - 实现创建 Token, record 中的 TokenID, UserID 与时间 由 params 填充
+func (t *TokensUsecase) createToken(ctx context.Context, params domain.JwtParams, roles []string, record *tokenBody.SessionBody) (string, error) {
 	// tokenExpires := params.GetIssueTime().Add(time.Second * params.GetExpiration())
-	tokenExpires := params.GetIssueTime().Add(params.GetExpirationSeconds())
+	issuedAt := params.GetIssueTime()
+	tokenExpires := issuedAt.Add(params.GetExpirationSeconds())
 	atClaims := jwt.MapClaims{}
 	atClaims["aud"] = params.GetAudience()
 	atClaims["iss"] = params.GetIssuer()
 	atClaims["jti"] = params.GetJwtID()
 	atClaims["exp"] = tokenExpires.Unix()
+	atClaims["typ"] = string(record.Type)
+	if len(params.GetScopes()) > 0 {
+		atClaims["scope"] = strings.Join(params.GetScopes(), " ")
+	}
+	if len(roles) > 0 {
+		atClaims["roles"] = roles
+	}
 
-	at := jwt.NewWithClaims(jwt.SigningMethodHS256, atClaims)
-	atStr, err := at.SignedString(params.GetSecret())
+	atStr, err := t.sign(atClaims, record.Type, params.GetSecret())
 	if err != nil {
 		return "", fmt.Errorf("%w : create token error", status.ErrInternalServerError)
 	}
 
-	err = t.tokensRepo.CreateTokenID(ctx, NewTokenDetailBody(params.GetJwtID(), params.GetAudience()), params.GetExpirationSeconds())
+	record.TokenID = params.GetJwtID()
+	record.UserID = params.GetAudience()
+	record.IssuedAt = &issuedAt
+	record.ExpiresAt = &tokenExpires
+	err = t.tokensRepo.CreateTokenID(ctx, record)
 
 	if err != nil {
 		return "", err
 	}
+	metrics.TokensIssuedTotal.WithLabelValues(string(record.Type)).Inc()
 	return atStr, nil
 }
 
+// sign - 配置了非对称密钥时 AccessToken 使用第一个密钥签名, 其他情况使用 HMAC
+func (t *TokensUsecase) sign(claims jwt.MapClaims, typ domain.TokenType, secret []byte) (string, error) {
+	if typ == domain.TokenTypeAccess && len(t.signingKeys) > 0 {
+		key := t.signingKeys[0]
+		token := jwt.NewWithClaims(key.method, claims)
+		token.Header["kid"] = key.kid
+		return token.SignedString(key.key)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+}
+
 // CheckAccessToken - 检查 AccessToken 是否正确
-func (t *TokensUsecase) CheckAccessToken(ctx context.Context, tokenStr string) (domain.TokenDetail, bool, error) {
-	return t.checkToken(ctx, tokenStr, t.tokenConfig.GetAccessTokenSecret())
+func (t *TokensUsecase) CheckAccessToken(ctx context.Context, tokenStr string) (td domain.TokenDetail, exist bool, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.CheckAccessToken")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		observeVerification(domain.TokenTypeAccess, exist, err)
+	}()
+	if t.revocations != nil {
+		return t.checkStatelessToken(ctx, tokenStr)
+	}
+	return t.checkToken(ctx, tokenStr, domain.TokenTypeAccess)
+}
+
+// checkStatelessToken - 签名与过期时间正确 且未被撤销 即视为存在
+func (t *TokensUsecase) checkStatelessToken(ctx context.Context, tokenStr string) (domain.TokenDetail, bool, error) {
+	td, err := t.parseToken(tokenStr, domain.TokenTypeAccess)
+	if err != nil {
+		return nil, false, err
+	}
+	revoked, err := t.revocations.IsRevoked(ctx, td.GetTokenID())
+	if err != nil {
+		return nil, false, err
+	}
+	if revoked {
+		return nil, false, domain.ErrTokenRevoked
+	}
+	return td, true, nil
 }
 
 // CheckRefreshToken - 检查 RefreshToken 是否正确
-func (t *TokensUsecase) CheckRefreshToken(ctx context.Context, tokenStr string) (domain.TokenDetail, bool, error) {
-	return t.checkToken(ctx, tokenStr, t.tokenConfig.GetRefreshTokenSecret())
+func (t *TokensUsecase) CheckRefreshToken(ctx context.Context, tokenStr string) (td domain.TokenDetail, exist bool, err error) {
+	ctx, span := tracing.Start(ctx, "TokensUsecase.CheckRefreshToken")
+	defer func() { tracing.End(span, err) }()
+	defer func() {
+		observeVerification(domain.TokenTypeRefresh, exist, err)
+	}()
+	return t.checkToken(ctx, tokenStr, domain.TokenTypeRefresh)
+}
+
+// observeVerification - 解析成功但不存在于存储中 与 ErrTokenRevoked 同样记为 token_revoked
+func observeVerification(typ domain.TokenType, exist bool, err error) {
+	if err == nil && !exist {
+		err = domain.ErrTokenRevoked
+	}
+	result, reason := metrics.Outcome(err)
+	metrics.TokenVerificationsTotal.WithLabelValues(string(typ), result, reason).Inc()
 }
 
 // checkToken - 返回值 tokenDetail, 是否存在于数据库, 是否出错
-func (t *TokensUsecase) checkToken(ctx context.Context, tokenStr string, secret []byte) (domain.TokenDetail, bool, error) {
-	td, err := parseJWTToken(tokenStr, secret)
+func (t *TokensUsecase) checkToken(ctx context.Context, tokenStr string, typ domain.TokenType) (domain.TokenDetail, bool, error) {
+	td, err := t.parseToken(tokenStr, typ)
 	if err != nil {
 		return nil, false, err
 	}
@@ -147,36 +424,121 @@ func (t *TokensUsecase) checkToken(ctx context.Context, tokenStr string, secret
 		return td, false, nil
 	}
 	if !ok {
-		return nil, false, fmt.Errorf("%w : userID not match", status.ErrUnauthorized)
+		return nil, false, domain.ErrTokenInvalid
 	}
 	return td, true, nil
 }
 
-func parseJWTToken(tokenStr string, secret []byte) (domain.TokenDetail, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		//Make sure that the token method conform to "SigningMethodHMAC" SigningMethodHS256
-		// if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// keyFunc - 两种 token 使用不同的密钥签名; 配置了非对称密钥时 AccessToken 只接受这些密钥的签名
+func (t *TokensUsecase) keyFunc(typ domain.TokenType) jwt.Keyfunc {
+	if typ == domain.TokenTypeAccess && len(t.signingKeys) > 0 {
+		return func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			for _, key := range t.signingKeys {
+				// alg 须与密钥一致, 防止用公钥冒充 HMAC 密钥
+				if key.kid == kid && token.Method.Alg() == key.method.Alg() {
+					return key.key.Public(), nil
+				}
+			}
+			return nil, fmt.Errorf("unknown signing key %q for %v", kid, token.Header["alg"])
+		}
+	}
+	secret := t.tokenConfig.GetAccessTokenSecret()
+	if typ == domain.TokenTypeRefresh {
+		secret = t.tokenConfig.GetRefreshTokenSecret()
+	}
+	return func(token *jwt.Token) (interface{}, error) {
+		// 只接受 HMAC, 防止 alg: none 或用公钥冒充 HMAC 密钥
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("%w : invalid method", status.ErrInternalServerError)
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
 		}
 		return secret, nil
-	})
+	}
+}
+
+// JWKS - implement domain.TokensUseCase interface
+func (t *TokensUsecase) JWKS() domain.JWKSet {
+	set := domain.JWKSet{Keys: make([]domain.JWK, 0, len(t.signingKeys))}
+	for _, key := range t.signingKeys {
+		set.Keys = append(set.Keys, key.jwk)
+	}
+	return set
+}
+
+// CheckSigningKeys - 用于就绪检查: 两种 token 的签名密钥均已配置; 使用非对称密钥时 不需要 AccessToken 的 HMAC 密钥
+func (t *TokensUsecase) CheckSigningKeys() error {
+	if len(t.tokenConfig.GetRefreshTokenSecret()) == 0 || (len(t.signingKeys) == 0 && len(t.tokenConfig.GetAccessTokenSecret()) == 0) {
+		return errors.New("token signing secret not configured")
+	}
+	return nil
+}
 
+// parseToken - 用 typ 对应的密钥校验; 签名错误 但能通过另一种 token 的密钥校验时, 返回 ErrTokenWrongType
+func (t *TokensUsecase) parseToken(tokenStr string, typ domain.TokenType) (*TokenDetailBody, error) {
+	td, err := parseJWTToken(tokenStr, t.keyFunc(typ), typ)
+	if !errors.Is(err, domain.ErrTokenBadSignature) {
+		return td, err
+	}
+	other := domain.TokenTypeAccess
+	if typ == domain.TokenTypeAccess {
+		other = domain.TokenTypeRefresh
+	}
+	if _, otherErr := parseJWTToken(tokenStr, t.keyFunc(other), other); otherErr == nil || errors.Is(otherErr, domain.ErrTokenExpired) {
+		return nil, domain.ErrTokenWrongType
+	}
+	return nil, err
+}
+
+// parseJWTToken - 校验签名、有效期与 typ, 失败时返回 domain.ErrToken* 之一;
+// 旧版本签发的 token 没有 typ, 只能依靠不同的密钥区分
+func parseJWTToken(tokenStr string, keyFunc jwt.Keyfunc, typ domain.TokenType) (*TokenDetailBody, error) {
+	token, err := jwt.Parse(tokenStr, keyFunc)
 	if err != nil {
-		return nil, fmt.Errorf("%w : jwt parse error", status.ErrInternalServerError)
+		return nil, classifyJWTError(err)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
-	if ok && token.Valid {
-		tokenUUID, ok := claims["jti"].(string)
-		if !ok {
-			return nil, fmt.Errorf("%w : jti not found", status.ErrInternalServerError)
-		}
-		userID, ok := claims["aud"].(string)
-		if err != nil {
-			return nil, fmt.Errorf("%w : aud not found", status.ErrInternalServerError)
-		}
-		return NewTokenDetailBody(tokenUUID, userID), nil
+	if !ok || !token.Valid {
+		return nil, domain.ErrTokenMalformed
+	}
+	tokenUUID, ok := claims["jti"].(string)
+	if !ok || tokenUUID == "" {
+		return nil, domain.ErrTokenMalformed
+	}
+	userID, ok := claims["aud"].(string)
+	if !ok || userID == "" {
+		return nil, domain.ErrTokenMalformed
+	}
+	if tokenType, ok := claims["typ"].(string); ok && domain.TokenType(tokenType) != typ {
+		return nil, domain.ErrTokenWrongType
+	}
+	scope, _ := claims["scope"].(string)
+	td := NewTokenDetailBody(tokenUUID, userID, strings.Fields(scope)...)
+	// exp 已由 jwt.Parse 校验, 此处只记录
+	if exp, ok := claims["exp"].(float64); ok {
+		expiresAt := time.Unix(int64(exp), 0)
+		td.expiresAt = &expiresAt
+	}
+	return td, nil
+}
+
+// classifyJWTError - jwt.Parse 会同时设置多个错误位, 签名错误优先于有效期错误,
+// 避免伪造的 token 被报告为过期
+func classifyJWTError(err error) error {
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return domain.ErrTokenMalformed
+	}
+	switch {
+	case ve.Errors&jwt.ValidationErrorMalformed != 0:
+		return domain.ErrTokenMalformed
+	case ve.Errors&(jwt.ValidationErrorUnverifiable|jwt.ValidationErrorSignatureInvalid) != 0:
+		return domain.ErrTokenBadSignature
+	case ve.Errors&jwt.ValidationErrorExpired != 0:
+		return domain.ErrTokenExpired
+	case ve.Errors&(jwt.ValidationErrorNotValidYet|jwt.ValidationErrorIssuedAt) != 0:
+		return domain.ErrTokenNotYetValid
+	default:
+		return domain.ErrTokenMalformed
 	}
-	return nil, err
 }
@@ -0,0 +1,147 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/domain"
+	memrepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	"github.com/alibug/go-identity-entry/token/tokentest"
+	"github.com/alibug/go-identity-entry/token/usecase"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+// countingRepo - 统计 CheckTokenID 的调用次数
+type countingRepo struct {
+	domain.TokensRepository
+	checks int
+}
+
+func (c *countingRepo) CheckTokenID(ctx context.Context, token domain.TokenDetail) (bool, error) {
+	c.checks++
+	return c.TokensRepository.CheckTokenID(ctx, token)
+}
+
+func TestStatelessAccessToken(t *testing.T) {
+	ctx := context.Background()
+	repo := &countingRepo{TokensRepository: memrepo.NewMemoryTokensRepository()}
+	tuc := usecase.NewTokensUsecase(repo, tokentest.Config{}, usecase.WithRevocationList(memrepo.NewMemoryRevocationList()))
+
+	tokens, err := tuc.CreateTokens(ctx, "u1", domain.ClientInfo{IP: "203.0.113.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td, exist, err := tuc.CheckAccessToken(ctx, tokens.GetAccessToken())
+	if err != nil || !exist || td.GetUserID() != "u1" {
+		t.Fatalf("CheckAccessToken = %v, %v, %v", td, exist, err)
+	}
+	if repo.checks != 0 {
+		t.Fatalf("CheckTokenID called %d times for an access token", repo.checks)
+	}
+
+	if _, err := tuc.CheckTokensAndLogout(ctx, tokens); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tuc.CheckAccessToken(ctx, tokens.GetAccessToken()); !errors.Is(err, status.ErrUnauthorized) {
+		t.Fatalf("CheckAccessToken after logout err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestStatelessRevokeUserTokens(t *testing.T) {
+	ctx := context.Background()
+	repo := memrepo.NewMemoryTokensRepository()
+	tuc := usecase.NewTokensUsecase(repo, tokentest.Config{}, usecase.WithRevocationList(memrepo.NewMemoryRevocationList()))
+
+	var issued []domain.Tokens
+	for i := 0; i < 2; i++ {
+		tokens, err := tuc.CreateTokens(ctx, "u1", domain.ClientInfo{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		issued = append(issued, tokens)
+	}
+	other, err := tuc.CreateTokens(ctx, "u2", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tuc.RevokeUserTokens(ctx, "u1"); err != nil {
+		t.Fatal(err)
+	}
+	for _, tokens := range issued {
+		if _, _, err := tuc.CheckAccessToken(ctx, tokens.GetAccessToken()); !errors.Is(err, status.ErrUnauthorized) {
+			t.Fatalf("CheckAccessToken after revoke err = %v, want ErrUnauthorized", err)
+		}
+	}
+	if _, exist, err := tuc.CheckAccessToken(ctx, other.GetAccessToken()); err != nil || !exist {
+		t.Fatalf("CheckAccessToken(other user) = %v, %v", exist, err)
+	}
+}
+
+type noSecretConfig struct{ tokentest.Config }
+
+func (noSecretConfig) GetRefreshTokenSecret() []byte { return nil }
+
+func TestCheckSigningKeys(t *testing.T) {
+	if err := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), tokentest.Config{}).CheckSigningKeys(); err != nil {
+		t.Fatalf("CheckSigningKeys() = %v", err)
+	}
+	if err := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), noSecretConfig{}).CheckSigningKeys(); err == nil {
+		t.Fatal("CheckSigningKeys() without refresh secret = nil")
+	}
+}
+
+// failingPublisher - 写入事件总是失败
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, event domain.Event) error {
+	return errors.New("outbox unavailable")
+}
+
+func TestPublishFailureDoesNotFail(t *testing.T) {
+	ctx := context.Background()
+	tuc := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), tokentest.Config{}, usecase.WithEventPublisher(failingPublisher{}))
+
+	// Token 已经写入, 与退出登录、撤销一致 事件写入失败只记录日志
+	tokens, err := tuc.LoginTokens(ctx, "u1", domain.ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginTokens = %v, want nil", err)
+	}
+	if _, err := tuc.CheckTokensAndLogout(ctx, tokens); err != nil {
+		t.Fatalf("CheckTokensAndLogout = %v, want nil", err)
+	}
+	if err := tuc.RevokeUserTokens(ctx, "u1"); err != nil {
+		t.Fatalf("RevokeUserTokens = %v, want nil", err)
+	}
+}
+
+// recordingPublisher - 记录发布的事件类型
+type recordingPublisher struct {
+	types []domain.EventType
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, event domain.Event) error {
+	p.types = append(p.types, event.GetType())
+	return nil
+}
+
+// 只有登录发布 UserLoggedIn; 修改密码后重新签发 Token 不是登录
+func TestOnlyLoginPublishesUserLoggedIn(t *testing.T) {
+	ctx := context.Background()
+	publisher := &recordingPublisher{}
+	tuc := usecase.NewTokensUsecase(memrepo.NewMemoryTokensRepository(), tokentest.Config{}, usecase.WithEventPublisher(publisher))
+
+	if _, err := tuc.CreateTokens(ctx, "u1", domain.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.types) != 0 {
+		t.Fatalf("CreateTokens published %v, want nothing", publisher.types)
+	}
+	if _, err := tuc.LoginTokens(ctx, "u1", domain.ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(publisher.types) != 1 || publisher.types[0] != domain.EventUserLoggedIn {
+		t.Fatalf("LoginTokens published %v, want [UserLoggedIn]", publisher.types)
+	}
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// redisHook - implement redis.Hook; 不使用 redisotel: 其 db.statement 包含命令的全部参数
+type redisHook struct{}
+
+// RedisHook - 通过 client.AddHook 添加, 在已有 span 下为每个命令创建子 span, 只记录命令名
+func RedisHook() redis.Hook {
+	return redisHook{}
+}
+
+func (redisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return startRedis(ctx, cmd.FullName(), attribute.Int("db.redis.num_cmd", 1)), nil
+}
+
+func (redisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedis(ctx, cmd.Err())
+	return nil
+}
+
+func (redisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return startRedis(ctx, "pipeline", attribute.Int("db.redis.num_cmd", len(cmds))), nil
+}
+
+func (redisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			endRedis(ctx, cmd.Err())
+			return nil
+		}
+	}
+	endRedis(ctx, nil)
+	return nil
+}
+
+// startRedis - 没有父 span 时 (如后台任务) 不创建 span
+func startRedis(ctx context.Context, operation string, attrs ...attribute.KeyValue) context.Context {
+	if !trace.SpanFromContext(ctx).IsRecording() {
+		return ctx
+	}
+	attrs = append(attrs, semconv.DBSystemRedis, semconv.DBOperationKey.String(operation))
+	ctx, _ = otel.Tracer(instrumentationName).Start(ctx, "redis."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+	return ctx
+}
+
+// endRedis - redis.Nil 表示键不存在, 不视为失败
+func endRedis(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+	if err != nil && err != redis.Nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,144 @@
+// Package tracing - OpenTelemetry 链路追踪
+//
+// Setup 设置全局的 TracerProvider 与 propagator; 未调用或 exporter 为 none 时 otel 使用 no-op 实现,
+// Start 创建的 span 不产生任何开销. HTTP 的 server span 由 otelgin 创建, MongoDB 的 span 由 otelmongo 创建,
+// Redis 的 span 由 RedisHook 创建, usecase 方法通过 Start 与 End 创建子 span.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName - 本服务创建的 span 所属的 tracer
+const instrumentationName = "github.com/alibug/go-identity-entry"
+
+// exporter 的取值
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type config struct {
+	exporter    string
+	endpoint    string
+	insecure    bool
+	sampleRatio float64
+	serviceName string
+	writer      io.Writer
+}
+
+// Option - Setup 可选配置
+type Option func(*config)
+
+// WithExporter - none (默认), stdout 或 otlp
+func WithExporter(exporter string) Option {
+	return func(c *config) {
+		c.exporter = exporter
+	}
+}
+
+// WithOTLPEndpoint - OTLP gRPC 地址, 默认 localhost:4317; insecure 为 true 时不使用 TLS
+func WithOTLPEndpoint(endpoint string, insecure bool) Option {
+	return func(c *config) {
+		c.endpoint = endpoint
+		c.insecure = insecure
+	}
+}
+
+// WithSampleRatio - 根 span 的采样比例, 默认 1; 有上游 span 时 沿用上游的采样决定
+func WithSampleRatio(ratio float64) Option {
+	return func(c *config) {
+		c.sampleRatio = ratio
+	}
+}
+
+// WithServiceName - 默认 go-identity-entry
+func WithServiceName(name string) Option {
+	return func(c *config) {
+		c.serviceName = name
+	}
+}
+
+// WithWriter - stdout exporter 的输出, 默认 os.Stdout
+func WithWriter(w io.Writer) Option {
+	return func(c *config) {
+		c.writer = w
+	}
+}
+
+// Setup - 设置全局 TracerProvider 与 W3C trace context propagator;
+// 返回的 shutdown 在退出前调用, 以导出缓冲中的 span
+func Setup(ctx context.Context, opts ...Option) (shutdown func(context.Context) error, err error) {
+	c := &config{
+		exporter:    ExporterNone,
+		endpoint:    "localhost:4317",
+		sampleRatio: 1,
+		serviceName: "go-identity-entry",
+		writer:      os.Stdout,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch c.exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(c.writer))
+	case ExporterOTLP:
+		clientOpts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(c.endpoint)}
+		if c.insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, clientOpts...)
+	default:
+		return nil, fmt.Errorf("unsupported trace exporter: %s", c.exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.sampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceNameKey.String(c.serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start - 以 ctx 中的 span 为父 span 创建子 span
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End - 结束 span; 带错误码的错误是客户端错误, 只记录错误码, 其他错误标记 span 失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		if coded := domain.AsCoded(err); coded != nil {
+			span.SetAttributes(attribute.String("error.code", string(coded.ErrorCode())))
+		} else {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
@@ -0,0 +1,125 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/tracing"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// record - 将全局 TracerProvider 替换为记录所有 span 的实现
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func ended(recorder *tracetest.SpanRecorder, name string) sdktrace.ReadOnlySpan {
+	for _, s := range recorder.Ended() {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func TestEnd(t *testing.T) {
+	recorder := record(t)
+	ctx := context.Background()
+
+	_, span := tracing.Start(ctx, "ok")
+	tracing.End(span, nil)
+	_, span = tracing.Start(ctx, "client")
+	tracing.End(span, domain.ErrTokenExpired)
+	_, span = tracing.Start(ctx, "internal")
+	tracing.End(span, errors.New("mongo: connection refused"))
+
+	if s := ended(recorder, "ok"); s == nil || s.Status().Code != codes.Unset {
+		t.Errorf("ok span = %v", s)
+	}
+	// 客户端错误 只记录错误码, 不标记失败
+	client := ended(recorder, "client")
+	if client == nil || client.Status().Code == codes.Error || len(client.Attributes()) != 1 || client.Attributes()[0].Value.AsString() != "token_expired" {
+		t.Errorf("client span = %v", client)
+	}
+	internal := ended(recorder, "internal")
+	if internal == nil || internal.Status().Code != codes.Error || len(internal.Events()) != 1 {
+		t.Errorf("internal span = %v", internal)
+	}
+}
+
+func TestRedisHook(t *testing.T) {
+	recorder := record(t)
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	client.AddHook(tracing.RedisHook())
+
+	// 没有父 span 时 不创建 span
+	client.Set(context.Background(), "k", "secret-value", 0)
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("got %d spans without a parent", n)
+	}
+
+	ctx, parent := tracing.Start(context.Background(), "parent")
+	client.Get(ctx, "missing")
+	client.Pipelined(ctx, func(p redis.Pipeliner) error {
+		p.Get(ctx, "k")
+		p.Del(ctx, "k")
+		return nil
+	})
+	parent.End()
+
+	get := ended(recorder, "redis.get")
+	if get == nil || get.Parent().SpanID() != parent.SpanContext().SpanID() || get.Status().Code == codes.Error {
+		t.Fatalf("redis.get span = %v", get)
+	}
+	if ended(recorder, "redis.pipeline") == nil {
+		t.Fatal("redis.pipeline span missing")
+	}
+	// 只记录命令名, 不记录参数
+	for _, span := range recorder.Ended() {
+		for _, attr := range span.Attributes() {
+			if strings.Contains(attr.Value.Emit(), "secret-value") || strings.Contains(attr.Value.Emit(), "missing") {
+				t.Errorf("span %s attribute %s contains a command argument", span.Name(), attr.Key)
+			}
+		}
+	}
+}
+
+func TestSetupStdout(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var buf bytes.Buffer
+	shutdown, err := tracing.Setup(context.Background(), tracing.WithExporter(tracing.ExporterStdout), tracing.WithWriter(&buf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := tracing.Start(context.Background(), "userUsecase.CheckAccountAndPassUC")
+	tracing.End(span, nil)
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"Name":"userUsecase.CheckAccountAndPassUC"`) || !strings.Contains(buf.String(), "go-identity-entry") {
+		t.Fatalf("stdout exporter output: %s", buf.String())
+	}
+}
+
+func TestSetupUnknownExporter(t *testing.T) {
+	if _, err := tracing.Setup(context.Background(), tracing.WithExporter("zipkin")); err == nil {
+		t.Fatal("want error for unknown exporter")
+	}
+}
@@ -0,0 +1,97 @@
+// Package account - 账号标识的规范化
+//
+// 账号在注册与登录时都会经过 Normalize, 保证 "Alice"、" alice " 与 "ａｌｉｃｅ" 对应同一个账号.
+package account
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-utils/status"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// confusableScripts - 外形相近, 不允许在同一账号中混用的文字
+var confusableScripts = map[string]*unicode.RangeTable{
+	"Latin":    unicode.Latin,
+	"Cyrillic": unicode.Cyrillic,
+	"Greek":    unicode.Greek,
+}
+
+// gmailDomains - 忽略本地部分中 "." 的邮箱域名
+var gmailDomains = map[string]bool{
+	"gmail.com":      true,
+	"googlemail.com": true,
+}
+
+// Normalizer - implement domain.AccountNormalizer
+type Normalizer struct {
+	// CanonicalizeEmail - 对邮箱账号 去掉 +tag, gmail 去掉本地部分的 "."
+	CanonicalizeEmail bool
+}
+
+// NewNormalizer - 创建账号规范化器
+func NewNormalizer(canonicalizeEmail bool) *Normalizer {
+	return &Normalizer{CanonicalizeEmail: canonicalizeEmail}
+}
+
+// Normalize - trim、NFKC、case fold, 可选的邮箱规范化; 账号为空或混用易混淆文字时返回 error
+func (n *Normalizer) Normalize(account string) (string, error) {
+	// case fold 之后可能不再是 NFKC, 因此前后各做一次
+	s := norm.NFKC.String(strings.TrimSpace(account))
+	s = norm.NFKC.String(cases.Fold().String(s))
+	if s == "" {
+		return "", domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidAccount, "account is empty")
+	}
+
+	if script, other := mixedScripts(s); other != "" {
+		return "", domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidAccount,
+			fmt.Sprintf("account mixes %s and %s characters", script, other))
+	}
+
+	if n.CanonicalizeEmail {
+		s = canonicalizeEmail(s)
+	}
+	return s, nil
+}
+
+// mixedScripts - 返回账号中出现的 两种易混淆文字, 未混用时 other 为空
+func mixedScripts(s string) (script string, other string) {
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		for name, table := range confusableScripts {
+			if !unicode.Is(table, r) {
+				continue
+			}
+			if script == "" {
+				script = name
+			} else if script != name {
+				return script, name
+			}
+		}
+	}
+	return script, ""
+}
+
+func canonicalizeEmail(s string) string {
+	at := strings.LastIndex(s, "@")
+	if at <= 0 {
+		return s
+	}
+	local, domain := s[:at], s[at+1:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if gmailDomains[domain] {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	return local + "@" + domain
+}
@@ -0,0 +1,47 @@
+package account
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/alibug/go-identity-utils/status"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		canonical bool
+		in        string
+		want      string
+	}{
+		{"trim and fold", false, "  Alice ", "alice"},
+		{"fullwidth", false, "ＡＬＩＣＥ", "alice"},
+		{"ligature", false, "ﬁona", "fiona"},
+		{"sharp s", false, "Straße", "strasse"},
+		{"email kept", false, "John.Doe+news@GMail.com", "john.doe+news@gmail.com"},
+		{"email canonical", true, "John.Doe+news@GoogleMail.com", "johndoe@gmail.com"},
+		{"other domain keeps dots", true, "john.doe+x@example.com", "john.doe@example.com"},
+		{"cjk", false, "张三", "张三"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewNormalizer(tt.canonical).Normalize(tt.in)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeRejects(t *testing.T) {
+	// 第二个字符为西里尔字母 а (U+0430)
+	for _, in := range []string{"   ", "pаypal"} {
+		_, err := NewNormalizer(false).Normalize(in)
+		if !errors.Is(err, status.ErrBadParamInput) {
+			t.Errorf("Normalize(%q) error = %v, want ErrBadParamInput", in, err)
+		}
+	}
+}
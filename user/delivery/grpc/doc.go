@@ -0,0 +1,4 @@
+// Package grpcdelivery - 供内部服务调用的 gRPC 接口, 与 restgin 共用 domain.UserUsecase 和 domain.TokensUseCase
+package grpcdelivery
+
+//go:generate buf generate
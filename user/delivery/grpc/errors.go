@@ -0,0 +1,76 @@
+package grpcdelivery
+
+import (
+	"context"
+	"errors"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-utils/status"
+	"go.uber.org/zap"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// errorDomain - ErrorInfo 的 domain, reason 为 domain.ErrorCode
+const errorDomain = "identity"
+
+// statusCodes - usecase 返回的错误 对应的 gRPC 状态码, 按顺序以 errors.Is 匹配
+var statusCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{status.ErrBadParamInput, codes.InvalidArgument},
+	{status.ErrUnauthorized, codes.Unauthenticated},
+	{status.ErrForbidden, codes.PermissionDenied},
+	{status.ErrNotFound, codes.NotFound},
+	{status.ErrConflict, codes.AlreadyExists},
+}
+
+// toStatus - 带错误码的错误 在 details 中附带 ErrorInfo, 有字段错误时 (如密码不符合策略) 再附带 BadRequest;
+// Internal 只返回通用的 message, 与 REST 接口一致
+func toStatus(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	code := codes.Internal
+	for _, c := range statusCodes {
+		if errors.Is(err, c.err) {
+			code = c.code
+			break
+		}
+	}
+	if code == codes.Internal {
+		logging.FromContext(ctx).Error("rpc failed", zap.Error(err))
+		return grpcstatus.Error(codes.Internal, "internal server error")
+	}
+
+	coded := domain.AsCoded(err)
+	if coded == nil {
+		return grpcstatus.Error(code, err.Error())
+	}
+	message := coded.Error()
+	var ce *domain.CodedError
+	if errors.As(err, &ce) {
+		message = ce.Message
+	}
+	info := &errdetails.ErrorInfo{Reason: string(coded.ErrorCode()), Domain: errorDomain}
+	st, detailErr := grpcstatus.New(code, message).WithDetails(info)
+	if detailErr != nil {
+		return grpcstatus.Error(code, message)
+	}
+	if fieldErrs := coded.FieldErrors(); len(fieldErrs) > 0 {
+		br := &errdetails.BadRequest{}
+		for _, fe := range fieldErrs {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+			})
+		}
+		if withBR, err := st.WithDetails(br); err == nil {
+			st = withBR
+		}
+	}
+	return st.Err()
+}
@@ -0,0 +1,64 @@
+package grpcdelivery
+
+import (
+	"context"
+	"strings"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// publicMethods - 不需要 access token 的方法, 其余方法 (包括以后新增的) 都需要
+var publicMethods = map[string]bool{
+	"/identity.v1.Identity/Register":      true,
+	"/identity.v1.Identity/Login":         true,
+	"/identity.v1.Identity/Logout":        true,
+	"/identity.v1.Identity/Refresh":       true,
+	"/identity.v1.Identity/ValidateToken": true,
+}
+
+type userIDKey struct{}
+
+// UserIDFrom - 取出 UnaryAuthInterceptor 写入的当前用户ID
+func UserIDFrom(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(string)
+	return userID, ok
+}
+
+// UnaryAuthInterceptor - 校验 metadata 中 authorization: Bearer <access token>, 并将 userID 写入 ctx;
+// 与 restgin.MustLoginInterceptor 一致, 受限 Token (带 scope) 不能调用
+func UnaryAuthInterceptor(tuc domain.TokensUseCase) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if publicMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+		token := bearerToken(ctx)
+		if token == "" {
+			return nil, grpcstatus.Error(codes.Unauthenticated, "You are not logged in")
+		}
+		td, exist, err := tuc.CheckAccessToken(ctx, token)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+		if !exist {
+			return nil, toStatus(ctx, domain.ErrTokenRevoked)
+		}
+		if len(td.GetScopes()) > 0 {
+			return nil, grpcstatus.Error(codes.PermissionDenied, "Your password has expired, please change it first")
+		}
+		return handler(context.WithValue(ctx, userIDKey{}, td.GetUserID()), req)
+	}
+}
+
+func bearerToken(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		if len(v) > 7 && strings.EqualFold(v[:7], "Bearer ") {
+			return strings.TrimSpace(v[7:])
+		}
+	}
+	return ""
+}
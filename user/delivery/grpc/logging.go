@@ -0,0 +1,53 @@
+package grpcdelivery
+
+import (
+	"context"
+	"time"
+
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/requestid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
+)
+
+// requestIDKey - metadata 中的请求 ID, 与 HTTP 的 X-Request-ID 相同
+const requestIDKey = "x-request-id"
+
+// UnaryLoggingInterceptor - 沿用 metadata 中的 x-request-id 并在响应 header 中返回, 将带 request_id 的
+// logger 写入 ctx, 调用结束后记录一条日志; 须在 UnaryAuthInterceptor 之前
+func UnaryLoggingInterceptor(base *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		var candidate string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(requestIDKey); len(v) > 0 {
+				candidate = v[0]
+			}
+		}
+		id := requestid.Ensure(candidate)
+		grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+		logger := base.With(zap.String("request_id", id))
+		ctx = logging.NewContext(requestid.NewContext(ctx, id), logger)
+
+		resp, err := handler(ctx, req)
+
+		code := grpcstatus.Code(err)
+		level := zapcore.InfoLevel
+		switch code {
+		case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+			level = zapcore.ErrorLevel
+		}
+		if ce := logger.Check(level, "rpc"); ce != nil {
+			ce.Write(
+				zap.String("method", info.FullMethod),
+				zap.String("code", code.String()),
+				zap.Duration("latency", time.Since(start)),
+			)
+		}
+		return resp, err
+	}
+}
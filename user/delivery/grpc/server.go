@@ -0,0 +1,227 @@
+package grpcdelivery
+
+import (
+	"context"
+	"net"
+
+	auditBody "github.com/alibug/go-identity-entry/audit/repository/body"
+	"github.com/alibug/go-identity-entry/domain"
+	tokenBody "github.com/alibug/go-identity-entry/token/repository/body"
+	"github.com/alibug/go-identity-entry/user/delivery/grpc/identitypb"
+	userBody "github.com/alibug/go-identity-entry/user/repository/body"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	grpcstatus "google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// IdentityServer - implement identitypb.IdentityServer
+type IdentityServer struct {
+	identitypb.UnimplementedIdentityServer
+	userUsecase   domain.UserUsecase
+	tokensUsecase domain.TokensUseCase
+	auditUsecase  domain.AuditUsecase
+}
+
+// NewIdentityServer - 在 s 上注册 Identity 服务; s 须配置 UnaryAuthInterceptor
+func NewIdentityServer(s *grpc.Server, uuc domain.UserUsecase, tuc domain.TokensUseCase, auc domain.AuditUsecase) {
+	identitypb.RegisterIdentityServer(s, &IdentityServer{
+		userUsecase:   uuc,
+		tokensUsecase: tuc,
+		auditUsecase:  auc,
+	})
+}
+
+// Register - 注册用户
+func (s *IdentityServer) Register(ctx context.Context, req *identitypb.RegisterRequest) (*identitypb.RegisterResponse, error) {
+	if req.GetAccount() == "" || req.GetPassword() == "" || req.GetDisplayName() == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "account, password and display_name are required")
+	}
+	err := s.userUsecase.RegisterUserUC(ctx, &userBody.RegisterBody{
+		Account:     req.GetAccount(),
+		Password:    req.GetPassword(),
+		Displayname: req.GetDisplayName(),
+	})
+	s.recordAudit(ctx, domain.AuditRegister, "", req.GetAccount(), err)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &identitypb.RegisterResponse{}, nil
+}
+
+// Login - 密码过期时 只签发修改密码用的受限 Token
+func (s *IdentityServer) Login(ctx context.Context, req *identitypb.LoginRequest) (*identitypb.LoginResponse, error) {
+	if req.GetAccount() == "" || req.GetPassword() == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "account and password are required")
+	}
+	user, err := s.userUsecase.CheckAccountAndPassUC(ctx, req.GetAccount(), req.GetPassword())
+	if err != nil {
+		s.recordAudit(ctx, domain.AuditLogin, "", req.GetAccount(), err)
+		return nil, toStatus(ctx, err)
+	}
+
+	var scopes []string
+	expired := s.userUsecase.PasswordExpiredUC(user)
+	if expired {
+		scopes = append(scopes, domain.ScopePasswordChange)
+	}
+	tokens, err := s.tokensUsecase.LoginTokens(ctx, user.GetUserID(), clientInfo(ctx), scopes...)
+	s.recordAudit(ctx, domain.AuditLogin, user.GetUserID(), req.GetAccount(), err)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &identitypb.LoginResponse{
+		Tokens:          toTokens(tokens),
+		UserId:          user.GetUserID(),
+		DisplayName:     user.GetDisplayName(),
+		PasswordExpired: expired,
+	}, nil
+}
+
+// Logout - 删除给定的 Tokens
+func (s *IdentityServer) Logout(ctx context.Context, req *identitypb.LogoutRequest) (*identitypb.LogoutResponse, error) {
+	if req.GetAccessToken() == "" && req.GetRefreshToken() == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "access_token or refresh_token is required")
+	}
+	userID, err := s.tokensUsecase.CheckTokensAndLogout(ctx, &tokenBody.TokenBody{
+		AccessToken:  req.GetAccessToken(),
+		RefreshToken: req.GetRefreshToken(),
+	})
+	s.recordAudit(ctx, domain.AuditLogout, userID, "", err)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &identitypb.LogoutResponse{UserId: userID}, nil
+}
+
+// Refresh - 用 RefreshToken 换取新的 Tokens
+func (s *IdentityServer) Refresh(ctx context.Context, req *identitypb.RefreshRequest) (*identitypb.RefreshResponse, error) {
+	if req.GetRefreshToken() == "" {
+		return nil, grpcstatus.Error(codes.InvalidArgument, "refresh_token is required")
+	}
+	tokens, err := s.tokensUsecase.RefreshTokens(ctx, req.GetRefreshToken(), clientInfo(ctx))
+	var userID string
+	if err == nil {
+		// RefreshTokens 不返回用户, 从新签发的 access token 中取出
+		if td, exist, checkErr := s.tokensUsecase.CheckAccessToken(ctx, tokens.GetAccessToken()); checkErr == nil && exist {
+			userID = td.GetUserID()
+		}
+	}
+	s.recordAudit(ctx, domain.AuditTokenRefresh, userID, "", err)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+	return &identitypb.RefreshResponse{Tokens: toTokens(tokens)}, nil
+}
+
+// ValidateToken - token 无效时 返回 valid: false 而不是错误;
+// 与 REST 的 MustLoginInterceptor 一致, 受限 token 只在 accept_scopes 包含其 scope 时有效
+func (s *IdentityServer) ValidateToken(ctx context.Context, req *identitypb.ValidateTokenRequest) (*identitypb.ValidateTokenResponse, error) {
+	td, exist, err := s.tokensUsecase.CheckAccessToken(ctx, req.GetAccessToken())
+	if err != nil || !exist || !scopeAccepted(td.GetScopes(), req.GetAcceptScopes()) {
+		return &identitypb.ValidateTokenResponse{Valid: false}, nil
+	}
+	resp := &identitypb.ValidateTokenResponse{
+		Valid:   true,
+		UserId:  td.GetUserID(),
+		TokenId: td.GetTokenID(),
+		Scopes:  td.GetScopes(),
+	}
+	if at := td.GetExpiresAt(); at != nil {
+		resp.ExpiresAt = timestamppb.New(*at)
+	}
+	return resp, nil
+}
+
+// GetUser - 查询其他用户 需要 admin 角色
+func (s *IdentityServer) GetUser(ctx context.Context, req *identitypb.GetUserRequest) (*identitypb.User, error) {
+	currentID, ok := UserIDFrom(ctx)
+	if !ok {
+		return nil, grpcstatus.Error(codes.Unauthenticated, "You are not logged in")
+	}
+	current, err := s.userUsecase.GetByIDUC(ctx, currentID)
+	if err != nil {
+		return nil, toStatus(ctx, err)
+	}
+
+	user := current
+	if id := req.GetUserId(); id != "" && id != currentID {
+		if !hasRole(current, "admin") {
+			return nil, grpcstatus.Error(codes.PermissionDenied, "You have no permission")
+		}
+		user, err = s.userUsecase.GetByIDUC(ctx, id)
+		if err != nil {
+			return nil, toStatus(ctx, err)
+		}
+	}
+	return &identitypb.User{
+		UserId:      user.GetUserID(),
+		Account:     user.GetAccount(),
+		DisplayName: user.GetDisplayName(),
+		Roles:       user.GetRoles(),
+	}, nil
+}
+
+func scopeAccepted(scopes []string, accept []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		for _, a := range accept {
+			if s == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasRole(user domain.User, role string) bool {
+	for _, r := range user.GetRoles() {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func toTokens(tokens domain.Tokens) *identitypb.Tokens {
+	return &identitypb.Tokens{AccessToken: tokens.GetAccessToken(), RefreshToken: tokens.GetRefreshToken()}
+}
+
+// clientInfo - 调用方的地址 与 user-agent
+func clientInfo(ctx context.Context) domain.ClientInfo {
+	var info domain.ClientInfo
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		info.IP = p.Addr.String()
+		if host, _, err := net.SplitHostPort(info.IP); err == nil {
+			info.IP = host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ua := md.Get("user-agent"); len(ua) > 0 {
+			info.UserAgent = ua[0]
+		}
+	}
+	return info
+}
+
+// recordAudit - 记录审计事件, err 不为 nil 时记为失败
+func (s *IdentityServer) recordAudit(ctx context.Context, eventType domain.AuditEventType, userID string, account string, err error) {
+	client := clientInfo(ctx)
+	event := &auditBody.EventBody{
+		Type:      eventType,
+		Outcome:   domain.AuditSuccess,
+		UserID:    userID,
+		Account:   account,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+	}
+	if err != nil {
+		event.Outcome = domain.AuditFailure
+		event.Reason = err.Error()
+	}
+	s.auditUsecase.RecordUC(ctx, event)
+}
@@ -0,0 +1,289 @@
+package grpcdelivery_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	_auditRepo "github.com/alibug/go-identity-entry/audit/repository/memory"
+	_auditUseCase "github.com/alibug/go-identity-entry/audit/usecase"
+	"github.com/alibug/go-identity-entry/domain"
+	_tokenRepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	"github.com/alibug/go-identity-entry/token/tokentest"
+	_tokenUseCase "github.com/alibug/go-identity-entry/token/usecase"
+	grpcdelivery "github.com/alibug/go-identity-entry/user/delivery/grpc"
+	"github.com/alibug/go-identity-entry/user/delivery/grpc/identitypb"
+	"github.com/alibug/go-identity-entry/user/password"
+	_userRepo "github.com/alibug/go-identity-entry/user/repository/memory"
+	_userUseCase "github.com/alibug/go-identity-entry/user/usecase"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClient - 使用内存仓库 通过 bufconn 启动服务
+func newClient(t *testing.T) identitypb.IdentityClient {
+	c, _ := newClientAndTokens(t)
+	return c
+}
+
+// newClientAndTokens - 同时返回服务使用的 TokensUseCase, 用于直接签发 token
+func newClientAndTokens(t *testing.T) (identitypb.IdentityClient, domain.TokensUseCase) {
+	c, tuc, _ := newClientWithAudit(t)
+	return c, tuc
+}
+
+// newClientWithAudit - 另外返回 AuditUsecase, 用于检查审计记录
+func newClientWithAudit(t *testing.T) (identitypb.IdentityClient, domain.TokensUseCase, domain.AuditUsecase) {
+	uuc := _userUseCase.NewUserUsecase(_userRepo.NewMemoryUserRepository(), time.Second,
+		_userUseCase.WithPasswordHasher(password.New(password.NewBcrypt(bcrypt.MinCost))),
+		_userUseCase.WithPasswordPolicy(password.NewPolicy(password.PolicyConfig{MinLength: 8})),
+	)
+	tuc := _tokenUseCase.NewTokensUsecase(_tokenRepo.NewMemoryTokensRepository(), tokentest.Config{})
+	auc := _auditUseCase.NewAuditUsecase(_auditRepo.NewMemoryAuditRepository(), time.Second)
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		grpcdelivery.UnaryLoggingInterceptor(zap.NewNop()),
+		grpcdelivery.UnaryAuthInterceptor(tuc),
+	))
+	grpcdelivery.NewIdentityServer(srv, uuc, tuc, auc)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return identitypb.NewIdentityClient(conn), tuc, auc
+}
+
+func withToken(accessToken string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+accessToken)
+}
+
+func register(t *testing.T, c identitypb.IdentityClient, account string) *identitypb.LoginResponse {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := c.Register(ctx, &identitypb.RegisterRequest{Account: account, Password: "correct horse battery", DisplayName: "Alice"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	resp, err := c.Login(ctx, &identitypb.LoginRequest{Account: account, Password: "correct horse battery"})
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	return resp
+}
+
+func assertCode(t *testing.T, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Fatalf("code = %v, want %v (err %v)", got, want, err)
+	}
+}
+
+// assertReason - details 中 ErrorInfo 的 reason 为 domain.ErrorCode
+func assertReason(t *testing.T, err error, want string) {
+	t.Helper()
+	for _, d := range status.Convert(err).Details() {
+		if info, ok := d.(*errdetails.ErrorInfo); ok && info.GetReason() == want {
+			return
+		}
+	}
+	t.Fatalf("reason %s not found in %v", want, status.Convert(err).Details())
+}
+
+func TestLoginValidateGetUser(t *testing.T) {
+	c := newClient(t)
+	login := register(t, c, "alice@example.com")
+	if login.GetUserId() == "" || login.GetDisplayName() != "Alice" || login.GetPasswordExpired() {
+		t.Fatalf("Login = %v", login)
+	}
+
+	v, err := c.ValidateToken(context.Background(), &identitypb.ValidateTokenRequest{AccessToken: login.GetTokens().GetAccessToken()})
+	if err != nil || !v.GetValid() || v.GetUserId() != login.GetUserId() || v.GetExpiresAt() == nil {
+		t.Fatalf("ValidateToken = %v, %v", v, err)
+	}
+	v, err = c.ValidateToken(context.Background(), &identitypb.ValidateTokenRequest{AccessToken: "garbage"})
+	if err != nil || v.GetValid() {
+		t.Fatalf("ValidateToken(garbage) = %v, %v", v, err)
+	}
+
+	user, err := c.GetUser(withToken(login.GetTokens().GetAccessToken()), &identitypb.GetUserRequest{})
+	if err != nil || user.GetUserId() != login.GetUserId() || user.GetAccount() != "alice@example.com" {
+		t.Fatalf("GetUser = %v, %v", user, err)
+	}
+}
+
+func TestValidateRestrictedToken(t *testing.T) {
+	c, tuc := newClientAndTokens(t)
+	ctx := context.Background()
+	login := register(t, c, "alice@example.com")
+
+	// 密码过期时签发的受限 token, 与 REST 一致 默认不被接受
+	tokens, err := tuc.CreateTokens(ctx, login.GetUserId(), domain.ClientInfo{}, domain.ScopePasswordChange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.ValidateToken(ctx, &identitypb.ValidateTokenRequest{AccessToken: tokens.GetAccessToken()})
+	if err != nil || v.GetValid() || v.GetUserId() != "" {
+		t.Fatalf("ValidateToken(restricted) = %v, %v", v, err)
+	}
+	v, err = c.ValidateToken(ctx, &identitypb.ValidateTokenRequest{AccessToken: tokens.GetAccessToken(), AcceptScopes: []string{domain.ScopePasswordChange}})
+	if err != nil || !v.GetValid() || v.GetUserId() != login.GetUserId() || len(v.GetScopes()) != 1 || v.GetScopes()[0] != domain.ScopePasswordChange {
+		t.Fatalf("ValidateToken(restricted, accepted) = %v, %v", v, err)
+	}
+	// 普通 token 不受 accept_scopes 影响
+	v, err = c.ValidateToken(ctx, &identitypb.ValidateTokenRequest{AccessToken: login.GetTokens().GetAccessToken(), AcceptScopes: []string{domain.ScopePasswordChange}})
+	if err != nil || !v.GetValid() {
+		t.Fatalf("ValidateToken(unrestricted) = %v, %v", v, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	login := register(t, c, "alice@example.com")
+
+	_, err := c.Register(ctx, &identitypb.RegisterRequest{Account: "ALICE@example.com", Password: "correct horse battery", DisplayName: "A"})
+	assertCode(t, err, codes.AlreadyExists)
+	assertReason(t, err, "account_exists")
+
+	_, err = c.Register(ctx, &identitypb.RegisterRequest{Account: "bob@example.com"})
+	assertCode(t, err, codes.InvalidArgument)
+
+	// 密码不符合策略时 details 中包含违反的规则
+	_, err = c.Register(ctx, &identitypb.RegisterRequest{Account: "bob@example.com", Password: "short", DisplayName: "Bob"})
+	assertCode(t, err, codes.InvalidArgument)
+	assertReason(t, err, "password_policy")
+	var violations int
+	for _, d := range status.Convert(err).Details() {
+		if br, ok := d.(*errdetails.BadRequest); ok {
+			violations += len(br.GetFieldViolations())
+		}
+	}
+	if violations == 0 {
+		t.Fatal("policy violation details missing")
+	}
+
+	// 与 REST 一致, 用户名或密码错误 为参数错误
+	_, err = c.Login(ctx, &identitypb.LoginRequest{Account: "alice@example.com", Password: "wrong password"})
+	assertCode(t, err, codes.InvalidArgument)
+	assertReason(t, err, "invalid_credentials")
+
+	_, err = c.GetUser(ctx, &identitypb.GetUserRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+	_, err = c.GetUser(withToken("garbage"), &identitypb.GetUserRequest{})
+	assertCode(t, err, codes.Unauthenticated)
+
+	// 非 admin 不能查询其他用户
+	other := register(t, c, "bob@example.com")
+	_, err = c.GetUser(withToken(login.GetTokens().GetAccessToken()), &identitypb.GetUserRequest{UserId: other.GetUserId()})
+	assertCode(t, err, codes.PermissionDenied)
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	c := newClient(t)
+	ctx := context.Background()
+	login := register(t, c, "alice@example.com")
+
+	refreshed, err := c.Refresh(ctx, &identitypb.RefreshRequest{RefreshToken: login.GetTokens().GetRefreshToken()})
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	// 旧的 Tokens 随即失效
+	_, err = c.Refresh(ctx, &identitypb.RefreshRequest{RefreshToken: login.GetTokens().GetRefreshToken()})
+	assertCode(t, err, codes.Unauthenticated)
+	if v, _ := c.ValidateToken(ctx, &identitypb.ValidateTokenRequest{AccessToken: login.GetTokens().GetAccessToken()}); v.GetValid() {
+		t.Fatal("old access token still valid after refresh")
+	}
+
+	out, err := c.Logout(ctx, &identitypb.LogoutRequest{
+		AccessToken:  refreshed.GetTokens().GetAccessToken(),
+		RefreshToken: refreshed.GetTokens().GetRefreshToken(),
+	})
+	if err != nil || out.GetUserId() != login.GetUserId() {
+		t.Fatalf("Logout = %v, %v", out, err)
+	}
+	if v, _ := c.ValidateToken(ctx, &identitypb.ValidateTokenRequest{AccessToken: refreshed.GetTokens().GetAccessToken()}); v.GetValid() {
+		t.Fatal("access token still valid after logout")
+	}
+	_, err = c.Refresh(ctx, &identitypb.RefreshRequest{RefreshToken: refreshed.GetTokens().GetRefreshToken()})
+	assertCode(t, err, codes.Unauthenticated)
+}
+
+func TestRefreshIsAudited(t *testing.T) {
+	c, _, auc := newClientWithAudit(t)
+	ctx := context.Background()
+	login := register(t, c, "alice@example.com")
+
+	if _, err := c.Refresh(ctx, &identitypb.RefreshRequest{RefreshToken: login.GetTokens().GetRefreshToken()}); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	_, err := c.Refresh(ctx, &identitypb.RefreshRequest{RefreshToken: login.GetTokens().GetRefreshToken()})
+	assertCode(t, err, codes.Unauthenticated)
+
+	res, err := auc.QueryUC(ctx, domain.AuditFilter{Type: domain.AuditTokenRefresh}, 1, 10)
+	if err != nil || res.Total != 2 {
+		t.Fatalf("QueryUC = %d events, %v; want 2", res.Total, err)
+	}
+	outcomes := map[domain.AuditOutcome]string{}
+	for _, e := range res.Items {
+		outcomes[e.GetOutcome()] = e.GetUserID()
+	}
+	if userID, ok := outcomes[domain.AuditSuccess]; !ok || userID != login.GetUserId() {
+		t.Errorf("success event user = %q, want %q", userID, login.GetUserId())
+	}
+	if _, ok := outcomes[domain.AuditFailure]; !ok {
+		t.Errorf("no failure event for the reused refresh token: %v", outcomes)
+	}
+}
+
+// 未列入 publicMethods 的方法 (例如以后新增的 RPC) 默认需要登录
+func TestAuthInterceptorDefaultsToAuthenticated(t *testing.T) {
+	tuc := _tokenUseCase.NewTokensUsecase(_tokenRepo.NewMemoryTokensRepository(), tokentest.Config{})
+	interceptor := grpcdelivery.UnaryAuthInterceptor(tuc)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) { return "ok", nil }
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/identity.v1.Identity/DeleteUser"}
+	_, err := interceptor(context.Background(), nil, info, handler)
+	assertCode(t, err, codes.Unauthenticated)
+
+	info = &grpc.UnaryServerInfo{FullMethod: "/identity.v1.Identity/Login"}
+	if resp, err := interceptor(context.Background(), nil, info, handler); err != nil || resp != "ok" {
+		t.Fatalf("public method = %v, %v", resp, err)
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	c := newClient(t)
+
+	// 沿用客户端传入的 x-request-id
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-42")
+	if _, err := c.ValidateToken(ctx, &identitypb.ValidateTokenRequest{AccessToken: "garbage"}, grpc.Header(&header)); err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] != "req-42" {
+		t.Fatalf("x-request-id = %v, want req-42", got)
+	}
+
+	// 没有时 生成一个, 出错时同样返回
+	header = nil
+	_, err := c.GetUser(context.Background(), &identitypb.GetUserRequest{}, grpc.Header(&header))
+	assertCode(t, err, codes.Unauthenticated)
+	if got := header.Get("x-request-id"); len(got) != 1 || got[0] == "" {
+		t.Fatalf("x-request-id = %v, want a generated id", got)
+	}
+}
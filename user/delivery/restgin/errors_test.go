@@ -0,0 +1,168 @@
+package restgin_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/token/tokentest"
+	"github.com/dgrijalva/jwt-go"
+)
+
+// errorBody - apierror.Body 与 apierror.Problem 的并集
+type errorBody struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	RequestID string `json:"request_id"`
+	Details   []struct {
+		Field string `json:"field"`
+		Rule  string `json:"rule"`
+	} `json:"details"`
+	Type   string `json:"type"`
+	Status int    `json:"status"`
+	Detail string `json:"detail"`
+	Errors []struct {
+		Field string `json:"field"`
+		Rule  string `json:"rule"`
+	} `json:"errors"`
+}
+
+func (h *harness) raw(req *http.Request) (*httptest.ResponseRecorder, errorBody) {
+	h.t.Helper()
+	w := httptest.NewRecorder()
+	h.engine.ServeHTTP(w, req)
+	var body errorBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		h.t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return w, body
+}
+
+func jsonRequest(path string, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+func TestValidationDetails(t *testing.T) {
+	h := newHarness(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		code   string
+		fields []string
+	}{
+		{"MissingFields", `{"account": "alice@example.com"}`, "validation_failed", []string{"password", "displayname"}},
+		{"WrongType", `{"account": 1, "password": "correct horse battery", "displayname": "A"}`, "validation_failed", []string{"account"}},
+		{"Malformed", `{"account": `, "invalid_request", nil},
+		{"Empty", ``, "invalid_request", nil},
+		{"PasswordPolicy", `{"account": "alice@example.com", "password": "abc", "displayname": "A"}`, "password_policy", []string{"password"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w, body := h.raw(jsonRequest("/register", tt.body))
+			if w.Code != http.StatusBadRequest || body.Code != tt.code {
+				t.Fatalf("status %d code %q, want 400 %q, body %s", w.Code, body.Code, tt.code, w.Body)
+			}
+			var fields []string
+			for _, d := range body.Details {
+				if d.Rule == "" {
+					t.Errorf("detail %s has no rule", d.Field)
+				}
+				fields = append(fields, d.Field)
+			}
+			if strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+				t.Fatalf("fields %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestRequestID(t *testing.T) {
+	h := newHarness(t)
+
+	req := jsonRequest("/login", `{}`)
+	req.Header.Set("X-Request-ID", "client-supplied-id")
+	w, body := h.raw(req)
+	if got := w.Header().Get("X-Request-ID"); got != "client-supplied-id" || body.RequestID != got {
+		t.Fatalf("header %q, body %q", got, body.RequestID)
+	}
+
+	// 不可见字符 视为无效, 重新生成
+	req = jsonRequest("/login", `{}`)
+	req.Header.Set("X-Request-ID", "bad id\t")
+	w, body = h.raw(req)
+	if got := w.Header().Get("X-Request-ID"); got == "" || got == "bad id\t" || body.RequestID != got {
+		t.Fatalf("header %q, body %q", got, body.RequestID)
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	h := newHarness(t)
+
+	req := jsonRequest("/register", `{"account": "alice@example.com"}`)
+	req.Header.Set("Accept", "application/problem+json")
+	w, body := h.raw(req)
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/problem+json") {
+		t.Fatalf("Content-Type %q", ct)
+	}
+	if body.Status != http.StatusBadRequest || body.Code != "validation_failed" || body.Type != "urn:identity:error:validation_failed" ||
+		body.Detail == "" || body.RequestID == "" || len(body.Errors) != 2 {
+		t.Fatalf("problem %+v", body)
+	}
+
+	// 未要求时 仍返回普通 JSON
+	w, _ = h.raw(jsonRequest("/register", `{"account": "alice@example.com"}`))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("Content-Type %q", ct)
+	}
+}
+
+func TestTokenErrors(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/register", alice)
+	h.do(http.MethodPost, "/login", alice)
+	refreshToken := h.cookies["refresh_token"].Value
+	h.cookies = map[string]*http.Cookie{}
+
+	expired := func() string {
+		s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"aud": "u1", "jti": "id++u1", "typ": "access", "exp": time.Now().Add(-time.Minute).Unix(),
+		}).SignedString(tokentest.Config{}.GetAccessTokenSecret())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		code   string
+	}{
+		{"NoToken", http.MethodGet, "/me/export", "", "not_logged_in"},
+		{"Expired", http.MethodGet, "/me/export", expired, "token_expired"},
+		{"Malformed", http.MethodGet, "/me/export", "garbage", "token_malformed"},
+		{"RefreshAsAccess", http.MethodGet, "/me/export", refreshToken, "token_wrong_type"},
+		// 过期的 cookie 退出登录 不再返回 500
+		{"LogoutExpired", http.MethodPost, "/logout", expired, "token_expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.token})
+			}
+			w, body := h.raw(req)
+			if w.Code != http.StatusUnauthorized || body.Code != tt.code {
+				t.Fatalf("status %d code %q, want 401 %q, body %s", w.Code, body.Code, tt.code, w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package restgin_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/apierror"
+	_auditRepo "github.com/alibug/go-identity-entry/audit/repository/memory"
+	_auditUseCase "github.com/alibug/go-identity-entry/audit/usecase"
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/requestid"
+	_tokenRepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	"github.com/alibug/go-identity-entry/token/tokentest"
+	_tokenUseCase "github.com/alibug/go-identity-entry/token/usecase"
+	"github.com/alibug/go-identity-entry/user/delivery/restgin"
+	"github.com/alibug/go-identity-entry/user/password"
+	_userRepo "github.com/alibug/go-identity-entry/user/repository/memory"
+	_userUseCase "github.com/alibug/go-identity-entry/user/usecase"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type cookieConfig struct{}
+
+func (cookieConfig) GetAccessTokenMaxAge() int    { return 900 }
+func (cookieConfig) GetRefreshTokenMaxAge() int   { return 86400 }
+func (cookieConfig) GetDomain() string            { return "example.com" }
+func (cookieConfig) GetAccessTokenField() string  { return "access_token" }
+func (cookieConfig) GetRefreshTokenField() string { return "refresh_token" }
+func (cookieConfig) GetUserIDField() string       { return "user_id" }
+func (cookieConfig) GetDisplayNameField() string  { return "displayname" }
+func (cookieConfig) GetSecure() bool              { return true }
+func (cookieConfig) GetHTTPOnly() bool            { return true }
+
+// introspection 接口的客户端凭据
+const (
+	introspectionClient = "orders"
+	introspectionSecret = "orders-secret"
+)
+
+// harness - 使用内存仓库启动 gin, 并像浏览器一样保存 cookie
+type harness struct {
+	t       *testing.T
+	engine  *gin.Engine
+	cookies map[string]*http.Cookie
+	// audit - 处理器写入的审计记录
+	audit domain.AuditRepository
+}
+
+func newHarness(t *testing.T) *harness {
+	gin.SetMode(gin.TestMode)
+	uuc := _userUseCase.NewUserUsecase(_userRepo.NewMemoryUserRepository(), time.Second,
+		_userUseCase.WithPasswordHasher(password.New(password.NewBcrypt(bcrypt.MinCost))),
+		_userUseCase.WithDeletionGrace(time.Hour),
+	)
+	tuc := _tokenUseCase.NewTokensUsecase(_tokenRepo.NewMemoryTokensRepository(), tokentest.Config{})
+	auditRepo := _auditRepo.NewMemoryAuditRepository()
+	auc := _auditUseCase.NewAuditUsecase(auditRepo, time.Second)
+
+	engine := gin.New()
+	engine.Use(requestid.Middleware(), apierror.Middleware())
+	restgin.NewUsersHandler(engine, uuc, tuc, auc, cookieConfig{})
+	restgin.NewIntrospectionHandler(engine.Group("/oauth", gin.BasicAuth(gin.Accounts{introspectionClient: introspectionSecret})), uuc, tuc)
+	return &harness{t: t, engine: engine, cookies: map[string]*http.Cookie{}, audit: auditRepo}
+}
+
+// do - 发送请求 并按 Set-Cookie 更新保存的 cookie
+func (h *harness) do(method string, path string, body interface{}) *httptest.ResponseRecorder {
+	h.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			h.t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	for _, c := range h.cookies {
+		req.AddCookie(&http.Cookie{Name: c.Name, Value: c.Value})
+	}
+
+	w := httptest.NewRecorder()
+	h.engine.ServeHTTP(w, req)
+	for _, c := range w.Result().Cookies() {
+		if c.MaxAge < 0 {
+			delete(h.cookies, c.Name)
+			continue
+		}
+		h.cookies[c.Name] = c
+	}
+	return w
+}
+
+// setCookies - 响应中的 Set-Cookie, 按名称索引
+func setCookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	cookies := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		cookies[c.Name] = c
+	}
+	return cookies
+}
+
+// decode - 将 JSON 响应解析为 map
+func decode(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode %q: %v", w.Body.String(), err)
+	}
+	return body
+}
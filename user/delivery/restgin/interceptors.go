@@ -0,0 +1,79 @@
+package restgin
+
+import (
+	"github.com/alibug/go-identity-entry/apierror"
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/gin-gonic/gin"
+)
+
+// UserIDKey - MustLoginInterceptor 将当前用户ID 写入 gin.Context 的键
+const UserIDKey = "userID"
+
+var (
+	errNotLoggedIn     = domain.NewCodedError(status.ErrUnauthorized, domain.CodeNotLoggedIn, "You are not logged in")
+	errPasswordExpired = domain.NewCodedError(status.ErrForbidden, domain.CodePasswordExpired, "Your password has expired, please change it first")
+	errNoPermission    = domain.NewCodedError(status.ErrForbidden, domain.CodeForbidden, "You have no permission")
+	errAlreadyLoggedIn = domain.NewCodedError(status.ErrForbidden, domain.CodeAlreadyLoggedIn, "You have logged in")
+)
+
+// MustLoginInterceptor - 校验 cookie 中的 AccessToken, 并将 userID 写入 gin.Context
+// 受限 Token (带 scope) 只在 acceptScopes 包含其 scope 时放行
+func MustLoginInterceptor(tuc domain.TokensUseCase, cc domain.CookieConfig, acceptScopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken, err := c.Cookie(cc.GetAccessTokenField())
+		if err != nil || accessToken == "" {
+			apierror.Abort(c, errNotLoggedIn)
+			return
+		}
+
+		// token 无效时 返回具体原因, 如 token_expired
+		td, exist, err := tuc.CheckAccessToken(c.Request.Context(), accessToken)
+		if err != nil {
+			apierror.Abort(c, err)
+			return
+		}
+		if !exist {
+			apierror.Abort(c, domain.ErrTokenRevoked)
+			return
+		}
+		if !scopeAccepted(td.GetScopes(), acceptScopes) {
+			apierror.Abort(c, errPasswordExpired)
+			return
+		}
+		c.Set(UserIDKey, td.GetUserID())
+		c.Next()
+	}
+}
+
+func scopeAccepted(scopes []string, accept []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, s := range scopes {
+		for _, a := range accept {
+			if s == a {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MustHaveRoleInterceptor - 要求当前用户拥有指定角色, 须在 MustLoginInterceptor 之后使用
+func MustHaveRoleInterceptor(uuc domain.UserUsecase, role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, err := uuc.GetByIDUC(c.Request.Context(), c.GetString(UserIDKey))
+		if err != nil {
+			apierror.Abort(c, errNoPermission)
+			return
+		}
+		for _, r := range user.GetRoles() {
+			if r == role {
+				c.Next()
+				return
+			}
+		}
+		apierror.Abort(c, errNoPermission)
+	}
+}
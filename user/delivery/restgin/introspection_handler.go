@@ -0,0 +1,73 @@
+package restgin
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/alibug/go-identity-entry/apierror"
+	"github.com/alibug/go-identity-entry/domain"
+	tokenBody "github.com/alibug/go-identity-entry/token/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/gin-gonic/gin"
+)
+
+// IntrospectionHandler - token introspection (RFC 7662), 供其他服务的 client.IntrospectionVerifier 使用
+type IntrospectionHandler struct {
+	userUsecase   domain.UserUsecase
+	tokensUsecase domain.TokensUseCase
+}
+
+// NewIntrospectionHandler - 在给定的 (已鉴权的) 路由组上 注册 introspection 接口
+func NewIntrospectionHandler(route gin.IRoutes, uuc domain.UserUsecase, tuc domain.TokensUseCase) {
+	handler := &IntrospectionHandler{
+		userUsecase:   uuc,
+		tokensUsecase: tuc,
+	}
+
+	route.POST("/introspect", handler.Introspect)
+}
+
+// Introspect - 无效 已过期 或已撤销的 token 返回 active: false
+func (h *IntrospectionHandler) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	token := c.PostForm("token")
+	if token == "" {
+		apierror.Respond(c, &domain.CodedError{
+			Code:    domain.CodeValidationFailed,
+			Message: "request validation failed",
+			Details: []domain.FieldError{{Field: "token", Rule: "required", Message: "token is required"}},
+			Err:     status.ErrBadParamInput,
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	inactive := &tokenBody.IntrospectionBody{Active: false}
+	td, exist, err := h.tokensUsecase.CheckAccessToken(ctx, token)
+	if err != nil || !exist {
+		c.JSON(http.StatusOK, inactive)
+		return
+	}
+	user, err := h.userUsecase.GetByIDUC(ctx, td.GetUserID())
+	if errors.Is(err, status.ErrNotFound) {
+		c.JSON(http.StatusOK, inactive)
+		return
+	}
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	resp := &tokenBody.IntrospectionBody{
+		Active: true,
+		Sub:    td.GetUserID(),
+		Scope:  strings.Join(td.GetScopes(), " "),
+		Jti:    td.GetTokenID(),
+		Roles:  user.GetRoles(),
+	}
+	if at := td.GetExpiresAt(); at != nil {
+		resp.Exp = at.Unix()
+	}
+	c.JSON(http.StatusOK, resp)
+}
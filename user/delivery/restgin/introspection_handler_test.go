@@ -0,0 +1,58 @@
+package restgin_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/client"
+)
+
+func TestIntrospection(t *testing.T) {
+	h := newHarness(t)
+	srv := httptest.NewServer(h.engine)
+	defer srv.Close()
+	ctx := context.Background()
+
+	if w := h.do(http.MethodPost, "/register", alice); w.Code != http.StatusCreated {
+		t.Fatalf("register: status %d, body %s", w.Code, w.Body)
+	}
+	w := h.do(http.MethodPost, "/login", map[string]string{"account": alice["account"], "password": alice["password"]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status %d, body %s", w.Code, w.Body)
+	}
+	accessToken := setCookies(w)["access_token"].Value
+
+	verifier := client.NewIntrospectionVerifier(srv.URL+"/oauth/introspect",
+		client.WithClientCredentials(introspectionClient, introspectionSecret),
+		client.WithResultCacheTTL(0, 0),
+	)
+	p, err := verifier.Verify(ctx, accessToken)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if p.Subject != h.cookies["user_id"].Value || p.TokenID == "" || p.ExpiresAt.IsZero() {
+		t.Fatalf("principal = %+v", p)
+	}
+
+	if _, err := verifier.Verify(ctx, "not-a-token"); !errors.Is(err, client.ErrInvalidToken) {
+		t.Fatalf("Verify(garbage) err = %v, want ErrInvalidToken", err)
+	}
+
+	// 退出登录后 token 失效
+	if w := h.do(http.MethodPost, "/logout", nil); w.Code != http.StatusOK {
+		t.Fatalf("logout: status %d, body %s", w.Code, w.Body)
+	}
+	if _, err := verifier.Verify(ctx, accessToken); !errors.Is(err, client.ErrInvalidToken) {
+		t.Fatalf("Verify(after logout) err = %v, want ErrInvalidToken", err)
+	}
+
+	// 没有凭据 不能调用
+	anonymous := client.NewIntrospectionVerifier(srv.URL + "/oauth/introspect")
+	_, err = anonymous.Verify(ctx, accessToken)
+	if err == nil || errors.Is(err, client.ErrInvalidToken) || client.StatusCode(err) != http.StatusServiceUnavailable {
+		t.Fatalf("Verify(no credentials) err = %v, want unavailable", err)
+	}
+}
@@ -0,0 +1,28 @@
+package restgin
+
+import (
+	"net/http"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// JWKSHandler - 公开 AccessToken 的公钥, 供其他服务的 client.JWKSVerifier 在本地校验 token
+type JWKSHandler struct {
+	tokensUsecase domain.TokensUseCase
+}
+
+// NewJWKSHandler - 注册 /.well-known/jwks.json, 不需要鉴权
+func NewJWKSHandler(route gin.IRoutes, tuc domain.TokensUseCase) {
+	handler := &JWKSHandler{
+		tokensUsecase: tuc,
+	}
+
+	route.GET("/.well-known/jwks.json", handler.JWKS)
+}
+
+// JWKS - 客户端遇到未知的 kid 时 会重新获取, 缓存时间不必与密钥轮换周期一致
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.tokensUsecase.JWKS())
+}
@@ -0,0 +1,180 @@
+package restgin_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/client"
+	"github.com/alibug/go-identity-entry/domain"
+	_tokenRepo "github.com/alibug/go-identity-entry/token/repository/memory"
+	"github.com/alibug/go-identity-entry/token/tokentest"
+	_tokenUseCase "github.com/alibug/go-identity-entry/token/usecase"
+	"github.com/alibug/go-identity-entry/user/delivery/restgin"
+	"github.com/alibug/go-identity-entry/user/repository/body"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+)
+
+func signingKey(t *testing.T, rsaKey bool) *_tokenUseCase.SigningKey {
+	t.Helper()
+	var (
+		key *_tokenUseCase.SigningKey
+		err error
+	)
+	if rsaKey {
+		var k *rsa.PrivateKey
+		if k, err = rsa.GenerateKey(rand.Reader, 2048); err == nil {
+			key, err = _tokenUseCase.NewSigningKey(k)
+		}
+	} else {
+		var k *ecdsa.PrivateKey
+		if k, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err == nil {
+			key, err = _tokenUseCase.NewSigningKey(k)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// 本服务签发的 AccessToken 能被 client.JWKSVerifier 通过 /.well-known/jwks.json 校验
+func TestJWKSVerifiesIssuedTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	current, previous := signingKey(t, false), signingKey(t, true)
+	repo := _tokenRepo.NewMemoryTokensRepository()
+
+	// 轮换前 用 previous 签发的 token
+	old, err := _tokenUseCase.NewTokensUsecase(repo, tokentest.Config{}, _tokenUseCase.WithAccessSigningKeys(previous)).
+		CreateTokens(ctx, "u1", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tuc := _tokenUseCase.NewTokensUsecase(repo, tokentest.Config{}, _tokenUseCase.WithAccessSigningKeys(current, previous))
+	tokens, err := tuc.CreateTokens(ctx, "u1", domain.ClientInfo{}, domain.ScopePasswordChange)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	engine := gin.New()
+	restgin.NewJWKSHandler(engine, tuc)
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	verifier := client.NewJWKSVerifier(srv.URL+"/.well-known/jwks.json", client.WithIssuer(tokentest.Config{}.GetIssuer()))
+
+	p, err := verifier.Verify(ctx, tokens.GetAccessToken())
+	if err != nil || p.Subject != "u1" || !p.HasScope(domain.ScopePasswordChange) {
+		t.Fatalf("Verify(current) = %+v, %v", p, err)
+	}
+	if p, err := verifier.Verify(ctx, old.GetAccessToken()); err != nil || p.Subject != "u1" {
+		t.Fatalf("Verify(previous) = %+v, %v", p, err)
+	}
+	// 本服务同样接受两个密钥签发的 token
+	for _, at := range []string{tokens.GetAccessToken(), old.GetAccessToken()} {
+		if _, exist, err := tuc.CheckAccessToken(ctx, at); err != nil || !exist {
+			t.Fatalf("CheckAccessToken = %v, %v", exist, err)
+		}
+	}
+	// RefreshToken 仍使用 HMAC, 不能通过 JWKS 校验
+	if _, err := verifier.Verify(ctx, tokens.GetRefreshToken()); !errors.Is(err, client.ErrInvalidToken) {
+		t.Fatalf("Verify(refresh) err = %v, want ErrInvalidToken", err)
+	}
+}
+
+// rolesRepository - 只实现 GetByID, 返回带有角色的用户
+type rolesRepository struct {
+	domain.UserRepository
+	roles map[string][]string
+}
+
+func (r rolesRepository) GetByID(ctx context.Context, id string) (domain.User, error) {
+	return &body.UserBody{Roles: r.roles[id]}, nil
+}
+
+// 本服务签发的 AccessToken 带有用户的角色, 刷新后仍然保留, 能通过 client.RequireRoles 的检查
+func TestJWKSIssuedTokensCarryRoles(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	users := rolesRepository{roles: map[string][]string{"admin": {"admin"}, "u1": nil}}
+	tuc := _tokenUseCase.NewTokensUsecase(_tokenRepo.NewMemoryTokensRepository(), tokentest.Config{},
+		_tokenUseCase.WithAccessSigningKeys(signingKey(t, true)), _tokenUseCase.WithUserRepository(users))
+
+	engine := gin.New()
+	restgin.NewJWKSHandler(engine, tuc)
+	srv := httptest.NewServer(engine)
+	defer srv.Close()
+	auth := client.NewAuthenticator(client.NewJWKSVerifier(srv.URL+"/.well-known/jwks.json"), client.RequireRoles("admin"))
+	authenticate := func(token string) error {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		_, err := auth.Authenticate(r)
+		return err
+	}
+
+	admin, err := tuc.CreateTokens(ctx, "admin", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authenticate(admin.GetAccessToken()); err != nil {
+		t.Fatalf("Authenticate(admin) err = %v", err)
+	}
+	refreshed, err := tuc.RefreshTokens(ctx, admin.GetRefreshToken(), domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authenticate(refreshed.GetAccessToken()); err != nil {
+		t.Fatalf("Authenticate(refreshed admin) err = %v", err)
+	}
+
+	user, err := tuc.CreateTokens(ctx, "u1", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authenticate(user.GetAccessToken()); !errors.Is(err, client.ErrMissingRole) {
+		t.Fatalf("Authenticate(u1) err = %v, want ErrMissingRole", err)
+	}
+	// RefreshToken 不能当作 AccessToken 使用
+	if err := authenticate(admin.GetRefreshToken()); !errors.Is(err, client.ErrInvalidToken) {
+		t.Fatalf("Authenticate(refresh token) err = %v, want ErrInvalidToken", err)
+	}
+}
+
+// 配置非对称密钥后 不再接受 HMAC 签名的 AccessToken, 也不接受用公钥作为 HMAC 密钥伪造的 token
+func TestAsymmetricAccessTokenRejectsHMAC(t *testing.T) {
+	ctx := context.Background()
+	key := signingKey(t, true)
+	tuc := _tokenUseCase.NewTokensUsecase(_tokenRepo.NewMemoryTokensRepository(), tokentest.Config{}, _tokenUseCase.WithAccessSigningKeys(key))
+
+	hmacTokens, err := _tokenUseCase.NewTokensUsecase(_tokenRepo.NewMemoryTokensRepository(), tokentest.Config{}).CreateTokens(ctx, "u1", domain.ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tuc.CheckAccessToken(ctx, hmacTokens.GetAccessToken()); !errors.Is(err, domain.ErrTokenBadSignature) {
+		t.Fatalf("CheckAccessToken(HS256) err = %v, want ErrTokenBadSignature", err)
+	}
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"aud": "u1", "jti": "x++u1", "typ": "access"})
+	forged.Header["kid"] = key.KeyID()
+	forgedStr, err := forged.SignedString([]byte(key.JWK().N))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tuc.CheckAccessToken(ctx, forgedStr); !errors.Is(err, domain.ErrTokenBadSignature) {
+		t.Fatalf("CheckAccessToken(forged) err = %v, want ErrTokenBadSignature", err)
+	}
+
+	if keys := tuc.JWKS().Keys; len(keys) != 1 || keys[0].Kid != key.KeyID() || keys[0].Alg != "RS256" || keys[0].Use != "sig" {
+		t.Fatalf("JWKS = %+v", keys)
+	}
+	if keys := _tokenUseCase.NewTokensUsecase(_tokenRepo.NewMemoryTokensRepository(), tokentest.Config{}).JWKS().Keys; keys == nil || len(keys) != 0 {
+		t.Fatalf("JWKS without signing keys = %#v, want empty", keys)
+	}
+}
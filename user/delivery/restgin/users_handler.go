@@ -1,13 +1,15 @@
 package restgin
 
 import (
+	"context"
 	"net/http"
+	"time"
 
+	"github.com/alibug/go-identity-entry/apierror"
+	auditBody "github.com/alibug/go-identity-entry/audit/repository/body"
 	"github.com/alibug/go-identity-entry/domain"
 	tokenBody "github.com/alibug/go-identity-entry/token/repository/body"
 	userBody "github.com/alibug/go-identity-entry/user/repository/body"
-	"github.com/alibug/go-identity-utils/config"
-	"github.com/alibug/go-identity-utils/status"
 	"github.com/gin-gonic/gin"
 )
 
@@ -15,14 +17,16 @@ import (
 type UsersHandler struct {
 	userUsecase   domain.UserUsecase
 	tokensUsecase domain.TokensUseCase
-	cookieConfig  config.CookieConfig
+	auditUsecase  domain.AuditUsecase
+	cookieConfig  domain.CookieConfig
 }
 
 // NewUsersHandler represent the httphandler for user
-func NewUsersHandler(route *gin.Engine, uuc domain.UserUsecase, tuc domain.TokensUseCase, cc config.CookieConfig) {
+func NewUsersHandler(route *gin.Engine, uuc domain.UserUsecase, tuc domain.TokensUseCase, auc domain.AuditUsecase, cc domain.CookieConfig) {
 	handler := &UsersHandler{
 		userUsecase:   uuc,
 		tokensUsecase: tuc,
+		auditUsecase:  auc,
 		cookieConfig:  cc,
 	}
 
@@ -30,6 +34,125 @@ func NewUsersHandler(route *gin.Engine, uuc domain.UserUsecase, tuc domain.Token
 	route.POST("/login", handler.mustNotLoginInterceptor(), handler.Login)
 	route.POST("/register", handler.mustNotLoginInterceptor(), handler.RegisterUser)
 	route.POST("/logout", handler.Logout)
+
+	// 密码过期后 只允许修改密码
+	mustLogin := MustLoginInterceptor(tuc, cc)
+	me := route.Group("/me")
+	me.GET("/export", mustLogin, handler.ExportMe)
+	me.DELETE("", mustLogin, handler.DeleteMe)
+	me.POST("/restore", mustLogin, handler.RestoreMe)
+	me.POST("/password", MustLoginInterceptor(tuc, cc, domain.ScopePasswordChange), handler.ChangePassword)
+}
+
+// ChangePassword - 修改密码后 使其他设备上的 Token 失效, 并为当前设备签发新 Token
+func (u *UsersHandler) ChangePassword(c *gin.Context) {
+	var body userBody.ChangePasswordBody
+	// 1、 校验 body 格式
+	if err := c.ShouldBind(&body); err != nil {
+		apierror.Respond(c, apierror.Binding(err))
+		return
+	}
+
+	// 2、校验旧密码 与 密码策略
+	userID := c.GetString(UserIDKey)
+	ctx := c.Request.Context()
+	err := u.userUsecase.ChangePasswordUC(ctx, userID, body.OldPassword, body.NewPassword)
+	u.recordAudit(c, domain.AuditPasswordChange, userID, "", err)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	// 3、删除所有 Token 后 重新签发
+	err = u.tokensUsecase.RevokeUserTokens(ctx, userID)
+	u.recordAudit(c, domain.AuditTokenRevoke, userID, "", err)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	tokens, err := u.tokensUsecase.CreateTokens(ctx, userID, clientInfo(c))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	u.setTokenToCookie(c, tokens)
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ExportMe - 导出当前用户的所有数据 (GDPR)
+func (u *UsersHandler) ExportMe(c *gin.Context) {
+	userID := c.GetString(UserIDKey)
+
+	ctx := c.Request.Context()
+	user, err := u.userUsecase.GetByIDUC(ctx, userID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	sessions, err := u.tokensUsecase.ListSessions(ctx, userID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	events, err := u.listAuditEvents(ctx, userID)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\"export.json\"")
+	c.JSON(http.StatusOK, userBody.ExportBody{
+		ExportedAt:  time.Now(),
+		Profile:     user,
+		Sessions:    sessions,
+		AuditEvents: events,
+	})
+}
+
+// DeleteMe - 重新校验密码后 注销当前用户, 并使所有 Token 失效
+func (u *UsersHandler) DeleteMe(c *gin.Context) {
+	var body userBody.DeleteAccountBody
+	// 1、 校验 body 格式
+	if err := c.ShouldBind(&body); err != nil {
+		apierror.Respond(c, apierror.Binding(err))
+		return
+	}
+
+	// 2、校验密码 并标记删除
+	userID := c.GetString(UserIDKey)
+	ctx := c.Request.Context()
+	deleteAt, err := u.userUsecase.ScheduleDeletionUC(ctx, userID, body.Password)
+	u.recordAudit(c, domain.AuditAccountDelete, userID, "", err)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	// 3、删除所有 Token
+	err = u.tokensUsecase.RevokeUserTokens(ctx, userID)
+	u.recordAudit(c, domain.AuditTokenRevoke, userID, "", err)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	u.clearAccessTokenInCookie(c)
+	u.clearUserInfoInCookie(c)
+	c.JSON(http.StatusAccepted, gin.H{"deletion_scheduled_at": deleteAt})
+}
+
+// RestoreMe - 宽限期内 撤销当前用户的注销申请
+func (u *UsersHandler) RestoreMe(c *gin.Context) {
+	userID := c.GetString(UserIDKey)
+	err := u.userUsecase.CancelDeletionUC(c.Request.Context(), userID)
+	u.recordAudit(c, domain.AuditAccountRestore, userID, "", err)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
 }
 
 // Logout -
@@ -37,15 +160,16 @@ func (u *UsersHandler) Logout(c *gin.Context) {
 	// 1、从 cookie 中 获取 token
 	tokens := u.getTokenFromCookie(c)
 	if tokens == nil {
-		c.JSON(status.GetStatusCode(status.ErrUnauthorized), status.ResponseError{Message: "You are not logged in"})
+		apierror.Respond(c, errNotLoggedIn)
 		return
 	}
 
 	// 3、Delete access token
 	ctx := c.Request.Context()
-	err := u.tokensUsecase.CheckTokensAndLogout(ctx, tokens)
+	userID, err := u.tokensUsecase.CheckTokensAndLogout(ctx, tokens)
+	u.recordAudit(c, domain.AuditLogout, userID, "", err)
 	if err != nil {
-		c.JSON(status.GetStatusCode(err), status.ResponseError{Message: err.Error()})
+		apierror.Respond(c, err)
 		return
 	}
 
@@ -60,7 +184,7 @@ func (u *UsersHandler) Login(c *gin.Context) {
 	var body userBody.LoginBody
 	// 1、 校验 body 格式
 	if err := c.ShouldBind(&body); err != nil {
-		c.JSON(status.GetStatusCode(err), status.ResponseError{Message: err.Error()})
+		apierror.Respond(c, apierror.Binding(err))
 		return
 	}
 
@@ -68,14 +192,21 @@ func (u *UsersHandler) Login(c *gin.Context) {
 	ctx := c.Request.Context()
 	user, err := u.userUsecase.CheckAccountAndPassUC(ctx, body.Account, body.Password)
 	if err != nil {
-		c.JSON(status.GetStatusCode(err), status.ResponseError{Message: err.Error()})
+		u.recordAudit(c, domain.AuditLogin, "", body.Account, err)
+		apierror.Respond(c, err)
 		return
 	}
 
-	// 4.1 、创建 Tokens
-	tokens, err := u.tokensUsecase.CreateTokens(ctx, user.GetUserID())
+	// 4.1 、创建 Tokens, 密码过期时 只签发修改密码用的受限 Token
+	var scopes []string
+	expired := u.userUsecase.PasswordExpiredUC(user)
+	if expired {
+		scopes = append(scopes, domain.ScopePasswordChange)
+	}
+	tokens, err := u.tokensUsecase.LoginTokens(ctx, user.GetUserID(), clientInfo(c), scopes...)
+	u.recordAudit(c, domain.AuditLogin, user.GetUserID(), body.Account, err)
 	if err != nil {
-		c.JSON(status.GetStatusCode(err), status.ResponseError{Message: err.Error()})
+		apierror.Respond(c, err)
 		return
 	}
 
@@ -83,8 +214,12 @@ func (u *UsersHandler) Login(c *gin.Context) {
 	u.setTokenToCookie(c, tokens)
 	u.setUserInfoToCookie(c, user)
 
-	// 6、⚠️ 此处是临时性的 设置 返回结果
-	c.JSON(http.StatusOK, gin.H{"displayname": user.GetDisplayName()})
+	// 6、⚠️ 此处是临时性的 设置 返回结果; 已申请注销时 返回删除时间, 客户端可提示撤销
+	res := gin.H{"displayname": user.GetDisplayName(), "password_expired": expired}
+	if at := user.GetDeletionScheduledAt(); at != nil {
+		res["deletion_scheduled_at"] = at
+	}
+	c.JSON(http.StatusOK, res)
 }
 
 // GetByID will get user by given id
@@ -95,7 +230,7 @@ func (u *UsersHandler) GetByID(c *gin.Context) {
 
 	user, err := u.userUsecase.GetByIDUC(ctx, id)
 	if err != nil {
-		c.JSON(status.GetStatusCode(err), status.ResponseError{Message: err.Error()})
+		apierror.Respond(c, err)
 		return
 	}
 
@@ -107,19 +242,59 @@ func (u *UsersHandler) RegisterUser(c *gin.Context) {
 	var body userBody.RegisterBody
 	// 1、 校验 body 格式
 	if err := c.ShouldBind(&body); err != nil {
-		c.JSON(status.GetStatusCode(err), status.ResponseError{Message: err.Error()})
+		apierror.Respond(c, apierror.Binding(err))
 		return
 	}
 
 	ctx := c.Request.Context()
 	err := u.userUsecase.RegisterUserUC(ctx, &body)
+	u.recordAudit(c, domain.AuditRegister, "", body.Account, err)
 	if err != nil {
-		c.JSON(status.GetStatusCode(err), status.ResponseError{Message: err.Error()})
+		apierror.Respond(c, err)
 		return
 	}
 	c.JSON(http.StatusCreated, gin.H{"ok": true})
 }
 
+// recordAudit - 记录审计事件, err 不为 nil 时记为失败
+func (u *UsersHandler) recordAudit(c *gin.Context, eventType domain.AuditEventType, userID string, account string, err error) {
+	event := &auditBody.EventBody{
+		Type:      eventType,
+		Outcome:   domain.AuditSuccess,
+		UserID:    userID,
+		Account:   account,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if err != nil {
+		event.Outcome = domain.AuditFailure
+		event.Reason = err.Error()
+	}
+	u.auditUsecase.RecordUC(c.Request.Context(), event)
+}
+
+// clientInfo - 签发 token 时记录的客户端信息
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+// listAuditEvents - 逐页读取用户的全部审计记录
+func (u *UsersHandler) listAuditEvents(ctx context.Context, userID string) ([]domain.AuditEvent, error) {
+	const limit = 100
+	filter := domain.AuditFilter{UserID: userID}
+	events := make([]domain.AuditEvent, 0)
+	for page := int64(1); ; page++ {
+		res, err := u.auditUsecase.QueryUC(ctx, filter, page, limit)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, res.Items...)
+		if int64(len(res.Items)) < res.Limit || int64(len(events)) >= res.Total {
+			return events, nil
+		}
+	}
+}
+
 func (u *UsersHandler) setTokenToCookie(c *gin.Context, tokens domain.Tokens) {
 	c.SetCookie(u.cookieConfig.GetAccessTokenField(), tokens.GetAccessToken(), u.cookieConfig.GetAccessTokenMaxAge(), "/", u.cookieConfig.GetDomain(), u.cookieConfig.GetSecure(), u.cookieConfig.GetHTTPOnly())
 	c.SetCookie(u.cookieConfig.GetRefreshTokenField(), tokens.GetRefreshToken(), u.cookieConfig.GetRefreshTokenMaxAge(), "/", u.cookieConfig.GetDomain(), u.cookieConfig.GetSecure(), u.cookieConfig.GetHTTPOnly())
@@ -160,8 +335,8 @@ func (u *UsersHandler) mustNotLoginInterceptor() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := u.getTokenFromCookie(c)
 		if token != nil {
-			c.JSON(status.GetStatusCode(status.ErrForbidden), status.ResponseError{Message: "You have logged in"})
-			c.Abort()
+			apierror.Abort(c, errAlreadyLoggedIn)
+			return
 		}
 		c.Next()
 	}
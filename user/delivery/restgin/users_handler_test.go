@@ -0,0 +1,261 @@
+package restgin_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	auditBody "github.com/alibug/go-identity-entry/audit/repository/body"
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+var alice = map[string]string{
+	"account":     "alice@example.com",
+	"password":    "correct horse battery",
+	"displayname": "Alice",
+}
+
+func TestRegisterLoginLogout(t *testing.T) {
+	h := newHarness(t)
+
+	// 注册
+	w := h.do(http.MethodPost, "/register", alice)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("register: status %d, body %s", w.Code, w.Body)
+	}
+	if body := decode(t, w); body["ok"] != true {
+		t.Fatalf("register: body %v", body)
+	}
+
+	// 登录
+	w = h.do(http.MethodPost, "/login", map[string]string{"account": "Alice@Example.com", "password": alice["password"]})
+	if w.Code != http.StatusOK {
+		t.Fatalf("login: status %d, body %s", w.Code, w.Body)
+	}
+	body := decode(t, w)
+	if body["displayname"] != "Alice" || body["password_expired"] != false {
+		t.Fatalf("login: body %v", body)
+	}
+
+	cookies := setCookies(w)
+	for name, maxAge := range map[string]int{"access_token": 900, "refresh_token": 86400, "user_id": 86400, "displayname": 86400} {
+		c, ok := cookies[name]
+		if !ok {
+			t.Fatalf("login: cookie %s not set", name)
+		}
+		if c.Value == "" || c.MaxAge != maxAge || c.Path != "/" || c.Domain != "example.com" || !c.Secure {
+			t.Errorf("login: cookie %s = %+v", name, c)
+		}
+		// displayname 供前端展示, 其余不允许脚本读取
+		if c.HttpOnly != (name != "displayname") {
+			t.Errorf("login: cookie %s HttpOnly = %v", name, c.HttpOnly)
+		}
+	}
+
+	// 已登录时 不能再次登录或注册
+	for _, path := range []string{"/login", "/register"} {
+		w = h.do(http.MethodPost, path, alice)
+		if w.Code != http.StatusForbidden {
+			t.Fatalf("%s while logged in: status %d, body %s", path, w.Code, w.Body)
+		}
+		if body := decode(t, w); body["message"] != "You have logged in" || body["code"] != "already_logged_in" {
+			t.Fatalf("%s while logged in: body %v", path, body)
+		}
+	}
+
+	// cookie 可访问需要登录的接口
+	w = h.do(http.MethodGet, "/me/export", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("export: status %d, body %s", w.Code, w.Body)
+	}
+	profile, _ := decode(t, w)["profile"].(map[string]interface{})
+	if profile["account"] != "alice@example.com" {
+		t.Fatalf("export: profile %v", profile)
+	}
+
+	// 退出登录 清理 cookie
+	w = h.do(http.MethodPost, "/logout", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("logout: status %d, body %s", w.Code, w.Body)
+	}
+	if body := decode(t, w); body["logout"] != true {
+		t.Fatalf("logout: body %v", body)
+	}
+	for _, name := range []string{"access_token", "refresh_token", "user_id", "displayname"} {
+		if c, ok := setCookies(w)[name]; !ok || c.MaxAge >= 0 || c.Value != "" {
+			t.Errorf("logout: cookie %s not cleared: %+v", name, c)
+		}
+	}
+	if len(h.cookies) != 0 {
+		t.Fatalf("cookies left after logout: %v", h.cookies)
+	}
+
+	// 退出后 需要登录的接口 与 再次退出 都返回 401
+	if w = h.do(http.MethodGet, "/me/export", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("export after logout: status %d", w.Code)
+	}
+	if w = h.do(http.MethodPost, "/logout", nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("logout twice: status %d", w.Code)
+	}
+
+	// 可以重新登录
+	if w = h.do(http.MethodPost, "/login", alice); w.Code != http.StatusOK {
+		t.Fatalf("login again: status %d, body %s", w.Code, w.Body)
+	}
+}
+
+func TestRegisterConflict(t *testing.T) {
+	h := newHarness(t)
+	if w := h.do(http.MethodPost, "/register", alice); w.Code != http.StatusCreated {
+		t.Fatalf("register: status %d, body %s", w.Code, w.Body)
+	}
+
+	// 账号规范化后 大小写不同视为同一账号
+	dup := map[string]string{"account": "ALICE@example.com", "password": "another password", "displayname": "A"}
+	w := h.do(http.MethodPost, "/register", dup)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("duplicate register: status %d, body %s", w.Code, w.Body)
+	}
+	if body := decode(t, w); body["code"] != "account_exists" {
+		t.Fatalf("duplicate register: body %v", body)
+	}
+}
+
+func TestLoginRejected(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/register", alice)
+
+	tests := []struct {
+		name    string
+		body    map[string]string
+		code    int
+		errCode string
+	}{
+		{"WrongPassword", map[string]string{"account": alice["account"], "password": "wrong password"}, http.StatusBadRequest, "invalid_credentials"},
+		{"UnknownAccount", map[string]string{"account": "bob@example.com", "password": alice["password"]}, http.StatusBadRequest, "invalid_credentials"},
+		{"MissingPassword", map[string]string{"account": alice["account"]}, http.StatusBadRequest, "validation_failed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := h.do(http.MethodPost, "/login", tt.body)
+			if w.Code != tt.code {
+				t.Fatalf("status %d, want %d, body %s", w.Code, tt.code, w.Body)
+			}
+			if _, ok := setCookies(w)["access_token"]; ok {
+				t.Fatal("access_token set on failed login")
+			}
+			body := decode(t, w)
+			if msg, _ := body["message"].(string); msg == "" {
+				t.Fatal("no error message")
+			}
+			if body["code"] != tt.errCode {
+				t.Fatalf("code %v, want %s", body["code"], tt.errCode)
+			}
+		})
+	}
+}
+
+func TestLoginAudited(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/register", alice)
+
+	req := jsonRequest("/login", `{"account": "alice@example.com", "password": "wrong password"}`)
+	req.RemoteAddr = "203.0.113.7:50000"
+	req.Header.Set("User-Agent", "audit-test/1.0")
+	if w, _ := h.raw(req); w.Code != http.StatusBadRequest {
+		t.Fatalf("login: status %d, body %s", w.Code, w.Body)
+	}
+
+	events, err := h.audit.Find(context.Background(), domain.AuditFilter{Type: domain.AuditLogin}, 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("login audit events = %d, want 1", len(events))
+	}
+	e := events[0].(*auditBody.EventBody)
+	if e.Outcome != domain.AuditFailure || e.Account != "alice@example.com" || e.UserID != "" || e.Reason == "" {
+		t.Fatalf("audit event = %+v", e)
+	}
+	if e.IP != "203.0.113.7" || e.UserAgent != "audit-test/1.0" || e.Timestamp.IsZero() {
+		t.Fatalf("audit event client = %q, %q at %v", e.IP, e.UserAgent, e.Timestamp)
+	}
+}
+
+func TestDeleteMeRevokesTokens(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/register", alice)
+	login := map[string]string{"account": alice["account"], "password": alice["password"]}
+
+	// 两台设备各登录一次
+	sessions := make([]map[string]*http.Cookie, 2)
+	for i := range sessions {
+		h.cookies = map[string]*http.Cookie{}
+		if w := h.do(http.MethodPost, "/login", login); w.Code != http.StatusOK {
+			t.Fatalf("login %d: status %d, body %s", i, w.Code, w.Body)
+		}
+		// 复制一份: 之后的请求会修改 h.cookies
+		sessions[i] = map[string]*http.Cookie{}
+		for name, c := range h.cookies {
+			sessions[i][name] = c
+		}
+	}
+
+	// 密码错误时 不注销, Token 仍然有效
+	w := h.do(http.MethodDelete, "/me", map[string]string{"password": "wrong password"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("delete with wrong password: status %d, body %s", w.Code, w.Body)
+	}
+	if w = h.do(http.MethodGet, "/me/export", nil); w.Code != http.StatusOK {
+		t.Fatalf("export after rejected delete: status %d", w.Code)
+	}
+
+	w = h.do(http.MethodDelete, "/me", map[string]string{"password": alice["password"]})
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("delete: status %d, body %s", w.Code, w.Body)
+	}
+	if at, _ := decode(t, w)["deletion_scheduled_at"].(string); at == "" {
+		t.Fatalf("delete: body %s", w.Body)
+	}
+
+	// 所有设备上的 Token 均已失效, 包括发起注销的这台 (其 cookie 已被清理)
+	for i, cookies := range sessions {
+		h.cookies = cookies
+		if w := h.do(http.MethodGet, "/me/export", nil); w.Code != http.StatusUnauthorized {
+			t.Fatalf("export with session %d after delete: status %d", i, w.Code)
+		}
+	}
+}
+
+func TestRestoreMeDuringGracePeriod(t *testing.T) {
+	h := newHarness(t)
+	h.do(http.MethodPost, "/register", alice)
+	login := map[string]string{"account": alice["account"], "password": alice["password"]}
+	h.do(http.MethodPost, "/login", login)
+	if w := h.do(http.MethodDelete, "/me", map[string]string{"password": alice["password"]}); w.Code != http.StatusAccepted {
+		t.Fatalf("delete: status %d, body %s", w.Code, w.Body)
+	}
+
+	// 宽限期内 仍可登录, 响应中带有删除时间
+	w := h.do(http.MethodPost, "/login", login)
+	if w.Code != http.StatusOK {
+		t.Fatalf("login during grace period: status %d, body %s", w.Code, w.Body)
+	}
+	if at, _ := decode(t, w)["deletion_scheduled_at"].(string); at == "" {
+		t.Fatalf("login during grace period: body %s, want deletion_scheduled_at", w.Body)
+	}
+
+	if w = h.do(http.MethodPost, "/me/restore", nil); w.Code != http.StatusOK {
+		t.Fatalf("restore: status %d, body %s", w.Code, w.Body)
+	}
+	events, err := h.audit.Find(context.Background(), domain.AuditFilter{Type: domain.AuditAccountRestore}, 0, 0)
+	if err != nil || len(events) != 1 || events[0].GetOutcome() != domain.AuditSuccess {
+		t.Fatalf("restore audit events = %v, %v", events, err)
+	}
+
+	h.cookies = map[string]*http.Cookie{}
+	w = h.do(http.MethodPost, "/login", login)
+	if _, ok := decode(t, w)["deletion_scheduled_at"]; w.Code != http.StatusOK || ok {
+		t.Fatalf("login after restore: status %d, body %s", w.Code, w.Body)
+	}
+}
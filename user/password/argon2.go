@@ -0,0 +1,75 @@
+package password
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+// Argon2id - argon2id 算法, Memory 单位为 KiB
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+// NewArgon2id - 参数为 0 时使用 64MiB / 3 / 2
+func NewArgon2id(memory uint32, iterations uint32, parallelism uint8) *Argon2id {
+	if memory == 0 {
+		memory = 64 * 1024
+	}
+	if iterations == 0 {
+		iterations = 3
+	}
+	if parallelism == 0 {
+		parallelism = 2
+	}
+	return &Argon2id{Memory: memory, Iterations: iterations, Parallelism: parallelism}
+}
+
+// Hash - implement domain.PasswordHasher
+func (a *Argon2id) Hash(password string) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	p := &phc{
+		id:      "argon2id",
+		version: argon2.Version,
+		params:  map[string]int{"m": int(a.Memory), "t": int(a.Iterations), "p": int(a.Parallelism)},
+		salt:    salt,
+		hash:    argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, keyLength),
+	}
+	return p.encode("m", "t", "p"), nil
+}
+
+// Verify - implement domain.PasswordHasher, 参数取自哈希值本身
+func (a *Argon2id) Verify(hash []byte, password string) (bool, error) {
+	p, err := decodePHC(hash)
+	if err != nil || p.id != "argon2id" || p.version != argon2.Version {
+		return false, ErrUnknownHash
+	}
+	key := argon2.IDKey([]byte(password), p.salt, uint32(p.params["t"]), uint32(p.params["m"]), uint8(p.params["p"]), uint32(len(p.hash)))
+	return subtle.ConstantTimeCompare(key, p.hash) == 1, nil
+}
+
+// NeedsRehash - implement domain.PasswordHasher
+func (a *Argon2id) NeedsRehash(hash []byte) bool {
+	p, err := decodePHC(hash)
+	if err != nil || p.version != argon2.Version {
+		return true
+	}
+	return p.params["m"] != int(a.Memory) || p.params["t"] != int(a.Iterations) || p.params["p"] != int(a.Parallelism)
+}
+
+// Identify - implement Algorithm
+func (a *Argon2id) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$argon2id$"))
+}
@@ -0,0 +1,57 @@
+package password
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/alibug/go-identity-utils/status"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// BcryptMaxBytes - bcrypt 只使用密码的前 72 字节, 超出部分被忽略
+const BcryptMaxBytes = 72
+
+// ErrPasswordTooLong - 密码超过 BcryptMaxBytes, 继续哈希会与同前缀的密码相同
+var ErrPasswordTooLong = fmt.Errorf("%w: password longer than %d bytes", status.ErrBadParamInput, BcryptMaxBytes)
+
+// Bcrypt - bcrypt 算法, Cost 可配置
+type Bcrypt struct {
+	Cost int
+}
+
+// NewBcrypt - cost 为 0 时使用 bcrypt.DefaultCost
+func NewBcrypt(cost int) *Bcrypt {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &Bcrypt{Cost: cost}
+}
+
+// Hash - implement domain.PasswordHasher
+func (b *Bcrypt) Hash(password string) ([]byte, error) {
+	if len(password) > BcryptMaxBytes {
+		return nil, ErrPasswordTooLong
+	}
+	return bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+}
+
+// Verify - implement domain.PasswordHasher
+// 不限制长度: 旧版本允许注册超长密码, 拒绝会导致这些用户无法登录
+func (b *Bcrypt) Verify(hash []byte, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// NeedsRehash - implement domain.PasswordHasher
+func (b *Bcrypt) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost != b.Cost
+}
+
+// Identify - implement Algorithm
+func (b *Bcrypt) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$2a$")) || bytes.HasPrefix(hash, []byte("$2b$")) || bytes.HasPrefix(hash, []byte("$2y$"))
+}
@@ -0,0 +1,129 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// prefixLength - k-anonymity 使用 SHA-1 的前 5 位十六进制 作为范围
+const prefixLength = 5
+
+// HIBPFile - 本地的 Have I Been Pwned 密码哈希文件
+//
+// 文件为按哈希排序的 "SHA1:COUNT" 行 (pwned-passwords-sha1-ordered-by-hash).
+// 检查时与 HIBP range API 一样: 按哈希前缀二分定位范围, 再比较范围内的后缀.
+type HIBPFile struct {
+	path     string
+	minCount int
+}
+
+// NewHIBPFile - minCount 为认定泄露的最少出现次数
+func NewHIBPFile(path string, minCount int) *HIBPFile {
+	if minCount < 1 {
+		minCount = 1
+	}
+	return &HIBPFile{path: path, minCount: minCount}
+}
+
+// IsBreached - implement BreachChecker
+func (h *HIBPFile) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	f, err := os.Open(h.path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+
+	offset, err := h.seekRange(f, info.Size(), prefix)
+	if err != nil {
+		return false, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.ToUpper(strings.TrimSpace(scanner.Text()))
+		if len(line) < prefixLength || line[:prefixLength] > prefix {
+			break
+		}
+		if line[:prefixLength] < prefix {
+			continue
+		}
+		parts := strings.SplitN(line[prefixLength:], ":", 2)
+		if parts[0] != suffix {
+			continue
+		}
+		count := 1
+		if len(parts) == 2 {
+			if n, err := strconv.Atoi(parts[1]); err == nil {
+				count = n
+			}
+		}
+		return count >= h.minCount, nil
+	}
+	return false, scanner.Err()
+}
+
+// seekRange - 二分查找 返回第一条前缀不小于 prefix 的行 之前的某个行首偏移
+func (h *HIBPFile) seekRange(f *os.File, size int64, prefix string) (int64, error) {
+	lo, hi := int64(0), size
+	for hi-lo > 4096 {
+		mid := lo + (hi-lo)/2
+		line, err := lineAfter(f, mid)
+		if err != nil {
+			return 0, err
+		}
+		if len(line) < prefixLength || strings.ToUpper(line[:prefixLength]) >= prefix {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	if lo == 0 {
+		return 0, nil
+	}
+	// 对齐到 lo 之后的下一个行首
+	buf := make([]byte, 4096)
+	n, err := f.ReadAt(buf, lo)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+		return lo + int64(i) + 1, nil
+	}
+	return lo, nil
+}
+
+// lineAfter - 返回 offset 之后的第一个完整行
+func lineAfter(f *os.File, offset int64) (string, error) {
+	buf := make([]byte, 256)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	buf = buf[:n]
+	start := bytes.IndexByte(buf, '\n')
+	if start < 0 {
+		return "", nil
+	}
+	buf = buf[start+1:]
+	if end := bytes.IndexByte(buf, '\n'); end >= 0 {
+		buf = buf[:end]
+	}
+	return strings.TrimSpace(string(buf)), nil
+}
@@ -0,0 +1,77 @@
+// Package password - 可插拔的密码哈希算法 (bcrypt / argon2id / scrypt)
+//
+// argon2id 与 scrypt 的哈希值采用 PHC 格式保存, 算法与参数都编码在哈希字符串中,
+// bcrypt 沿用其自身的 $2a$ 格式, 以兼容已有数据.
+package password
+
+import (
+	"errors"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/metrics"
+)
+
+// ErrUnknownHash - 无法识别哈希值所用的算法
+var ErrUnknownHash = errors.New("unknown password hash format")
+
+// Algorithm - 一种具体的哈希算法
+type Algorithm interface {
+	domain.PasswordHasher
+	// Identify - 哈希值是否由本算法生成
+	Identify(hash []byte) bool
+}
+
+// hasher - 用 current 生成新哈希, 用所有已知算法校验旧哈希
+type hasher struct {
+	current    Algorithm
+	algorithms []Algorithm
+}
+
+// New - current 为新密码使用的算法, 其他算法生成的哈希在校验成功后需要重新哈希
+func New(current Algorithm) domain.PasswordHasher {
+	return &hasher{
+		current:    current,
+		algorithms: []Algorithm{current, NewBcrypt(0), NewArgon2id(0, 0, 0), NewScrypt(0, 0, 0)},
+	}
+}
+
+func (h *hasher) Hash(password string) ([]byte, error) {
+	defer observe(h.current, "hash", time.Now())
+	return h.current.Hash(password)
+}
+
+func (h *hasher) Verify(hash []byte, password string) (bool, error) {
+	for _, a := range h.algorithms {
+		if a.Identify(hash) {
+			defer observe(a, "verify", time.Now())
+			return a.Verify(hash, password)
+		}
+	}
+	return false, ErrUnknownHash
+}
+
+func (h *hasher) NeedsRehash(hash []byte) bool {
+	if !h.current.Identify(hash) {
+		return true
+	}
+	return h.current.NeedsRehash(hash)
+}
+
+// observe - 记录哈希耗时, 用于评估 cost 等参数是否合适
+func observe(a Algorithm, operation string, start time.Time) {
+	metrics.PasswordHashDuration.WithLabelValues(algorithmName(a), operation).Observe(time.Since(start).Seconds())
+}
+
+func algorithmName(a Algorithm) string {
+	switch a.(type) {
+	case *Bcrypt:
+		return "bcrypt"
+	case *Argon2id:
+		return "argon2id"
+	case *Scrypt:
+		return "scrypt"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,145 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// 测试使用较低的参数, 保证速度
+func testAlgorithms() map[string]Algorithm {
+	return map[string]Algorithm{
+		"bcrypt":   NewBcrypt(4),
+		"argon2id": NewArgon2id(1024, 1, 1),
+		"scrypt":   NewScrypt(10, 8, 1),
+	}
+}
+
+func TestHashAndVerify(t *testing.T) {
+	for name, a := range testAlgorithms() {
+		t.Run(name, func(t *testing.T) {
+			h := New(a)
+			hash, err := h.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !a.Identify(hash) {
+				t.Errorf("%s does not identify its own hash %q", name, hash)
+			}
+			if ok, err := h.Verify(hash, "correct horse"); !ok || err != nil {
+				t.Errorf("Verify(correct) = %v, %v", ok, err)
+			}
+			if ok, err := h.Verify(hash, "wrong horse"); ok || err != nil {
+				t.Errorf("Verify(wrong) = %v, %v", ok, err)
+			}
+			if h.NeedsRehash(hash) {
+				t.Errorf("fresh hash %q needs rehash", hash)
+			}
+		})
+	}
+}
+
+func TestBcryptRejectsLongPassword(t *testing.T) {
+	h := New(NewBcrypt(4))
+	if _, err := h.Hash(strings.Repeat("a", BcryptMaxBytes)); err != nil {
+		t.Fatalf("Hash(72 bytes) err = %v", err)
+	}
+	// 超出部分会被忽略, 与同前缀的密码哈希相同, 必须拒绝
+	if _, err := h.Hash(strings.Repeat("a", BcryptMaxBytes+1)); !errors.Is(err, ErrPasswordTooLong) {
+		t.Fatalf("Hash(73 bytes) err = %v, want ErrPasswordTooLong", err)
+	}
+}
+
+func TestPHCFormat(t *testing.T) {
+	hash, _ := NewArgon2id(1024, 1, 1).Hash("pw")
+	if !strings.HasPrefix(string(hash), "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Errorf("unexpected argon2id hash %q", hash)
+	}
+	hash, _ = NewScrypt(10, 8, 1).Hash("pw")
+	if !strings.HasPrefix(string(hash), "$scrypt$ln=10,r=8,p=1$") {
+		t.Errorf("unexpected scrypt hash %q", hash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	oldBcrypt, _ := NewBcrypt(4).Hash("pw")
+	oldArgon, _ := NewArgon2id(1024, 1, 1).Hash("pw")
+
+	tests := []struct {
+		name    string
+		current Algorithm
+		hash    []byte
+		want    bool
+	}{
+		{"same bcrypt cost", NewBcrypt(4), oldBcrypt, false},
+		{"bcrypt cost raised", NewBcrypt(5), oldBcrypt, true},
+		{"bcrypt to argon2id", NewArgon2id(1024, 1, 1), oldBcrypt, true},
+		{"argon2id memory raised", NewArgon2id(2048, 1, 1), oldArgon, true},
+		{"argon2id to scrypt", NewScrypt(10, 8, 1), oldArgon, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(tt.current)
+			if got := h.NeedsRehash(tt.hash); got != tt.want {
+				t.Errorf("NeedsRehash() = %v, want %v", got, tt.want)
+			}
+			// 旧算法的哈希 仍可校验
+			if ok, err := h.Verify(tt.hash, "pw"); !ok || err != nil {
+				t.Errorf("Verify() = %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestVerifyUnknownHash(t *testing.T) {
+	if _, err := New(NewBcrypt(4)).Verify([]byte("plain"), "plain"); err != ErrUnknownHash {
+		t.Errorf("Verify() error = %v, want ErrUnknownHash", err)
+	}
+}
+
+func TestVerifyCorruptParams(t *testing.T) {
+	hasher := New(NewArgon2id(1024, 1, 1))
+	for _, hash := range []string{
+		// t、p 为 0 时 argon2.IDKey 会 panic
+		"$argon2id$v=19$m=1024,t=0,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=1024,t=1,p=0$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=2147483647,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=1024,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		// 空哈希 与任意密码的空 key 相等
+		"$argon2id$v=19$m=1024,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$",
+		"$scrypt$ln=40,r=8,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		// 单次校验超过 256MiB: 128*32*2^20 = 4GiB, 128*16*2^18 = 512MiB
+		"$scrypt$ln=20,r=32,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$scrypt$ln=18,r=16,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$scrypt$ln=17,r=16,p=64$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=1048576,t=1,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+		"$argon2id$v=19$m=65536,t=64,p=1$c2FsdHNhbHRzYWx0c2FsdA$aGFzaGhhc2hoYXNoaGFzaA",
+	} {
+		if ok, err := hasher.Verify([]byte(hash), "pw"); ok || err != ErrUnknownHash {
+			t.Errorf("Verify(%q) = %v, %v, want ErrUnknownHash", hash, ok, err)
+		}
+	}
+}
+
+func TestCheckParams(t *testing.T) {
+	tests := []struct {
+		name string
+		alg  Algorithm
+		ok   bool
+	}{
+		{"bcrypt", NewBcrypt(0), true},
+		{"argon2id default", NewArgon2id(0, 0, 0), true},
+		{"argon2id 256MiB", NewArgon2id(256<<10, 3, 2), true},
+		{"argon2id 512MiB", NewArgon2id(512<<10, 3, 2), false},
+		{"argon2id t=20", NewArgon2id(0, 20, 2), false},
+		{"scrypt default", NewScrypt(0, 0, 0), true},
+		{"scrypt 256MiB", NewScrypt(17, 16, 1), true},
+		{"scrypt ln=18", NewScrypt(18, 8, 1), false},
+		{"scrypt r=32", NewScrypt(15, 32, 1), false},
+	}
+	for _, tt := range tests {
+		if err := CheckParams(tt.alg); (err == nil) != tt.ok {
+			t.Errorf("CheckParams(%s) = %v, want ok %v", tt.name, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package password
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// phc - PHC 格式的哈希字符串: $<id>[$v=<version>]$<param>=<value>(,<param>=<value>)*$<salt>$<hash>
+type phc struct {
+	id      string
+	version int
+	params  map[string]int
+	salt    []byte
+	hash    []byte
+}
+
+// encode - 参数按 keys 的顺序输出
+func (p *phc) encode(keys ...string) []byte {
+	var b strings.Builder
+	b.WriteString("$" + p.id)
+	if p.version != 0 {
+		fmt.Fprintf(&b, "$v=%d", p.version)
+	}
+	params := make([]string, 0, len(keys))
+	for _, k := range keys {
+		params = append(params, fmt.Sprintf("%s=%d", k, p.params[k]))
+	}
+	b.WriteString("$" + strings.Join(params, ","))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.salt))
+	b.WriteString("$" + base64.RawStdEncoding.EncodeToString(p.hash))
+	return []byte(b.String())
+}
+
+func decodePHC(s []byte) (*phc, error) {
+	parts := strings.Split(string(s), "$")
+	// 第一段为空字符串
+	if len(parts) < 5 || parts[0] != "" {
+		return nil, ErrUnknownHash
+	}
+	p := &phc{id: parts[1], params: map[string]int{}}
+	rest := parts[2:]
+
+	if strings.HasPrefix(rest[0], "v=") {
+		v, err := strconv.Atoi(strings.TrimPrefix(rest[0], "v="))
+		if err != nil {
+			return nil, ErrUnknownHash
+		}
+		p.version = v
+		rest = rest[1:]
+	}
+	if len(rest) != 3 {
+		return nil, ErrUnknownHash
+	}
+
+	for _, kv := range strings.Split(rest[0], ",") {
+		pair := strings.SplitN(kv, "=", 2)
+		if len(pair) != 2 {
+			return nil, ErrUnknownHash
+		}
+		v, err := strconv.Atoi(pair[1])
+		if err != nil {
+			return nil, ErrUnknownHash
+		}
+		p.params[pair[0]] = v
+	}
+
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(rest[1]); err != nil {
+		return nil, ErrUnknownHash
+	}
+	if p.hash, err = base64.RawStdEncoding.DecodeString(rest[2]); err != nil || len(p.hash) == 0 {
+		return nil, ErrUnknownHash
+	}
+	if !p.inBounds() {
+		return nil, ErrUnknownHash
+	}
+	return p, nil
+}
+
+// paramBounds - 各算法参数的取值范围 [min, max]; 参数直接取自存储的哈希值,
+// 损坏或被篡改的记录 会使 argon2.IDKey panic (t 或 p 为 0), 或使一次校验 占用过多内存与 CPU.
+// 上限为单次校验约 256MiB 内存, 并限制迭代次数, 使最坏情况下一次校验仍在数秒内完成
+var paramBounds = map[string]map[string][2]int{
+	// m 单位为 KiB, 上限 256MiB; 耗时约与 m*t 成正比
+	"argon2id": {"m": {8, 256 << 10}, "t": {1, 10}, "p": {1, 16}},
+	// N = 2^ln, 内存为 128*r*N 字节: 上限 128*16*2^17 = 256MiB; p 个块依次计算, 耗时与 p 成正比
+	"scrypt": {"ln": {1, 17}, "r": {1, 16}, "p": {1, 4}},
+}
+
+// CheckParams - 配置的参数超出 paramBounds 时 生成的哈希将无法校验, 启动时检查; bcrypt 不检查
+func CheckParams(a Algorithm) error {
+	var id string
+	var params map[string]int
+	switch a := a.(type) {
+	case *Argon2id:
+		id, params = "argon2id", map[string]int{"m": int(a.Memory), "t": int(a.Iterations), "p": int(a.Parallelism)}
+	case *Scrypt:
+		id, params = "scrypt", map[string]int{"ln": a.LogN, "r": a.R, "p": a.P}
+	default:
+		return nil
+	}
+	if !(&phc{id: id, params: params}).inBounds() {
+		return fmt.Errorf("%s parameters %v exceed the supported range %v", id, params, paramBounds[id])
+	}
+	return nil
+}
+
+// inBounds - 未登记的算法不检查; 已登记的算法 参数缺失或超出范围时返回 false
+func (p *phc) inBounds() bool {
+	for k, bounds := range paramBounds[p.id] {
+		v, ok := p.params[k]
+		if !ok || v < bounds[0] || v > bounds[1] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,184 @@
+package password
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed" // 内置常见密码列表
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+//go:embed common_passwords.txt
+var commonPasswordsFile []byte
+
+// Violation - 密码违反的一条规则
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError - 密码不符合策略, 包含所有违反的规则
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (e *PolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		messages = append(messages, v.Message)
+	}
+	return "password does not meet policy: " + strings.Join(messages, "; ")
+}
+
+// Unwrap - errors.Is(err, status.ErrBadParamInput) 为 true
+func (e *PolicyError) Unwrap() error {
+	return status.ErrBadParamInput
+}
+
+// ErrorCode - implement domain.Coded interface
+func (e *PolicyError) ErrorCode() domain.ErrorCode {
+	return domain.CodePasswordPolicy
+}
+
+// FieldErrors - 每条违反的规则 对应一个 password 字段的错误
+func (e *PolicyError) FieldErrors() []domain.FieldError {
+	details := make([]domain.FieldError, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		details = append(details, domain.FieldError{Field: "password", Rule: v.Rule, Message: v.Message})
+	}
+	return details
+}
+
+// BreachChecker - 检查密码是否出现在已泄露的密码库中
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// PolicyConfig - 密码策略配置, 零值表示不启用对应规则
+type PolicyConfig struct {
+	MinLength int
+	// MaxLength - 按字符计
+	MaxLength int
+	// MaxBytes - 按 UTF-8 字节计, 使用 bcrypt 时不应超过 BcryptMaxBytes
+	MaxBytes      int
+	RequireLower  bool
+	RequireUpper  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	// CommonTopN - 拒绝内置列表中最常见的前 N 个密码
+	CommonTopN int
+	// Breached - 可选的泄露密码检查
+	Breached BreachChecker
+}
+
+// CommonPasswordCount - 内置常见密码列表的条数, CommonTopN 超过时按此计
+func CommonPasswordCount() int {
+	n := 0
+	scanner := bufio.NewScanner(bytes.NewReader(commonPasswordsFile))
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// Policy - implement domain.PasswordPolicy
+type Policy struct {
+	config PolicyConfig
+	common map[string]bool
+}
+
+// NewPolicy - 创建密码策略
+func NewPolicy(config PolicyConfig) *Policy {
+	p := &Policy{config: config, common: map[string]bool{}}
+	scanner := bufio.NewScanner(bytes.NewReader(commonPasswordsFile))
+	for len(p.common) < config.CommonTopN && scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			p.common[strings.ToLower(line)] = true
+		}
+	}
+	return p
+}
+
+// Validate - implement domain.PasswordPolicy, 不符合时返回 *PolicyError
+func (p *Policy) Validate(password string, account string, displayname string) error {
+	var violations []Violation
+	add := func(rule string, format string, args ...interface{}) {
+		violations = append(violations, Violation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if p.config.MinLength > 0 && length < p.config.MinLength {
+		add("min_length", "must be at least %d characters", p.config.MinLength)
+	}
+	if p.config.MaxLength > 0 && length > p.config.MaxLength {
+		add("max_length", "must be at most %d characters", p.config.MaxLength)
+	}
+	if p.config.MaxBytes > 0 && len(password) > p.config.MaxBytes {
+		add("max_bytes", "must be at most %d bytes", p.config.MaxBytes)
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.config.RequireLower && !lower {
+		add("lowercase", "must contain a lowercase letter")
+	}
+	if p.config.RequireUpper && !upper {
+		add("uppercase", "must contain an uppercase letter")
+	}
+	if p.config.RequireDigit && !digit {
+		add("digit", "must contain a digit")
+	}
+	if p.config.RequireSymbol && !symbol {
+		add("symbol", "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	if containsIdentity(lowered, account) {
+		add("contains_account", "must not contain the account")
+	}
+	if containsIdentity(lowered, displayname) {
+		add("contains_displayname", "must not contain the display name")
+	}
+	if p.common[lowered] {
+		add("common", "is too common")
+	}
+
+	if p.config.Breached != nil {
+		breached, err := p.config.Breached.IsBreached(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			add("breached", "has appeared in a data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// containsIdentity - 过短的账号/昵称 不做检查, 避免误判
+func containsIdentity(lowered string, identity string) bool {
+	identity = strings.ToLower(strings.TrimSpace(identity))
+	return utf8.RuneCountInString(identity) >= 3 && strings.Contains(lowered, identity)
+}
@@ -0,0 +1,135 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alibug/go-identity-utils/status"
+)
+
+func violatedRules(err error) map[string]bool {
+	rules := map[string]bool{}
+	var policyErr *PolicyError
+	if errors.As(err, &policyErr) {
+		for _, v := range policyErr.Violations {
+			rules[v.Rule] = true
+		}
+	}
+	return rules
+}
+
+func TestPolicyValidate(t *testing.T) {
+	policy := NewPolicy(PolicyConfig{
+		MinLength:     8,
+		MaxLength:     20,
+		RequireLower:  true,
+		RequireUpper:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		CommonTopN:    1000,
+	})
+
+	tests := []struct {
+		name     string
+		password string
+		want     []string
+	}{
+		{"valid", "Tr0ub4dor&3", nil},
+		{"too short", "Aa1!", []string{"min_length"}},
+		{"too long", "Aa1!Aa1!Aa1!Aa1!Aa1!x", []string{"max_length"}},
+		{"missing classes", "abcdefghij", []string{"uppercase", "digit", "symbol"}},
+		{"contains account", "xAlice99!", []string{"contains_account"}},
+		{"contains displayname", "Wonder1and!", []string{"contains_displayname"}},
+		{"common", "password", []string{"common", "uppercase", "digit", "symbol"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password, "alice", "wonder")
+			rules := violatedRules(err)
+			if len(rules) != len(tt.want) {
+				t.Fatalf("Validate(%q) violations = %v, want %v", tt.password, rules, tt.want)
+			}
+			for _, r := range tt.want {
+				if !rules[r] {
+					t.Errorf("Validate(%q) missing violation %q", tt.password, r)
+				}
+			}
+			if err != nil && !errors.Is(err, status.ErrBadParamInput) {
+				t.Errorf("error %v does not wrap ErrBadParamInput", err)
+			}
+		})
+	}
+}
+
+func TestPolicyMaxBytes(t *testing.T) {
+	policy := NewPolicy(PolicyConfig{MaxBytes: BcryptMaxBytes})
+	// 24 个三字节字符: 按字符计 24, 按字节计 72
+	if err := policy.Validate(strings.Repeat("密", 24), "", ""); err != nil {
+		t.Fatalf("72 bytes rejected: %v", err)
+	}
+	if rules := violatedRules(policy.Validate(strings.Repeat("密", 24)+"x", "", "")); !rules["max_bytes"] {
+		t.Fatalf("73 bytes violations = %v, want max_bytes", rules)
+	}
+}
+
+func TestCommonPasswordList(t *testing.T) {
+	n := CommonPasswordCount()
+	if n == 0 {
+		t.Fatal("embedded common password list is empty")
+	}
+	// 默认 CommonTopN 为列表长度, 列表中每一条都应被拒绝
+	policy := NewPolicy(PolicyConfig{CommonTopN: n})
+	if len(policy.common) != n {
+		t.Fatalf("loaded %d common passwords, want %d", len(policy.common), n)
+	}
+}
+
+func TestHIBPFile(t *testing.T) {
+	// SHA-1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	// SHA-1("123456")   = 7C4A8D09CA3762AF61E59520943DC26494F8941B
+	lines := "0000000000000000000000000000000000000001:3\n" +
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n" +
+		"5BAA6FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:1\n" +
+		"7C4A8D09CA3762AF61E59520943DC26494F8941B:2\n" +
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:1\n"
+	// 填充足够多的行, 触发二分查找
+	var filler []string
+	for i := 0; i < 5000; i++ {
+		sum := sha1.Sum([]byte(strconv.Itoa(i)))
+		filler = append(filler, strings.ToUpper(hex.EncodeToString(sum[:]))+":1\n")
+	}
+	all := append(strings.SplitAfter(lines, "\n"), filler...)
+	sort.Strings(all)
+
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(all, "")), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		password string
+		minCount int
+		want     bool
+	}{
+		{"password", 1, true},
+		{"123456", 1, true},
+		{"123456", 10, false},
+		{"not-in-the-file", 1, false},
+	}
+	for _, tt := range tests {
+		got, err := NewHIBPFile(path, tt.minCount).IsBreached(tt.password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("IsBreached(%q, min %d) = %v, want %v", tt.password, tt.minCount, got, tt.want)
+		}
+	}
+}
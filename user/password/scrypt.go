@@ -0,0 +1,76 @@
+package password
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Scrypt - scrypt 算法, N = 2^LogN
+type Scrypt struct {
+	LogN int
+	R    int
+	P    int
+}
+
+// NewScrypt - 参数为 0 时使用 N=2^15, r=8, p=1
+func NewScrypt(logN int, r int, p int) *Scrypt {
+	if logN == 0 {
+		logN = 15
+	}
+	if r == 0 {
+		r = 8
+	}
+	if p == 0 {
+		p = 1
+	}
+	return &Scrypt{LogN: logN, R: r, P: p}
+}
+
+// Hash - implement domain.PasswordHasher
+func (s *Scrypt) Hash(password string) ([]byte, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key, err := scrypt.Key([]byte(password), salt, 1<<s.LogN, s.R, s.P, keyLength)
+	if err != nil {
+		return nil, err
+	}
+	p := &phc{
+		id:     "scrypt",
+		params: map[string]int{"ln": s.LogN, "r": s.R, "p": s.P},
+		salt:   salt,
+		hash:   key,
+	}
+	return p.encode("ln", "r", "p"), nil
+}
+
+// Verify - implement domain.PasswordHasher, 参数取自哈希值本身
+func (s *Scrypt) Verify(hash []byte, password string) (bool, error) {
+	p, err := decodePHC(hash)
+	if err != nil || p.id != "scrypt" {
+		return false, ErrUnknownHash
+	}
+	key, err := scrypt.Key([]byte(password), p.salt, 1<<p.params["ln"], p.params["r"], p.params["p"], len(p.hash))
+	if err != nil {
+		return false, err
+	}
+	return subtle.ConstantTimeCompare(key, p.hash) == 1, nil
+}
+
+// NeedsRehash - implement domain.PasswordHasher
+func (s *Scrypt) NeedsRehash(hash []byte) bool {
+	p, err := decodePHC(hash)
+	if err != nil {
+		return true
+	}
+	return p.params["ln"] != s.LogN || p.params["r"] != s.R || p.params["p"] != s.P
+}
+
+// Identify - implement Algorithm
+func (s *Scrypt) Identify(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte("$scrypt$"))
+}
@@ -0,0 +1,6 @@
+package body
+
+// DeleteAccountBody - 注销账号时 需要再次输入密码
+type DeleteAccountBody struct {
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,15 @@
+package body
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// ExportBody - 用户数据导出 (GDPR)
+type ExportBody struct {
+	ExportedAt  time.Time           `json:"exported_at"`
+	Profile     domain.User         `json:"profile"`
+	Sessions    []domain.Session    `json:"sessions"`
+	AuditEvents []domain.AuditEvent `json:"audit_events"`
+}
@@ -2,6 +2,6 @@ package body
 
 // LoginBody - User login structure
 type LoginBody struct {
-	Account  string `json:"account"`
-	Password string `json:"password"`
+	Account  string `json:"account" binding:"required"`
+	Password string `json:"password" binding:"required"`
 }
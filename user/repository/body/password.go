@@ -0,0 +1,7 @@
+package body
+
+// ChangePasswordBody - 修改密码
+type ChangePasswordBody struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
+}
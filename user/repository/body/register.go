@@ -2,17 +2,17 @@ package body
 
 import (
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // RegisterBody ... 注册信息
 type RegisterBody struct {
 	Account     string     `json:"account" bson:"account" binding:"required"`
-	Password    string     `json:"password"  bson:"-" binding:"required,gte=6"`
+	Password    string     `json:"password"  bson:"-" binding:"required"`
 	Displayname string     `json:"displayname"  bson:"displayname" binding:"required"`
 	CryptPass   []byte     `json:"-" bson:"cryptpass,omitempty"`
 	CreatedAt   *time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	// PasswordChangedAt - 注册时即为密码设置时间
+	PasswordChangedAt *time.Time `json:"-" bson:"password_changed_at,omitempty"`
 }
 
 // GetAccount - implement domain.RegisterBody
@@ -25,17 +25,37 @@ func (r *RegisterBody) GetPassword() string {
 	return r.Password
 }
 
+// SetAccount - implement domain.RegisterBody
+func (r *RegisterBody) SetAccount(account string) {
+	r.Account = account
+}
+
+// GetDisplayName - implement domain.RegisterBody
+func (r *RegisterBody) GetDisplayName() string {
+	return r.Displayname
+}
+
+// GetCryptPass - implement domain.RegisterBody
+func (r *RegisterBody) GetCryptPass() []byte {
+	return r.CryptPass
+}
+
+// GetPasswordChangedAt - implement domain.RegisterBody
+func (r *RegisterBody) GetPasswordChangedAt() *time.Time {
+	return r.PasswordChangedAt
+}
+
 // SetCreatedTime - implement domain.RegisterBody
 func (r *RegisterBody) SetCreatedTime(t *time.Time) {
 	r.CreatedAt = t
 }
 
+// SetPasswordChangedTime - implement domain.RegisterBody
+func (r *RegisterBody) SetPasswordChangedTime(t *time.Time) {
+	r.PasswordChangedAt = t
+}
+
 // SetCryptPass - implement domain.RegisterBody
-func (r *RegisterBody) SetCryptPass() error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(r.Password), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-	r.CryptPass = hashedPassword
-	return nil
+func (r *RegisterBody) SetCryptPass(hash []byte) {
+	r.CryptPass = hash
 }
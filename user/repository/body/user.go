@@ -10,11 +10,18 @@ import (
 type UserBody struct {
 	ID converter.StrToObjectID `bson:"_id,omitempty" json:"id,omitempty"` // 用户ID
 	// RegisterBody
-	Account     string     `json:"account" bson:"account" binding:"required"`
-	Displayname string     `json:"displayname"  bson:"displayname" binding:"required"`
-	CryptPass   []byte     `json:"-" bson:"cryptpass,omitempty"`
-	CreatedAt   *time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
-	UpdatedAt   *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"` // 更新时间
+	Account     string `json:"account" bson:"account" binding:"required"`
+	Displayname string `json:"displayname"  bson:"displayname" binding:"required"`
+	CryptPass   []byte `json:"-" bson:"cryptpass,omitempty"`
+	// PasswordHistory - 之前使用过的密码哈希, 最近的在前
+	PasswordHistory   [][]byte   `json:"-" bson:"password_history,omitempty"`
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty" bson:"password_changed_at,omitempty"`
+	CreatedAt         *time.Time `json:"created_at,omitempty" bson:"created_at,omitempty"`
+	UpdatedAt         *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"` // 更新时间
+	Roles             []string   `bson:"roles,omitempty" json:"roles,omitempty"`           // 角色, 如 admin
+	// DeletionScheduledAt - 用户申请注销后 实际删除的时间
+	DeletionScheduledAt *time.Time `bson:"deletion_scheduled_at,omitempty" json:"deletion_scheduled_at,omitempty"`
+	AnonymizedAt        *time.Time `bson:"anonymized_at,omitempty" json:"anonymized_at,omitempty"`
 }
 
 // GetUserID - implement domain.User
@@ -22,17 +29,42 @@ func (u *UserBody) GetUserID() string {
 	return string(u.ID)
 }
 
+// GetAccount - implement domain.User
+func (u *UserBody) GetAccount() string {
+	return u.Account
+}
+
 // GetDisplayName - implement domain.User
 func (u *UserBody) GetDisplayName() string {
 	return u.Displayname
 }
 
+// GetRoles - implement domain.User
+func (u *UserBody) GetRoles() []string {
+	return u.Roles
+}
+
 // GetCryptPass - implement domain.User
 func (u *UserBody) GetCryptPass() []byte {
 	return u.CryptPass
 }
 
+// GetPasswordHistory - implement domain.User
+func (u *UserBody) GetPasswordHistory() [][]byte {
+	return u.PasswordHistory
+}
+
+// GetPasswordChangedAt - implement domain.User
+func (u *UserBody) GetPasswordChangedAt() *time.Time {
+	return u.PasswordChangedAt
+}
+
 // SetUpdatedTime - implement domain.User
 func (u *UserBody) SetUpdatedTime(t *time.Time) {
 	u.UpdatedAt = t
 }
+
+// GetDeletionScheduledAt - implement domain.User
+func (u *UserBody) GetDeletionScheduledAt() *time.Time {
+	return u.DeletionScheduledAt
+}
@@ -0,0 +1,178 @@
+package memrepo
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/user/repository/body"
+	"github.com/alibug/go-identity-utils/converter"
+	"github.com/alibug/go-identity-utils/status"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// memoryUserRepository - 仅用于本地开发和测试, 数据不持久化
+type memoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[string]*body.UserBody
+	// accounts - 小写账号 -> 用户ID, 与 Mongo 的 account_unique_ci 一致
+	accounts map[string]string
+}
+
+// NewMemoryUserRepository will create an object that represent the user.Repository interface
+func NewMemoryUserRepository() domain.UserRepository {
+	return &memoryUserRepository{
+		users:    map[string]*body.UserBody{},
+		accounts: map[string]string{},
+	}
+}
+
+func (m *memoryUserRepository) RegisterUser(ctx context.Context, register domain.Register) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := strings.ToLower(register.GetAccount())
+	if _, ok := m.accounts[key]; ok {
+		return status.ErrConflict
+	}
+
+	now := time.Now()
+	register.SetCreatedTime(&now)
+	// 与 Mongo 一致 使用 ObjectID, 以便格式校验的行为相同
+	id := primitive.NewObjectID().Hex()
+	m.users[id] = &body.UserBody{
+		ID:                converter.StrToObjectID(id),
+		Account:           register.GetAccount(),
+		Displayname:       register.GetDisplayName(),
+		CryptPass:         register.GetCryptPass(),
+		PasswordChangedAt: register.GetPasswordChangedAt(),
+		CreatedAt:         &now,
+	}
+	m.accounts[key] = id
+	return nil
+}
+
+func (m *memoryUserRepository) GetByID(ctx context.Context, id string) (domain.User, error) {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return nil, status.ErrBadParamInput
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[id]
+	if !ok {
+		return nil, status.ErrNotFound
+	}
+	return copyUser(u), nil
+}
+
+func (m *memoryUserRepository) GetByAccount(ctx context.Context, account string) (domain.User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	// 与唯一约束一致 大小写不敏感
+	id, ok := m.accounts[strings.ToLower(account)]
+	if !ok {
+		return nil, status.ErrNotFound
+	}
+	return copyUser(m.users[id]), nil
+}
+
+func (m *memoryUserRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	return m.update(id, true, func(u *body.UserBody) {
+		u.DeletionScheduledAt = &at
+	})
+}
+
+func (m *memoryUserRepository) CancelDeletion(ctx context.Context, id string) error {
+	return m.update(id, true, func(u *body.UserBody) {
+		u.DeletionScheduledAt = nil
+	})
+}
+
+func (m *memoryUserRepository) ListDueDeletions(ctx context.Context, before time.Time) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var ids []string
+	for id, u := range m.users {
+		if u.DeletionScheduledAt != nil && !u.DeletionScheduledAt.After(before) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryUserRepository) AnonymizeUser(ctx context.Context, id string) error {
+	// 账号替换为不可登录的占位值, 只保留用户ID 供其他数据引用
+	return m.update(id, false, func(u *body.UserBody) {
+		delete(m.accounts, strings.ToLower(u.Account))
+		u.Account = "deleted:" + id
+		m.accounts[u.Account] = id
+		now := time.Now()
+		u.Displayname = ""
+		u.AnonymizedAt = &now
+		u.CryptPass = nil
+		u.PasswordHistory = nil
+		u.CreatedAt = nil
+		u.DeletionScheduledAt = nil
+	})
+}
+
+func (m *memoryUserRepository) UpdateCryptPass(ctx context.Context, id string, hash []byte) error {
+	return m.update(id, false, func(u *body.UserBody) {
+		u.CryptPass = hash
+	})
+}
+
+func (m *memoryUserRepository) ChangePassword(ctx context.Context, id string, hash []byte, history [][]byte, changedAt time.Time) error {
+	return m.update(id, false, func(u *body.UserBody) {
+		u.CryptPass = hash
+		u.PasswordHistory = history
+		u.PasswordChangedAt = &changedAt
+	})
+}
+
+func (m *memoryUserRepository) DeleteUser(ctx context.Context, id string) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[id]; ok {
+		delete(m.accounts, strings.ToLower(u.Account))
+		delete(m.users, id)
+	}
+	return nil
+}
+
+// update - mustExist 为 false 时, 用户不存在视为成功, 与 Mongo 的 UpdateOne 一致
+func (m *memoryUserRepository) update(id string, mustExist bool, fn func(u *body.UserBody)) error {
+	if _, err := primitive.ObjectIDFromHex(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.users[id]
+	if !ok {
+		if mustExist {
+			return status.ErrNotFound
+		}
+		return nil
+	}
+	fn(u)
+	now := time.Now()
+	u.UpdatedAt = &now
+	return nil
+}
+
+// copyUser - 返回副本, 避免调用方修改仓库中的数据
+func copyUser(u *body.UserBody) *body.UserBody {
+	c := *u
+	c.Roles = append([]string(nil), u.Roles...)
+	c.PasswordHistory = append([][]byte(nil), u.PasswordHistory...)
+	return &c
+}
@@ -0,0 +1,15 @@
+package memrepo_test
+
+import (
+	"testing"
+
+	"github.com/alibug/go-identity-entry/domain"
+	memrepo "github.com/alibug/go-identity-entry/user/repository/memory"
+	"github.com/alibug/go-identity-entry/user/repository/repotest"
+)
+
+func TestContract(t *testing.T) {
+	repotest.UserRepositoryContract(t, func(t *testing.T) domain.UserRepository {
+		return memrepo.NewMemoryUserRepository()
+	})
+}
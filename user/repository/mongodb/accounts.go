@@ -0,0 +1,130 @@
+package mongorepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// accountIndexName - account 唯一索引, 大小写不敏感
+const accountIndexName = "account_unique_ci"
+
+// AccountReport - NormalizeAccounts 的结果
+type AccountReport struct {
+	// Updated - 已改写为规范形式的账号数
+	Updated int
+	// Collisions - 规范形式 -> 冲突的原始账号, 这些账号保持不变, 需人工处理
+	Collisions map[string][]string
+	// Invalid - 无法规范化的原始账号
+	Invalid []string
+}
+
+// accountCollation - account 索引的 collation; 查询须指定相同的 collation 才能使用该索引
+var accountCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// EnsureAccountIndex - 在 account 上创建 大小写不敏感的唯一索引
+func EnsureAccountIndex(ctx context.Context, coll *mongo.Collection) error {
+	_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "account", Value: 1}},
+		Options: options.Index().
+			SetName(accountIndexName).
+			SetUnique(true).
+			SetCollation(accountCollation),
+	})
+	return err
+}
+
+// ErrAccountIndexMissing - account 唯一索引不存在 或定义不符, 注册将无法拦截重复账号
+var ErrAccountIndexMissing = errors.New("unique case-insensitive index on account not found, run `migrate up`")
+
+// CheckAccountIndex - 启动时确认 account 唯一索引已创建: RegisterUser 依赖它拦截重复账号, 不再预先查询
+func CheckAccountIndex(ctx context.Context, coll *mongo.Collection) error {
+	cur, err := coll.Indexes().List(ctx)
+	// 集合尚不存在 (NamespaceNotFound)
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Code == 26 {
+		return ErrAccountIndexMissing
+	}
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var index struct {
+			Name      string `bson:"name"`
+			Unique    bool   `bson:"unique"`
+			Collation struct {
+				Locale   string `bson:"locale"`
+				Strength int    `bson:"strength"`
+			} `bson:"collation"`
+		}
+		if err := cur.Decode(&index); err != nil {
+			return err
+		}
+		if index.Name == accountIndexName {
+			if !index.Unique || index.Collation.Strength != 2 {
+				return fmt.Errorf("%w: index %s is not unique and case-insensitive", ErrAccountIndexMissing, accountIndexName)
+			}
+			return nil
+		}
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+	return ErrAccountIndexMissing
+}
+
+// NormalizeAccounts - 将已有账号改写为规范形式, 并报告冲突
+func NormalizeAccounts(ctx context.Context, coll *mongo.Collection, normalizer domain.AccountNormalizer) (*AccountReport, error) {
+	cur, err := coll.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1, "account": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	type account struct {
+		ID      primitive.ObjectID `bson:"_id"`
+		Account string             `bson:"account"`
+	}
+	groups := map[string][]account{}
+	report := &AccountReport{Collisions: map[string][]string{}}
+	for cur.Next(ctx) {
+		var a account
+		if err := cur.Decode(&a); err != nil {
+			return nil, err
+		}
+		normalized, err := normalizer.Normalize(a.Account)
+		if err != nil {
+			report.Invalid = append(report.Invalid, a.Account)
+			continue
+		}
+		groups[normalized] = append(groups[normalized], a)
+	}
+	if err := cur.Err(); err != nil {
+		return nil, err
+	}
+
+	for normalized, accounts := range groups {
+		if len(accounts) > 1 {
+			for _, a := range accounts {
+				report.Collisions[normalized] = append(report.Collisions[normalized], a.Account)
+			}
+			continue
+		}
+		if accounts[0].Account == normalized {
+			continue
+		}
+		_, err := coll.UpdateOne(ctx, bson.M{"_id": accounts[0].ID}, bson.M{"$set": bson.M{"account": normalized}})
+		if err != nil {
+			return report, err
+		}
+		report.Updated++
+	}
+	return report, nil
+}
@@ -0,0 +1,87 @@
+package mongorepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/migration"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// usersColl - 用户集合名
+const usersColl = "users"
+
+// deletionIndexName - 供 ListDueDeletions 使用
+const deletionIndexName = "deletion_scheduled_at"
+
+// Migrations - users 集合的迁移, 版本号一经发布不可修改
+func Migrations(db *mongo.Database, normalizer domain.AccountNormalizer) []migration.Migration {
+	coll := db.Collection(usersColl)
+	return []migration.Migration{
+		{
+			Version:     1,
+			Description: "normalize accounts",
+			Up: func(ctx context.Context) error {
+				report, err := NormalizeAccounts(ctx, coll, normalizer)
+				if err != nil {
+					return err
+				}
+				for _, a := range report.Invalid {
+					logging.FromContext(ctx).Warn("account cannot be normalized", zap.String("account", a))
+				}
+				// 冲突账号需人工处理后 重新执行迁移
+				if len(report.Collisions) > 0 {
+					for normalized, accounts := range report.Collisions {
+						logging.FromContext(ctx).Error("account collision", zap.String("normalized", normalized), zap.Strings("accounts", accounts))
+					}
+					return fmt.Errorf("%d account collisions must be resolved manually", len(report.Collisions))
+				}
+				logging.FromContext(ctx).Info("accounts normalized", zap.Int("updated", report.Updated))
+				return nil
+			},
+		},
+		{
+			Version:     2,
+			Description: "unique case-insensitive index on account",
+			Up: func(ctx context.Context) error {
+				return EnsureAccountIndex(ctx, coll)
+			},
+			Down: func(ctx context.Context) error {
+				_, err := coll.Indexes().DropOne(ctx, accountIndexName)
+				return err
+			},
+		},
+		{
+			Version:     3,
+			Description: "backfill password_changed_at from created_at",
+			Up: func(ctx context.Context) error {
+				// 老用户没有 password_changed_at, 否则 密码过期策略 会视其为从未设置
+				_, err := coll.UpdateMany(ctx,
+					bson.M{"password_changed_at": bson.M{"$exists": false}, "created_at": bson.M{"$exists": true}},
+					mongo.Pipeline{{{Key: "$set", Value: bson.M{"password_changed_at": "$created_at"}}}},
+				)
+				return err
+			},
+		},
+		{
+			Version:     4,
+			Description: "index on deletion_scheduled_at",
+			Up: func(ctx context.Context) error {
+				_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+					Keys:    bson.D{{Key: "deletion_scheduled_at", Value: 1}},
+					Options: options.Index().SetName(deletionIndexName).SetSparse(true),
+				})
+				return err
+			},
+			Down: func(ctx context.Context) error {
+				_, err := coll.Indexes().DropOne(ctx, deletionIndexName)
+				return err
+			},
+		},
+	}
+}
@@ -0,0 +1,41 @@
+package mongorepo_test
+
+import (
+	"context"
+	"testing"
+
+	mongorepo "github.com/alibug/go-identity-entry/user/repository/mongodb"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// 不需要 MongoDB: 使用驱动自带的 mock 部署, 检查发送的命令
+func TestGetByAccountUsesIndexCollation(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	defer mt.Close()
+
+	mt.Run("find", func(mt *mtest.T) {
+		ns := mt.Coll.Database().Name() + "." + mt.Coll.Name()
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, ns, mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "account", Value: "alice@example.com"}}))
+
+		user, err := mongorepo.NewMongoUserRepository(mt.Coll).GetByAccount(context.Background(), "alice@example.com")
+		if err != nil || user.GetAccount() != "alice@example.com" {
+			t.Fatalf("GetByAccount = %v, %v", user, err)
+		}
+
+		// 与 account_unique_ci 的 collation 一致, 查询才能使用该索引
+		cmd := mt.GetStartedEvent().Command
+		collation, ok := cmd.Lookup("collation").DocumentOK()
+		if !ok {
+			t.Fatalf("find sent without collation: %v", cmd)
+		}
+		if locale := collation.Lookup("locale").StringValue(); locale != "en" {
+			t.Errorf("collation locale = %q, want en", locale)
+		}
+		if strength := collation.Lookup("strength").Int32(); strength != 2 {
+			t.Errorf("collation strength = %d, want 2", strength)
+		}
+	})
+}
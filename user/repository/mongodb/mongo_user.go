@@ -5,11 +5,14 @@ import (
 	"time"
 
 	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
 	"github.com/alibug/go-identity-entry/user/repository/body"
 	"github.com/alibug/go-identity-utils/status"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
 )
 
 type mongoUserRepository struct {
@@ -22,27 +25,20 @@ func NewMongoUserRepository(coll *mongo.Collection) domain.UserRepository {
 }
 
 func (m *mongoUserRepository) RegisterUser(ctx context.Context, register domain.Register) error {
-	// 1、 GetByUsername, if user existed, throw err
-	// 	  如果已有同名用户，抛出错误
-	_, err := m.GetByAccount(ctx, register.GetAccount())
-	if err == nil {
-		return status.ErrConflict
-	}
-
-	// 2、SetCreatedTime
-	//	 如果没有错误，设置用户注册日期
+	// 1、SetCreatedTime
+	//	 设置用户注册日期
 	now := time.Now()
 	register.SetCreatedTime(&now)
 
-	// 3、SetCyptPass
-	err = register.SetCryptPass()
-	if err != nil {
-		return err
+	// 2、Insert User
+	//	 将用户数据入库, 同名用户由 account 唯一索引拦截 (见 EnsureAccountIndex)
+	//	 先查后插在并发注册时会同时成功, 所以不再预先 GetByAccount
+	_, err := m.userColl.InsertOne(ctx, register)
+	if mongo.IsDuplicateKeyError(err) {
+		// 原始错误中有冲突的索引名, 映射为 ErrConflict 后 只记录在日志中
+		logging.FromContext(ctx).Debug("register rejected by unique index", zap.Error(err))
+		return status.ErrConflict
 	}
-
-	// 3、Insert User
-	//	 将用户数据入库
-	_, err = m.userColl.InsertOne(ctx, register)
 	return err
 }
 
@@ -54,11 +50,123 @@ func (m *mongoUserRepository) GetByID(ctx context.Context, id string) (domain.Us
 
 	var u body.UserBody
 	err = m.userColl.FindOne(ctx, bson.M{"_id": objectID}).Decode(&u)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.ErrNotFound
+	}
 	return &u, err
 }
 
+// GetByAccount - 与 account_unique_ci 使用相同的 collation, 否则无法使用该索引 而扫描全表
 func (m *mongoUserRepository) GetByAccount(ctx context.Context, account string) (domain.User, error) {
 	var u body.UserBody
-	err := m.userColl.FindOne(ctx, bson.M{"account": account}).Decode(&u)
+	err := m.userColl.FindOne(ctx, bson.M{"account": account}, options.FindOne().SetCollation(accountCollation)).Decode(&u)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.ErrNotFound
+	}
 	return &u, err
 }
+
+func (m *mongoUserRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	now := time.Now()
+	res, err := m.userColl.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"deletion_scheduled_at": at, "updated_at": now}})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoUserRepository) CancelDeletion(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	res, err := m.userColl.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set":   bson.M{"updated_at": time.Now()},
+		"$unset": bson.M{"deletion_scheduled_at": ""},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoUserRepository) ListDueDeletions(ctx context.Context, before time.Time) ([]string, error) {
+	cur, err := m.userColl.Find(ctx, bson.M{"deletion_scheduled_at": bson.M{"$lte": before}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var ids []string
+	for cur.Next(ctx) {
+		var u body.UserBody
+		if err := cur.Decode(&u); err != nil {
+			return nil, err
+		}
+		ids = append(ids, u.GetUserID())
+	}
+	return ids, cur.Err()
+}
+
+func (m *mongoUserRepository) AnonymizeUser(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	// 账号替换为不可登录的占位值, 只保留用户ID 供其他数据引用
+	now := time.Now()
+	_, err = m.userColl.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{
+		"$set":   bson.M{"account": "deleted:" + id, "displayname": "", "anonymized_at": now, "updated_at": now},
+		"$unset": bson.M{"cryptpass": "", "password_history": "", "created_at": "", "deletion_scheduled_at": ""},
+	})
+	return err
+}
+
+func (m *mongoUserRepository) UpdateCryptPass(ctx context.Context, id string, hash []byte) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	now := time.Now()
+	_, err = m.userColl.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{"cryptpass": hash, "updated_at": now}})
+	return err
+}
+
+func (m *mongoUserRepository) ChangePassword(ctx context.Context, id string, hash []byte, history [][]byte, changedAt time.Time) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	_, err = m.userColl.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": bson.M{
+		"cryptpass":           hash,
+		"password_history":    history,
+		"password_changed_at": changedAt,
+		"updated_at":          changedAt,
+	}})
+	return err
+}
+
+func (m *mongoUserRepository) DeleteUser(ctx context.Context, id string) error {
+	objectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	_, err = m.userColl.DeleteOne(ctx, bson.M{"_id": objectID})
+	return err
+}
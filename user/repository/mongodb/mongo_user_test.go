@@ -0,0 +1,67 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	mongorepo "github.com/alibug/go-identity-entry/user/repository/mongodb"
+	"github.com/alibug/go-identity-entry/user/repository/repotest"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// testColl - 需要 MONGO_TEST_URI 指向一个可用的 MongoDB, 否则跳过
+func testColl(t *testing.T) *mongo.Collection {
+	t.Helper()
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	coll := client.Database(fmt.Sprintf("identity_test_%d", time.Now().UnixNano())).Collection("users")
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = coll.Database().Drop(ctx)
+		_ = client.Disconnect(ctx)
+	})
+
+	if err := mongorepo.EnsureAccountIndex(ctx, coll); err != nil {
+		t.Fatal(err)
+	}
+	return coll
+}
+
+func TestCheckAccountIndex(t *testing.T) {
+	coll := testColl(t)
+	ctx := context.Background()
+	if err := mongorepo.CheckAccountIndex(ctx, coll); err != nil {
+		t.Fatalf("CheckAccountIndex = %v", err)
+	}
+	if _, err := coll.Indexes().DropAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := mongorepo.CheckAccountIndex(ctx, coll); !errors.Is(err, mongorepo.ErrAccountIndexMissing) {
+		t.Fatalf("CheckAccountIndex = %v, want ErrAccountIndexMissing", err)
+	}
+	if err := mongorepo.CheckAccountIndex(ctx, coll.Database().Collection("missing")); !errors.Is(err, mongorepo.ErrAccountIndexMissing) {
+		t.Fatalf("CheckAccountIndex(missing collection) = %v, want ErrAccountIndexMissing", err)
+	}
+}
+
+func TestContract(t *testing.T) {
+	repotest.UserRepositoryContract(t, func(t *testing.T) domain.UserRepository {
+		return mongorepo.NewMongoUserRepository(testColl(t))
+	})
+}
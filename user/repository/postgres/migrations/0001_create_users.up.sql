@@ -0,0 +1,16 @@
+CREATE TABLE users (
+    id                    UUID PRIMARY KEY,
+    account               TEXT NOT NULL,
+    displayname           TEXT NOT NULL DEFAULT '',
+    cryptpass             BYTEA,
+    password_history      BYTEA[],
+    password_changed_at   TIMESTAMPTZ,
+    created_at            TIMESTAMPTZ,
+    updated_at            TIMESTAMPTZ,
+    roles                 TEXT[] NOT NULL DEFAULT '{}',
+    deletion_scheduled_at TIMESTAMPTZ,
+    anonymized_at         TIMESTAMPTZ
+);
+
+-- 与 Mongo 的 account_unique_ci 一致: 大小写不敏感的唯一约束
+CREATE UNIQUE INDEX users_account_unique_ci ON users (lower(account));
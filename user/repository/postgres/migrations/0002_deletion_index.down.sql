@@ -0,0 +1 @@
+DROP INDEX users_deletion_scheduled_at;
@@ -0,0 +1,2 @@
+CREATE INDEX users_deletion_scheduled_at ON users (deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
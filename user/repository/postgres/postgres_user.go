@@ -0,0 +1,186 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/user/repository/body"
+	"github.com/alibug/go-identity-utils/converter"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// uniqueViolation - Postgres 唯一约束冲突的错误码
+const uniqueViolation = "23505"
+
+const selectUser = `SELECT id, account, displayname, cryptpass, password_history, password_changed_at,
+	created_at, updated_at, roles, deletion_scheduled_at, anonymized_at FROM users`
+
+type postgresUserRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresUserRepository will create an object that represent the user.Repository interface
+func NewPostgresUserRepository(db *sql.DB) domain.UserRepository {
+	return &postgresUserRepository{db}
+}
+
+func (p *postgresUserRepository) RegisterUser(ctx context.Context, register domain.Register) error {
+	// 1、SetCreatedTime
+	now := time.Now()
+	register.SetCreatedTime(&now)
+
+	// 2、Insert User, 同名用户由 users_account_unique_ci 拦截
+	_, err := p.db.ExecContext(ctx, `INSERT INTO users (id, account, displayname, cryptpass, password_changed_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		uuid.New(), register.GetAccount(), register.GetDisplayName(), register.GetCryptPass(), register.GetPasswordChangedAt(), now)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		logging.FromContext(ctx).Debug("register rejected by unique index", zap.String("constraint", pqErr.Constraint), zap.Error(err))
+		return status.ErrConflict
+	}
+	return err
+}
+
+func (p *postgresUserRepository) GetByID(ctx context.Context, id string) (domain.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, status.ErrBadParamInput
+	}
+	return p.getOne(ctx, selectUser+` WHERE id = $1`, userID)
+}
+
+// GetByAccount - 条件与 users_account_unique_ci 的表达式一致, 才能使用该索引
+func (p *postgresUserRepository) GetByAccount(ctx context.Context, account string) (domain.User, error) {
+	return p.getOne(ctx, selectUser+` WHERE lower(account) = lower($1)`, account)
+}
+
+func (p *postgresUserRepository) getOne(ctx context.Context, query string, arg interface{}) (domain.User, error) {
+	var (
+		u  body.UserBody
+		id string
+	)
+	err := p.db.QueryRowContext(ctx, query, arg).Scan(
+		&id, &u.Account, &u.Displayname, &u.CryptPass, (*pq.ByteaArray)(&u.PasswordHistory), &u.PasswordChangedAt,
+		&u.CreatedAt, &u.UpdatedAt, pq.Array(&u.Roles), &u.DeletionScheduledAt, &u.AnonymizedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, status.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	u.ID = converter.StrToObjectID(id)
+	return &u, nil
+}
+
+func (p *postgresUserRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	res, err := p.db.ExecContext(ctx, `UPDATE users SET deletion_scheduled_at = $2, updated_at = $3 WHERE id = $1`,
+		userID, at, time.Now())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresUserRepository) CancelDeletion(ctx context.Context, id string) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	res, err := p.db.ExecContext(ctx, `UPDATE users SET deletion_scheduled_at = NULL, updated_at = $2 WHERE id = $1`,
+		userID, time.Now())
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresUserRepository) ListDueDeletions(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := p.db.QueryContext(ctx, `SELECT id FROM users WHERE deletion_scheduled_at <= $1`, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (p *postgresUserRepository) AnonymizeUser(ctx context.Context, id string) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	// 账号替换为不可登录的占位值, 只保留用户ID 供其他数据引用
+	now := time.Now()
+	_, err = p.db.ExecContext(ctx, `UPDATE users SET account = $2, displayname = '', anonymized_at = $3, updated_at = $3,
+		cryptpass = NULL, password_history = NULL, created_at = NULL, deletion_scheduled_at = NULL WHERE id = $1`,
+		userID, "deleted:"+id, now)
+	return err
+}
+
+func (p *postgresUserRepository) UpdateCryptPass(ctx context.Context, id string, hash []byte) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	_, err = p.db.ExecContext(ctx, `UPDATE users SET cryptpass = $2, updated_at = $3 WHERE id = $1`, userID, hash, time.Now())
+	return err
+}
+
+func (p *postgresUserRepository) ChangePassword(ctx context.Context, id string, hash []byte, history [][]byte, changedAt time.Time) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	_, err = p.db.ExecContext(ctx, `UPDATE users SET cryptpass = $2, password_history = $3, password_changed_at = $4, updated_at = $4
+		WHERE id = $1`, userID, hash, pq.ByteaArray(history), changedAt)
+	return err
+}
+
+func (p *postgresUserRepository) DeleteUser(ctx context.Context, id string) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return status.ErrBadParamInput
+	}
+
+	_, err = p.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	return err
+}
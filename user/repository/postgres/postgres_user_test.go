@@ -0,0 +1,83 @@
+package pgrepo_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/migration"
+	pgrepo "github.com/alibug/go-identity-entry/user/repository/postgres"
+	"github.com/alibug/go-identity-entry/user/repository/repotest"
+	_ "github.com/lib/pq"
+)
+
+// testDB - 需要 POSTGRES_TEST_DSN (postgres:// URL) 指向一个可用的 Postgres, 否则跳过;
+// 每个测试使用独立的 schema
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("identity_test_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := u.Query()
+	q.Set("search_path", schema)
+	u.RawQuery = q.Encode()
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		admin.Close()
+	})
+
+	if _, err := migration.NewMigrator(migration.NewPostgresStore(db), pgrepo.Migrations(db)).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestContract(t *testing.T) {
+	repotest.UserRepositoryContract(t, func(t *testing.T) domain.UserRepository {
+		return pgrepo.NewPostgresUserRepository(testDB(t))
+	})
+}
+
+func TestMigrationsDown(t *testing.T) {
+	db := testDB(t)
+	migrator := migration.NewMigrator(migration.NewPostgresStore(db), pgrepo.Migrations(db))
+
+	ctx := context.Background()
+	done, err := migrator.Down(ctx, len(pgrepo.Migrations(db)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(done) != len(pgrepo.Migrations(db)) {
+		t.Fatalf("Down = %v", done)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
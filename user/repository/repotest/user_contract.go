@@ -0,0 +1,276 @@
+// Package repotest - domain.UserRepository 各实现共用的契约测试
+package repotest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/user/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+// UserRepositoryContract - newRepo 每次调用须返回一个空的仓库
+func UserRepositoryContract(t *testing.T, newRepo func(t *testing.T) domain.UserRepository) {
+	t.Run("RegisterAndGet", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		changedAt := time.Now().Add(-time.Hour)
+
+		register(t, repo, "alice@example.com", &changedAt)
+		byAccount, err := repo.GetByAccount(ctx, "alice@example.com")
+		if err != nil {
+			t.Fatalf("GetByAccount: %v", err)
+		}
+		if byAccount.GetUserID() == "" || byAccount.GetDisplayName() != "alice@example.com" ||
+			!bytes.Equal(byAccount.GetCryptPass(), []byte("hash")) {
+			t.Fatalf("GetByAccount returned %+v", byAccount)
+		}
+		if at := byAccount.GetPasswordChangedAt(); at == nil || !sameTime(*at, changedAt) {
+			t.Fatalf("password_changed_at = %v, want %v", at, changedAt)
+		}
+
+		byID, err := repo.GetByID(ctx, byAccount.GetUserID())
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if byID.GetAccount() != "alice@example.com" {
+			t.Fatalf("GetByID account = %q", byID.GetAccount())
+		}
+	})
+
+	t.Run("RegisterConflict", func(t *testing.T) {
+		repo := newRepo(t)
+		register(t, repo, "bob@example.com", nil)
+
+		// 唯一约束 大小写不敏感
+		for _, account := range []string{"bob@example.com", "Bob@Example.com"} {
+			err := repo.RegisterUser(context.Background(), newRegister(account, nil))
+			if !errors.Is(err, status.ErrConflict) {
+				t.Fatalf("RegisterUser(%q) = %v, want ErrConflict", account, err)
+			}
+		}
+	})
+
+	t.Run("RegisterConflictMixedCaseFirst", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		// 先注册大小写混合的账号: 只把查询条件转为小写 或只对小写账号建唯一约束的实现 会在此出错
+		id := register(t, repo, "Alice@Example.com", nil)
+
+		u, err := repo.GetByAccount(ctx, "alice@example.com")
+		if err != nil || u.GetUserID() != id {
+			t.Fatalf("GetByAccount(lower) = %v, %v; want user %s", u, err, id)
+		}
+		if u.GetAccount() != "Alice@Example.com" {
+			t.Fatalf("account = %q, want the registered spelling", u.GetAccount())
+		}
+		if err := repo.RegisterUser(ctx, newRegister("alice@example.com", nil)); !errors.Is(err, status.ErrConflict) {
+			t.Fatalf("RegisterUser(lower) = %v, want ErrConflict", err)
+		}
+	})
+
+	t.Run("RegisterConcurrent", func(t *testing.T) {
+		repo := newRepo(t)
+		// 唯一约束 而不是先查询再写入 才能保证并发注册同一账号时 只有一个成功
+		const n = 20
+		var (
+			wg       sync.WaitGroup
+			mu       sync.Mutex
+			won      int
+			conflict int
+		)
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				// 大小写不同的同一账号也应冲突
+				account := "judy@example.com"
+				if i%2 == 1 {
+					account = "Judy@Example.com"
+				}
+				err := repo.RegisterUser(context.Background(), newRegister(account, nil))
+
+				mu.Lock()
+				defer mu.Unlock()
+				switch {
+				case err == nil:
+					won++
+				case errors.Is(err, status.ErrConflict):
+					conflict++
+				default:
+					t.Errorf("RegisterUser(%q): %v", account, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		if won != 1 || conflict != n-1 {
+			t.Fatalf("won = %d, conflict = %d, want 1 and %d", won, conflict, n-1)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+
+		if _, err := repo.GetByAccount(ctx, "nobody@example.com"); !errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("GetByAccount = %v, want ErrNotFound", err)
+		}
+		if _, err := repo.GetByID(ctx, "not-an-id"); !errors.Is(err, status.ErrBadParamInput) {
+			t.Fatalf("GetByID(malformed) = %v, want ErrBadParamInput", err)
+		}
+
+		id := register(t, repo, "carol@example.com", nil)
+		if err := repo.DeleteUser(ctx, id); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+		if _, err := repo.GetByID(ctx, id); !errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("GetByID(deleted) = %v, want ErrNotFound", err)
+		}
+		if err := repo.ScheduleDeletion(ctx, id, time.Now()); !errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("ScheduleDeletion(deleted) = %v, want ErrNotFound", err)
+		}
+		if err := repo.CancelDeletion(ctx, id); !errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("CancelDeletion(deleted) = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("DeleteIdempotent", func(t *testing.T) {
+		repo := newRepo(t)
+		id := register(t, repo, "dave@example.com", nil)
+		for i := 0; i < 2; i++ {
+			if err := repo.DeleteUser(context.Background(), id); err != nil {
+				t.Fatalf("DeleteUser #%d: %v", i+1, err)
+			}
+		}
+	})
+
+	t.Run("ScheduleDeletion", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		due := register(t, repo, "erin@example.com", nil)
+		later := register(t, repo, "frank@example.com", nil)
+		register(t, repo, "grace@example.com", nil)
+
+		now := time.Now()
+		if err := repo.ScheduleDeletion(ctx, due, now.Add(-time.Minute)); err != nil {
+			t.Fatalf("ScheduleDeletion: %v", err)
+		}
+		if err := repo.ScheduleDeletion(ctx, later, now.Add(time.Hour)); err != nil {
+			t.Fatalf("ScheduleDeletion: %v", err)
+		}
+
+		ids, err := repo.ListDueDeletions(ctx, now)
+		if err != nil {
+			t.Fatalf("ListDueDeletions: %v", err)
+		}
+		if len(ids) != 1 || ids[0] != due {
+			t.Fatalf("ListDueDeletions = %v, want [%s]", ids, due)
+		}
+		u, err := repo.GetByID(ctx, due)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if u.GetDeletionScheduledAt() == nil {
+			t.Fatal("deletion_scheduled_at not set")
+		}
+
+		// 撤销后 不再到期
+		if err := repo.CancelDeletion(ctx, due); err != nil {
+			t.Fatalf("CancelDeletion: %v", err)
+		}
+		if ids, err := repo.ListDueDeletions(ctx, now); err != nil || len(ids) != 0 {
+			t.Fatalf("ListDueDeletions after cancel = %v, %v, want none", ids, err)
+		}
+		if u, _ := repo.GetByID(ctx, due); u.GetDeletionScheduledAt() != nil {
+			t.Fatalf("deletion_scheduled_at = %v after cancel, want nil", u.GetDeletionScheduledAt())
+		}
+	})
+
+	t.Run("ChangePassword", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		id := register(t, repo, "heidi@example.com", nil)
+
+		changedAt := time.Now()
+		history := [][]byte{[]byte("hash"), []byte("older")}
+		if err := repo.ChangePassword(ctx, id, []byte("new"), history, changedAt); err != nil {
+			t.Fatalf("ChangePassword: %v", err)
+		}
+		u, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if !bytes.Equal(u.GetCryptPass(), []byte("new")) {
+			t.Fatalf("cryptpass = %q, want new", u.GetCryptPass())
+		}
+		got := u.GetPasswordHistory()
+		if len(got) != 2 || !bytes.Equal(got[0], history[0]) || !bytes.Equal(got[1], history[1]) {
+			t.Fatalf("password_history = %q, want %q", got, history)
+		}
+		if at := u.GetPasswordChangedAt(); at == nil || !sameTime(*at, changedAt) {
+			t.Fatalf("password_changed_at = %v, want %v", at, changedAt)
+		}
+
+		if err := repo.UpdateCryptPass(ctx, id, []byte("rehashed")); err != nil {
+			t.Fatalf("UpdateCryptPass: %v", err)
+		}
+		u, _ = repo.GetByID(ctx, id)
+		if !bytes.Equal(u.GetCryptPass(), []byte("rehashed")) {
+			t.Fatalf("cryptpass = %q, want rehashed", u.GetCryptPass())
+		}
+	})
+
+	t.Run("AnonymizeUser", func(t *testing.T) {
+		repo := newRepo(t)
+		ctx := context.Background()
+		id := register(t, repo, "ivan@example.com", nil)
+
+		if err := repo.AnonymizeUser(ctx, id); err != nil {
+			t.Fatalf("AnonymizeUser: %v", err)
+		}
+		u, err := repo.GetByID(ctx, id)
+		if err != nil {
+			t.Fatalf("GetByID: %v", err)
+		}
+		if u.GetAccount() != "deleted:"+id || u.GetDisplayName() != "" || len(u.GetCryptPass()) != 0 {
+			t.Fatalf("user not anonymized: %+v", u)
+		}
+		// 原账号可以重新注册
+		register(t, repo, "ivan@example.com", nil)
+	})
+}
+
+func newRegister(account string, changedAt *time.Time) *body.RegisterBody {
+	return &body.RegisterBody{
+		Account:           account,
+		Displayname:       account,
+		CryptPass:         []byte("hash"),
+		PasswordChangedAt: changedAt,
+	}
+}
+
+// register - 注册并返回用户ID
+func register(t *testing.T, repo domain.UserRepository, account string, changedAt *time.Time) string {
+	t.Helper()
+	ctx := context.Background()
+	if err := repo.RegisterUser(ctx, newRegister(account, changedAt)); err != nil {
+		t.Fatalf("RegisterUser(%q): %v", account, err)
+	}
+	u, err := repo.GetByAccount(ctx, account)
+	if err != nil {
+		t.Fatalf("GetByAccount(%q): %v", account, err)
+	}
+	return u.GetUserID()
+}
+
+// sameTime - 各存储的时间精度不同, Mongo 为毫秒
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Millisecond && d < time.Millisecond
+}
@@ -0,0 +1,18 @@
+package sqliterepo
+
+import (
+	"database/sql"
+	"embed"
+
+	"github.com/alibug/go-identity-entry/migration"
+)
+
+// migrationFiles - 文件名为 <版本>_<描述>.up.sql / .down.sql
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migrations - users 表的迁移
+func Migrations(db *sql.DB) []migration.Migration {
+	return migration.SQLFiles(db, migrationFiles, "migrations")
+}
@@ -0,0 +1,20 @@
+-- 时间统一以 UTC 写入, 以便按文本比较
+CREATE TABLE users (
+    id                    TEXT PRIMARY KEY,
+    account               TEXT NOT NULL,
+    displayname           TEXT NOT NULL DEFAULT '',
+    cryptpass             BLOB,
+    password_history      TEXT,
+    password_changed_at   DATETIME,
+    created_at            DATETIME,
+    updated_at            DATETIME,
+    roles                 TEXT,
+    deletion_scheduled_at DATETIME,
+    anonymized_at         DATETIME
+);
+
+-- 与 Mongo 的 account_unique_ci 一致: 大小写不敏感的唯一约束
+CREATE UNIQUE INDEX users_account_unique_ci ON users (lower(account));
+
+CREATE INDEX users_deletion_scheduled_at ON users (deletion_scheduled_at)
+    WHERE deletion_scheduled_at IS NOT NULL;
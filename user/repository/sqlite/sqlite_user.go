@@ -0,0 +1,212 @@
+package sqliterepo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/user/repository/body"
+	"github.com/alibug/go-identity-utils/converter"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const selectUser = `SELECT id, account, displayname, cryptpass, password_history, password_changed_at,
+	created_at, updated_at, roles, deletion_scheduled_at, anonymized_at FROM users`
+
+type sqliteUserRepository struct {
+	db *sql.DB
+}
+
+// busyTimeout - 其他连接持有写锁时 等待的时间; 不设置时 并发写入立即返回 SQLITE_BUSY
+const busyTimeout = 5 * time.Second
+
+// Open - 打开 SQLite 数据库文件, 设置 busy_timeout, 并发注册等写入 排队执行而不是失败
+func Open(path string) (*sql.DB, error) {
+	return sql.Open("sqlite", fmt.Sprintf("file:%s?_pragma=busy_timeout(%d)", path, busyTimeout.Milliseconds()))
+}
+
+// NewSQLiteUserRepository will create an object that represent the user.Repository interface
+func NewSQLiteUserRepository(db *sql.DB) domain.UserRepository {
+	return &sqliteUserRepository{db}
+}
+
+func (s *sqliteUserRepository) RegisterUser(ctx context.Context, register domain.Register) error {
+	// 1、SetCreatedTime
+	now := time.Now()
+	register.SetCreatedTime(&now)
+
+	// 2、Insert User, 同名用户由 users_account_unique_ci 拦截
+	_, err := s.db.ExecContext(ctx, `INSERT INTO users (id, account, displayname, cryptpass, password_changed_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		uuid.New().String(), register.GetAccount(), register.GetDisplayName(), register.GetCryptPass(),
+		utc(register.GetPasswordChangedAt()), now.UTC())
+	var sqliteErr *sqlite.Error
+	if errors.As(err, &sqliteErr) && sqliteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE {
+		logging.FromContext(ctx).Debug("register rejected by unique index", zap.Error(err))
+		return status.ErrConflict
+	}
+	return err
+}
+
+func (s *sqliteUserRepository) GetByID(ctx context.Context, id string) (domain.User, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, status.ErrBadParamInput
+	}
+	return s.getOne(ctx, selectUser+` WHERE id = ?`, id)
+}
+
+// GetByAccount - 条件与 users_account_unique_ci 的表达式一致, 才能使用该索引
+func (s *sqliteUserRepository) GetByAccount(ctx context.Context, account string) (domain.User, error) {
+	return s.getOne(ctx, selectUser+` WHERE lower(account) = lower(?)`, account)
+}
+
+func (s *sqliteUserRepository) getOne(ctx context.Context, query string, arg interface{}) (domain.User, error) {
+	var (
+		u              body.UserBody
+		id             string
+		history, roles sql.NullString
+	)
+	err := s.db.QueryRowContext(ctx, query, arg).Scan(
+		&id, &u.Account, &u.Displayname, &u.CryptPass, &history, &u.PasswordChangedAt,
+		&u.CreatedAt, &u.UpdatedAt, &roles, &u.DeletionScheduledAt, &u.AnonymizedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, status.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 数组列以 JSON 保存
+	if history.Valid {
+		if err := json.Unmarshal([]byte(history.String), &u.PasswordHistory); err != nil {
+			return nil, err
+		}
+	}
+	if roles.Valid {
+		if err := json.Unmarshal([]byte(roles.String), &u.Roles); err != nil {
+			return nil, err
+		}
+	}
+	u.ID = converter.StrToObjectID(id)
+	return &u, nil
+}
+
+func (s *sqliteUserRepository) ScheduleDeletion(ctx context.Context, id string, at time.Time) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET deletion_scheduled_at = ?, updated_at = ? WHERE id = ?`,
+		at.UTC(), time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteUserRepository) CancelDeletion(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	res, err := s.db.ExecContext(ctx, `UPDATE users SET deletion_scheduled_at = NULL, updated_at = ? WHERE id = ?`,
+		time.Now().UTC(), id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqliteUserRepository) ListDueDeletions(ctx context.Context, before time.Time) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT id FROM users WHERE deletion_scheduled_at <= ?`, before.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+func (s *sqliteUserRepository) AnonymizeUser(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	// 账号替换为不可登录的占位值, 只保留用户ID 供其他数据引用
+	now := time.Now().UTC()
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET account = ?, displayname = '', anonymized_at = ?, updated_at = ?,
+		cryptpass = NULL, password_history = NULL, created_at = NULL, deletion_scheduled_at = NULL WHERE id = ?`,
+		"deleted:"+id, now, now, id)
+	return err
+}
+
+func (s *sqliteUserRepository) UpdateCryptPass(ctx context.Context, id string, hash []byte) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET cryptpass = ?, updated_at = ? WHERE id = ?`, hash, time.Now().UTC(), id)
+	return err
+}
+
+func (s *sqliteUserRepository) ChangePassword(ctx context.Context, id string, hash []byte, history [][]byte, changedAt time.Time) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	encoded, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `UPDATE users SET cryptpass = ?, password_history = ?, password_changed_at = ?, updated_at = ?
+		WHERE id = ?`, hash, string(encoded), changedAt.UTC(), changedAt.UTC(), id)
+	return err
+}
+
+func (s *sqliteUserRepository) DeleteUser(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return status.ErrBadParamInput
+	}
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
+	return err
+}
+
+// utc - 时间统一以 UTC 写入, 以便按文本比较
+func utc(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
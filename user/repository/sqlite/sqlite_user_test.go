@@ -0,0 +1,54 @@
+package sqliterepo_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/migration"
+	"github.com/alibug/go-identity-entry/user/repository/repotest"
+	sqliterepo "github.com/alibug/go-identity-entry/user/repository/sqlite"
+	_ "modernc.org/sqlite"
+)
+
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sqliterepo.Open(filepath.Join(t.TempDir(), "identity.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := migration.NewMigrator(migration.NewSQLiteStore(db), sqliterepo.Migrations(db)).Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestContract(t *testing.T) {
+	repotest.UserRepositoryContract(t, func(t *testing.T) domain.UserRepository {
+		return sqliterepo.NewSQLiteUserRepository(testDB(t))
+	})
+}
+
+func TestMigrationsDown(t *testing.T) {
+	db := testDB(t)
+	migrator := migration.NewMigrator(migration.NewSQLiteStore(db), sqliterepo.Migrations(db))
+
+	ctx := context.Background()
+	if _, err := migrator.Down(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statuses[0].AppliedAt != nil {
+		t.Fatal("migration 1 still applied")
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
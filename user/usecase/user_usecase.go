@@ -2,35 +2,174 @@ package usecase
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/alibug/go-identity-entry/domain"
+	eventBody "github.com/alibug/go-identity-entry/event/repository/body"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-entry/metrics"
+	"github.com/alibug/go-identity-entry/tracing"
+	"github.com/alibug/go-identity-entry/user/account"
+	"github.com/alibug/go-identity-entry/user/password"
 	"github.com/alibug/go-identity-utils/status"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// errInvalidCredentials - 账号不存在与密码错误 返回同一个错误, 避免泄露账号是否存在
+var errInvalidCredentials = domain.NewCodedError(status.ErrBadParamInput, domain.CodeInvalidCredentials, "username or password invalid")
+
 type userUsecase struct {
 	userRepo       domain.UserRepository
 	contextTimeout time.Duration
+	// deletionGrace - 用户申请注销后 到实际删除之间的宽限期
+	deletionGrace time.Duration
+	// eraseOnDelete - true 时彻底删除用户, 否则只做匿名化
+	eraseOnDelete bool
+	publisher     domain.EventPublisher
+	transactor    domain.Transactor
+	hasher        domain.PasswordHasher
+	policy        domain.PasswordPolicy
+	normalizer    domain.AccountNormalizer
+	// historySize - 新密码不能与最近 historySize 个密码 (含当前密码) 相同
+	historySize int
+	// passwordMaxAge - 按角色要求的密码最长使用时间
+	passwordMaxAge map[string]time.Duration
+}
+
+// Option - 用于配置 userUsecase 的可选参数
+type Option func(*userUsecase)
+
+// WithDeletionGrace - 设置注销宽限期, 为 0 时立即删除
+func WithDeletionGrace(grace time.Duration) Option {
+	return func(u *userUsecase) {
+		u.deletionGrace = grace
+	}
+}
+
+// WithEraseOnDelete - 注销时彻底删除用户文档, 而非匿名化
+func WithEraseOnDelete(erase bool) Option {
+	return func(u *userUsecase) {
+		u.eraseOnDelete = erase
+	}
+}
+
+// WithEventPublisher - 发布 UserRegistered 等领域事件; 未配置 WithTransactor 时 写入失败只记录日志
+func WithEventPublisher(p domain.EventPublisher) Option {
+	return func(u *userUsecase) {
+		u.publisher = p
+	}
+}
+
+// WithTransactor - 让用户数据与领域事件 在同一事务中写入
+func WithTransactor(tx domain.Transactor) Option {
+	return func(u *userUsecase) {
+		u.transactor = tx
+	}
+}
+
+// WithPasswordHasher - 设置密码哈希算法, 缺省为 bcrypt.DefaultCost
+func WithPasswordHasher(h domain.PasswordHasher) Option {
+	return func(u *userUsecase) {
+		u.hasher = h
+	}
+}
+
+// WithPasswordPolicy - 设置密码策略, 缺省只要求至少 6 位
+func WithPasswordPolicy(p domain.PasswordPolicy) Option {
+	return func(u *userUsecase) {
+		u.policy = p
+	}
+}
+
+// WithPasswordHistory - 禁止重复使用最近 size 个密码, 0 表示不限制
+func WithPasswordHistory(size int) Option {
+	return func(u *userUsecase) {
+		u.historySize = size
+	}
+}
+
+// WithPasswordMaxAge - 拥有对应角色的用户 须在 maxAge 内修改密码
+func WithPasswordMaxAge(maxAge map[string]time.Duration) Option {
+	return func(u *userUsecase) {
+		u.passwordMaxAge = maxAge
+	}
+}
+
+// WithAccountNormalizer - 设置账号规范化规则, 缺省不做邮箱规范化
+func WithAccountNormalizer(n domain.AccountNormalizer) Option {
+	return func(u *userUsecase) {
+		u.normalizer = n
+	}
 }
 
 // NewUserUsecase will create new an userUsecase object representation of domain.ArticleUsecase interface
-func NewUserUsecase(repo domain.UserRepository, timeout time.Duration) domain.UserUsecase {
-	return &userUsecase{
+func NewUserUsecase(repo domain.UserRepository, timeout time.Duration, opts ...Option) domain.UserUsecase {
+	u := &userUsecase{
 		userRepo:       repo,
 		contextTimeout: timeout,
+		hasher:         password.New(password.NewBcrypt(bcrypt.DefaultCost)),
+		policy:         password.NewPolicy(password.PolicyConfig{MinLength: 6}),
+		normalizer:     account.NewNormalizer(false),
+	}
+	for _, opt := range opts {
+		opt(u)
 	}
+	return u
 }
 
-func (u *userUsecase) RegisterUserUC(c context.Context, body domain.Register) error {
-	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+func (u *userUsecase) RegisterUserUC(c context.Context, body domain.Register) (err error) {
+	ctx, span := tracing.Start(c, "userUsecase.RegisterUserUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
-	return u.userRepo.RegisterUser(ctx, body)
+	defer func() {
+		metrics.RegistrationsTotal.WithLabelValues(metrics.Outcome(err)).Inc()
+	}()
+
+	normalized, err := u.normalizer.Normalize(body.GetAccount())
+	if err != nil {
+		return err
+	}
+	body.SetAccount(normalized)
+
+	err = u.policy.Validate(body.GetPassword(), body.GetAccount(), body.GetDisplayName())
+	if err != nil {
+		return err
+	}
+
+	hash, err := u.hashPassword(ctx, body.GetPassword())
+	if err != nil {
+		return err
+	}
+	body.SetCryptPass(hash)
+	now := time.Now()
+	body.SetPasswordChangedTime(&now)
+
+	return u.withTransaction(ctx, func(ctx context.Context) error {
+		err := u.userRepo.RegisterUser(ctx, body)
+		if errors.Is(err, status.ErrConflict) {
+			return domain.NewCodedError(status.ErrConflict, domain.CodeAccountExists, "account already exists")
+		}
+		if err != nil || u.publisher == nil {
+			return err
+		}
+		user, err := u.userRepo.GetByAccount(ctx, body.GetAccount())
+		if err != nil {
+			return u.publishFailed(ctx, domain.EventUserRegistered, err)
+		}
+		return u.publish(ctx, domain.EventUserRegistered, user.GetUserID(), map[string]string{
+			"account":     user.GetAccount(),
+			"displayname": user.GetDisplayName(),
+		})
+	})
 }
 
 func (u *userUsecase) GetByIDUC(c context.Context, id string) (res domain.User, err error) {
-	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	ctx, span := tracing.Start(c, "userUsecase.GetByIDUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
 	res, err = u.userRepo.GetByID(ctx, id)
@@ -42,9 +181,15 @@ func (u *userUsecase) GetByIDUC(c context.Context, id string) (res domain.User,
 }
 
 func (u *userUsecase) GetByAccountUC(c context.Context, username string) (res domain.User, err error) {
-	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+	ctx, span := tracing.Start(c, "userUsecase.GetByAccountUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
 
+	username, err = u.normalizer.Normalize(username)
+	if err != nil {
+		return
+	}
 	res, err = u.userRepo.GetByAccount(ctx, username)
 	if err != nil {
 		return
@@ -53,19 +198,250 @@ func (u *userUsecase) GetByAccountUC(c context.Context, username string) (res do
 	return
 }
 
-func (u *userUsecase) CheckAccountAndPassUC(c context.Context, username string, password string) (domain.User, error) {
-	ctx, cancel := context.WithTimeout(c, u.contextTimeout)
+func (u *userUsecase) CheckAccountAndPassUC(c context.Context, username string, password string) (_ domain.User, err error) {
+	ctx, span := tracing.Start(c, "userUsecase.CheckAccountAndPassUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
 	defer cancel()
+	// 账号不存在与密码错误 同为 invalid_credentials
+	defer func() {
+		metrics.LoginsTotal.WithLabelValues(metrics.Outcome(err)).Inc()
+	}()
 
+	username, err = u.normalizer.Normalize(username)
+	if err != nil {
+		return nil, errInvalidCredentials
+	}
 	res, err := u.userRepo.GetByAccount(ctx, username)
 	if err != nil {
-		return nil, fmt.Errorf("%w: username or password invalid", status.ErrBadParamInput)
+		return nil, errInvalidCredentials
 	}
 
 	// 2、用户存在 则比较密码
-	err = bcrypt.CompareHashAndPassword(res.GetCryptPass(), []byte(password))
-	if err != nil {
-		return nil, fmt.Errorf("%w: username or password invalid", status.ErrBadParamInput)
+	ok, err := u.verifyPassword(ctx, res.GetCryptPass(), password)
+	if err != nil || !ok {
+		return nil, errInvalidCredentials
+	}
+	// 已申请注销的用户 宽限期内仍可登录, 以便通过 CancelDeletionUC 撤销
+
+	// 3、哈希算法或参数已过时 则用当前配置重新哈希, 失败不影响登录
+	if u.hasher.NeedsRehash(res.GetCryptPass()) {
+		u.rehash(ctx, res.GetUserID(), password)
 	}
 	return res, nil
 }
+
+func (u *userUsecase) ChangePasswordUC(c context.Context, id string, oldPassword string, newPassword string) (err error) {
+	ctx, span := tracing.Start(c, "userUsecase.ChangePasswordUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// 1、校验旧密码
+	res, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	ok, err := u.verifyPassword(ctx, res.GetCryptPass(), oldPassword)
+	if err != nil || !ok {
+		return domain.NewCodedError(status.ErrUnauthorized, domain.CodeInvalidCredentials, "password invalid")
+	}
+
+	// 2、新密码 须符合密码策略
+	err = u.policy.Validate(newPassword, res.GetAccount(), res.GetDisplayName())
+	if err != nil {
+		return err
+	}
+
+	// 3、不能与最近使用过的密码相同
+	history := append([][]byte{res.GetCryptPass()}, res.GetPasswordHistory()...)
+	if u.historySize > 0 {
+		if len(history) > u.historySize {
+			history = history[:u.historySize]
+		}
+		for _, old := range history {
+			if ok, _ := u.verifyPassword(ctx, old, newPassword); ok {
+				return domain.NewCodedError(status.ErrBadParamInput, domain.CodePasswordReused, "password was used recently")
+			}
+		}
+		// 新密码成为当前密码后, 历史中只需保留 historySize-1 个
+		if len(history) > u.historySize-1 {
+			history = history[:u.historySize-1]
+		}
+	} else {
+		history = nil
+	}
+
+	hash, err := u.hashPassword(ctx, newPassword)
+	if err != nil {
+		return err
+	}
+	return u.withTransaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.ChangePassword(ctx, id, hash, history, time.Now()); err != nil {
+			return err
+		}
+		return u.publish(ctx, domain.EventPasswordChanged, id, nil)
+	})
+}
+
+func (u *userUsecase) PasswordExpiredUC(user domain.User) bool {
+	var maxAge time.Duration
+	for _, role := range user.GetRoles() {
+		if age, ok := u.passwordMaxAge[role]; ok && (maxAge == 0 || age < maxAge) {
+			maxAge = age
+		}
+	}
+	if maxAge == 0 {
+		return false
+	}
+	// 没有修改记录的旧用户 视为已过期
+	changedAt := user.GetPasswordChangedAt()
+	return changedAt == nil || time.Since(*changedAt) > maxAge
+}
+
+// hashPassword - 哈希耗时单独作为一个 span, 便于与存储耗时区分
+func (u *userUsecase) hashPassword(ctx context.Context, password string) ([]byte, error) {
+	_, span := tracing.Start(ctx, "password.Hash")
+	hash, err := u.hasher.Hash(password)
+	tracing.End(span, err)
+	return hash, err
+}
+
+// verifyPassword - 同 hashPassword
+func (u *userUsecase) verifyPassword(ctx context.Context, hash []byte, password string) (bool, error) {
+	_, span := tracing.Start(ctx, "password.Verify")
+	ok, err := u.hasher.Verify(hash, password)
+	tracing.End(span, err)
+	return ok, err
+}
+
+func (u *userUsecase) rehash(ctx context.Context, id string, password string) {
+	hash, err := u.hashPassword(ctx, password)
+	if err == nil {
+		err = u.userRepo.UpdateCryptPass(ctx, id, hash)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Warn("rehash password failed", zap.String("user_id", id), zap.Error(err))
+	}
+}
+
+func (u *userUsecase) ScheduleDeletionUC(c context.Context, id string, password string) (_ time.Time, err error) {
+	ctx, span := tracing.Start(c, "userUsecase.ScheduleDeletionUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// 1、注销前 重新校验密码
+	res, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ok, err := u.verifyPassword(ctx, res.GetCryptPass(), password)
+	if err != nil || !ok {
+		return time.Time{}, domain.NewCodedError(status.ErrUnauthorized, domain.CodeInvalidCredentials, "password invalid")
+	}
+
+	// 2、已经申请过注销 直接返回原定时间
+	if at := res.GetDeletionScheduledAt(); at != nil {
+		return *at, nil
+	}
+
+	at := time.Now().Add(u.deletionGrace)
+	err = u.withTransaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.ScheduleDeletion(ctx, id, at); err != nil {
+			return err
+		}
+		return u.publish(ctx, domain.EventUserDeletionScheduled, id, map[string]string{
+			"deletion_scheduled_at": at.Format(time.RFC3339),
+		})
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// 3、没有宽限期 立即删除
+	if u.deletionGrace <= 0 {
+		err = u.purgeUser(ctx, id)
+	}
+	return at, err
+}
+
+func (u *userUsecase) CancelDeletionUC(c context.Context, id string) (err error) {
+	ctx, span := tracing.Start(c, "userUsecase.CancelDeletionUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	// 没有申请注销 视为成功
+	res, err := u.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if res.GetDeletionScheduledAt() == nil {
+		return nil
+	}
+
+	return u.withTransaction(ctx, func(ctx context.Context) error {
+		if err := u.userRepo.CancelDeletion(ctx, id); err != nil {
+			return err
+		}
+		return u.publish(ctx, domain.EventUserDeletionCancelled, id, nil)
+	})
+}
+
+func (u *userUsecase) PurgeDeletedUsersUC(c context.Context) (_ int, err error) {
+	ctx, span := tracing.Start(c, "userUsecase.PurgeDeletedUsersUC")
+	defer func() { tracing.End(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, u.contextTimeout)
+	defer cancel()
+
+	ids, err := u.userRepo.ListDueDeletions(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, id := range ids {
+		if err := u.purgeUser(ctx, id); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (u *userUsecase) purgeUser(ctx context.Context, id string) error {
+	if u.eraseOnDelete {
+		return u.userRepo.DeleteUser(ctx, id)
+	}
+	return u.userRepo.AnonymizeUser(ctx, id)
+}
+
+// withTransaction - 未配置 transactor 时 直接执行 fn
+func (u *userUsecase) withTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if u.transactor == nil {
+		return fn(ctx)
+	}
+	return u.transactor.WithTransaction(ctx, fn)
+}
+
+// publish - 未配置 publisher 时 忽略事件
+func (u *userUsecase) publish(ctx context.Context, eventType domain.EventType, userID string, payload map[string]string) error {
+	if u.publisher == nil {
+		return nil
+	}
+	if err := u.publisher.Publish(ctx, eventBody.NewEventBody(eventType, userID, payload)); err != nil {
+		return u.publishFailed(ctx, eventType, err)
+	}
+	return nil
+}
+
+// publishFailed - 在事务中 返回 err 使用户数据一同回滚; 没有事务时 用户数据已经写入,
+// 与 TokensUsecase 一致 只记录日志, 不让已成功的操作返回失败
+func (u *userUsecase) publishFailed(ctx context.Context, eventType domain.EventType, err error) error {
+	if u.transactor != nil {
+		return err
+	}
+	logging.FromContext(ctx).Warn("publish event failed", zap.String("event", string(eventType)), zap.Error(err))
+	return nil
+}
@@ -0,0 +1,421 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/metrics"
+	"github.com/alibug/go-identity-entry/user/password"
+	"github.com/alibug/go-identity-entry/user/repository/body"
+	memrepo "github.com/alibug/go-identity-entry/user/repository/memory"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// passwordRepo - 只实现修改密码用到的方法
+type passwordRepo struct {
+	domain.UserRepository
+	user *body.UserBody
+}
+
+func (r *passwordRepo) GetByID(ctx context.Context, id string) (domain.User, error) {
+	return r.user, nil
+}
+
+func (r *passwordRepo) ChangePassword(ctx context.Context, id string, hash []byte, history [][]byte, changedAt time.Time) error {
+	r.user.CryptPass = hash
+	r.user.PasswordHistory = history
+	r.user.PasswordChangedAt = &changedAt
+	return nil
+}
+
+func TestChangePasswordRejectsReuse(t *testing.T) {
+	hasher := password.New(password.NewBcrypt(4))
+	hash, _ := hasher.Hash("first-pass")
+	repo := &passwordRepo{user: &body.UserBody{ID: "u1", Account: "alice", CryptPass: hash}}
+	uc := NewUserUsecase(repo, time.Second, WithPasswordHasher(hasher), WithPasswordHistory(3))
+	ctx := context.Background()
+
+	// 依次修改为 second, third 后, 历史中保留 second 与 first
+	current := "first-pass"
+	for _, next := range []string{"second-pass", "third-pass"} {
+		if err := uc.ChangePasswordUC(ctx, "u1", current, next); err != nil {
+			t.Fatalf("ChangePasswordUC(%s -> %s): %v", current, next, err)
+		}
+		current = next
+	}
+	if n := len(repo.user.PasswordHistory); n != 2 {
+		t.Fatalf("history size = %d, want 2", n)
+	}
+
+	for _, reused := range []string{"third-pass", "second-pass", "first-pass"} {
+		err := uc.ChangePasswordUC(ctx, "u1", current, reused)
+		if !errors.Is(err, status.ErrBadParamInput) {
+			t.Errorf("reusing %q: err = %v, want ErrBadParamInput", reused, err)
+		}
+	}
+
+	// 第四次修改后 first 超出历史范围, 可以再次使用
+	if err := uc.ChangePasswordUC(ctx, "u1", current, "fourth-pass"); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.ChangePasswordUC(ctx, "u1", "fourth-pass", "first-pass"); err != nil {
+		t.Errorf("first-pass should be allowed again: %v", err)
+	}
+}
+
+// loginRepo - 只实现登录用到的方法, 记录 UpdateCryptPass 的调用
+type loginRepo struct {
+	domain.UserRepository
+	user    *body.UserBody
+	updates [][]byte
+}
+
+func (r *loginRepo) GetByAccount(ctx context.Context, account string) (domain.User, error) {
+	return r.user, nil
+}
+
+func (r *loginRepo) UpdateCryptPass(ctx context.Context, id string, hash []byte) error {
+	r.updates = append(r.updates, hash)
+	return nil
+}
+
+func TestLoginRehashesOutdatedHash(t *testing.T) {
+	ctx := context.Background()
+	oldHash, _ := password.New(password.NewBcrypt(4)).Hash("correct-pass")
+	current := password.New(password.NewArgon2id(1024, 1, 1))
+	currentHash, _ := current.Hash("correct-pass")
+
+	tests := []struct {
+		name     string
+		hash     []byte
+		password string
+		rehashed bool
+	}{
+		{"outdated", oldHash, "correct-pass", true},
+		{"current", currentHash, "correct-pass", false},
+		{"wrong password", oldHash, "wrong-pass", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &loginRepo{user: &body.UserBody{ID: "u1", Account: "alice", CryptPass: tt.hash}}
+			uc := NewUserUsecase(repo, time.Second, WithPasswordHasher(current))
+			uc.CheckAccountAndPassUC(ctx, "alice", tt.password)
+
+			if !tt.rehashed {
+				if len(repo.updates) != 0 {
+					t.Fatalf("UpdateCryptPass called %d times, want 0", len(repo.updates))
+				}
+				return
+			}
+			if len(repo.updates) != 1 {
+				t.Fatalf("UpdateCryptPass called %d times, want 1", len(repo.updates))
+			}
+			// 新哈希使用当前算法与参数, 且仍能校验原密码
+			if current.NeedsRehash(repo.updates[0]) {
+				t.Errorf("new hash %q still needs rehash", repo.updates[0])
+			}
+			if ok, err := current.Verify(repo.updates[0], tt.password); err != nil || !ok {
+				t.Errorf("Verify(new hash) = %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestPasswordExpired(t *testing.T) {
+	uc := NewUserUsecase(nil, time.Second, WithPasswordMaxAge(map[string]time.Duration{
+		"admin":   90 * 24 * time.Hour,
+		"auditor": 30 * 24 * time.Hour,
+	}))
+	daysAgo := func(d int) *time.Time {
+		t := time.Now().Add(-time.Duration(d) * 24 * time.Hour)
+		return &t
+	}
+
+	tests := []struct {
+		name string
+		user *body.UserBody
+		want bool
+	}{
+		{"no rotation role", &body.UserBody{PasswordChangedAt: daysAgo(365)}, false},
+		{"admin within max age", &body.UserBody{Roles: []string{"admin"}, PasswordChangedAt: daysAgo(60)}, false},
+		{"admin past max age", &body.UserBody{Roles: []string{"admin"}, PasswordChangedAt: daysAgo(100)}, true},
+		{"strictest role wins", &body.UserBody{Roles: []string{"admin", "auditor"}, PasswordChangedAt: daysAgo(60)}, true},
+		{"never changed", &body.UserBody{Roles: []string{"admin"}}, true},
+	}
+	for _, tt := range tests {
+		if got := uc.PasswordExpiredUC(tt.user); got != tt.want {
+			t.Errorf("%s: PasswordExpiredUC() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoginMetrics(t *testing.T) {
+	hasher := password.New(password.NewBcrypt(4))
+	uc := NewUserUsecase(memrepo.NewMemoryUserRepository(), time.Second, WithPasswordHasher(hasher))
+	ctx := context.Background()
+	if err := uc.RegisterUserUC(ctx, &body.RegisterBody{Account: "alice", Password: "first-pass", Displayname: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func(result, reason string) float64 {
+		return testutil.ToFloat64(metrics.LoginsTotal.WithLabelValues(result, reason))
+	}
+	success, invalid := count(metrics.ResultSuccess, ""), count(metrics.ResultFailure, "invalid_credentials")
+	uc.CheckAccountAndPassUC(ctx, "alice", "first-pass")
+	uc.CheckAccountAndPassUC(ctx, "alice", "wrong-pass")
+	uc.CheckAccountAndPassUC(ctx, "nobody", "first-pass")
+	if d := count(metrics.ResultSuccess, "") - success; d != 1 {
+		t.Errorf("successful logins = %v, want 1", d)
+	}
+	if d := count(metrics.ResultFailure, "invalid_credentials") - invalid; d != 2 {
+		t.Errorf("invalid_credentials logins = %v, want 2", d)
+	}
+	if n := testutil.CollectAndCount(metrics.PasswordHashDuration, "identity_password_hash_duration_seconds"); n == 0 {
+		t.Error("password hash duration not recorded")
+	}
+}
+
+func TestLoginSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+
+	uc := NewUserUsecase(memrepo.NewMemoryUserRepository(), time.Second, WithPasswordHasher(password.New(password.NewBcrypt(4))))
+	ctx := context.Background()
+	if err := uc.RegisterUserUC(ctx, &body.RegisterBody{Account: "alice", Password: "first-pass", Displayname: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.CheckAccountAndPassUC(ctx, "alice", "first-pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 密码校验是登录 span 的子 span, 可以与存储耗时区分
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		spans[s.Name()] = s
+	}
+	login, verify := spans["userUsecase.CheckAccountAndPassUC"], spans["password.Verify"]
+	if login == nil || verify == nil {
+		t.Fatalf("spans = %v", spans)
+	}
+	if verify.Parent().SpanID() != login.SpanContext().SpanID() {
+		t.Error("password.Verify is not a child of the login span")
+	}
+	if spans["userUsecase.RegisterUserUC"] == nil || spans["password.Hash"] == nil {
+		t.Errorf("register spans missing: %v", spans)
+	}
+}
+
+// failingPublisher - 写入事件总是失败
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, event domain.Event) error {
+	return errors.New("outbox unavailable")
+}
+
+// passThroughTransactor - 直接执行 fn, 返回其 error
+type passThroughTransactor struct{}
+
+func (passThroughTransactor) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestPublishFailure(t *testing.T) {
+	hasher := password.New(password.NewBcrypt(4))
+	ctx := context.Background()
+	register := &body.RegisterBody{Account: "alice", Password: "first-pass", Displayname: "Alice"}
+
+	// 没有事务时 用户已经写入, 事件写入失败只记录日志
+	repo := memrepo.NewMemoryUserRepository()
+	uc := NewUserUsecase(repo, time.Second, WithPasswordHasher(hasher), WithEventPublisher(failingPublisher{}))
+	if err := uc.RegisterUserUC(ctx, register); err != nil {
+		t.Fatalf("RegisterUserUC without transaction = %v, want nil", err)
+	}
+	user, err := repo.GetByAccount(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.ChangePasswordUC(ctx, user.GetUserID(), "first-pass", "second-pass"); err != nil {
+		t.Fatalf("ChangePasswordUC without transaction = %v, want nil", err)
+	}
+
+	// 在事务中 返回 error, 使用户数据一同回滚
+	uc = NewUserUsecase(memrepo.NewMemoryUserRepository(), time.Second, WithPasswordHasher(hasher),
+		WithEventPublisher(failingPublisher{}), WithTransactor(passThroughTransactor{}))
+	if err := uc.RegisterUserUC(ctx, register); err == nil {
+		t.Fatal("RegisterUserUC in transaction = nil, want the publish error")
+	}
+}
+
+// registerAlice - 注册后返回用户ID
+func registerAlice(t *testing.T, uc domain.UserUsecase, repo domain.UserRepository) string {
+	t.Helper()
+	ctx := context.Background()
+	if err := uc.RegisterUserUC(ctx, &body.RegisterBody{Account: "alice", Password: "first-pass", Displayname: "Alice"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := repo.GetByAccount(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user.GetUserID()
+}
+
+func TestScheduleDeletion(t *testing.T) {
+	repo := memrepo.NewMemoryUserRepository()
+	uc := NewUserUsecase(repo, time.Second, WithPasswordHasher(password.New(password.NewBcrypt(4))), WithDeletionGrace(time.Hour))
+	ctx := context.Background()
+	id := registerAlice(t, uc, repo)
+
+	// 密码错误 不安排注销
+	_, err := uc.ScheduleDeletionUC(ctx, id, "wrong-pass")
+	var coded *domain.CodedError
+	if !errors.Is(err, status.ErrUnauthorized) || !errors.As(err, &coded) || coded.Code != domain.CodeInvalidCredentials {
+		t.Fatalf("ScheduleDeletionUC(wrong password) err = %v, want invalid_credentials", err)
+	}
+	if user, _ := repo.GetByID(ctx, id); user.GetDeletionScheduledAt() != nil {
+		t.Fatal("deletion scheduled after a wrong password")
+	}
+
+	before := time.Now()
+	at, err := uc.ScheduleDeletionUC(ctx, id, "first-pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at.Before(before.Add(time.Hour)) || at.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("deletion scheduled at %v, want an hour from now", at)
+	}
+
+	// 再次申请 返回原定时间, 不会推迟
+	again, err := uc.ScheduleDeletionUC(ctx, id, "first-pass")
+	if err != nil || !again.Equal(at) {
+		t.Fatalf("second ScheduleDeletionUC = %v, %v, want %v", again, err, at)
+	}
+	if user, _ := repo.GetByID(ctx, id); user.GetDeletionScheduledAt() == nil || !user.GetDeletionScheduledAt().Equal(at) {
+		t.Fatalf("stored deletion time = %v, want %v", user.GetDeletionScheduledAt(), at)
+	}
+
+	// 宽限期内 不会被清理
+	if n, err := uc.PurgeDeletedUsersUC(ctx); err != nil || n != 0 {
+		t.Fatalf("PurgeDeletedUsersUC before due = %d, %v, want 0", n, err)
+	}
+	if _, err := repo.GetByID(ctx, id); err != nil {
+		t.Fatalf("user purged before the grace period ended: %v", err)
+	}
+}
+
+// recordingPublisher - 记录发布的事件类型
+type recordingPublisher struct {
+	types []domain.EventType
+}
+
+func (r *recordingPublisher) Publish(ctx context.Context, event domain.Event) error {
+	r.types = append(r.types, event.GetType())
+	return nil
+}
+
+func TestCancelDeletion(t *testing.T) {
+	repo := memrepo.NewMemoryUserRepository()
+	publisher := &recordingPublisher{}
+	uc := NewUserUsecase(repo, time.Second, WithPasswordHasher(password.New(password.NewBcrypt(4))),
+		WithDeletionGrace(time.Millisecond), WithEventPublisher(publisher))
+	ctx := context.Background()
+	id := registerAlice(t, uc, repo)
+	if _, err := uc.ScheduleDeletionUC(ctx, id, "first-pass"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 宽限期内 仍可登录
+	user, err := uc.CheckAccountAndPassUC(ctx, "alice", "first-pass")
+	if err != nil || user.GetDeletionScheduledAt() == nil {
+		t.Fatalf("login during grace period = %v, %v", user, err)
+	}
+
+	if err := uc.CancelDeletionUC(ctx, id); err != nil {
+		t.Fatalf("CancelDeletionUC: %v", err)
+	}
+	// 没有待撤销的注销 视为成功, 不再发布事件
+	if err := uc.CancelDeletionUC(ctx, id); err != nil {
+		t.Fatalf("second CancelDeletionUC: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if n, err := uc.PurgeDeletedUsersUC(ctx); err != nil || n != 0 {
+		t.Fatalf("PurgeDeletedUsersUC after cancel = %d, %v, want 0", n, err)
+	}
+	if user, err := repo.GetByID(ctx, id); err != nil || user.GetAccount() != "alice" || user.GetDeletionScheduledAt() != nil {
+		t.Fatalf("user after cancel = %+v, %v", user, err)
+	}
+
+	want := []domain.EventType{domain.EventUserRegistered, domain.EventUserDeletionScheduled, domain.EventUserDeletionCancelled}
+	if len(publisher.types) != len(want) {
+		t.Fatalf("published %v, want %v", publisher.types, want)
+	}
+	for i := range want {
+		if publisher.types[i] != want[i] {
+			t.Fatalf("published %v, want %v", publisher.types, want)
+		}
+	}
+}
+
+func TestPurgeDeletedUsers(t *testing.T) {
+	for _, erase := range []bool{false, true} {
+		repo := memrepo.NewMemoryUserRepository()
+		uc := NewUserUsecase(repo, time.Second, WithPasswordHasher(password.New(password.NewBcrypt(4))),
+			WithDeletionGrace(time.Millisecond), WithEraseOnDelete(erase))
+		ctx := context.Background()
+		id := registerAlice(t, uc, repo)
+		if _, err := uc.ScheduleDeletionUC(ctx, id, "first-pass"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+
+		if n, err := uc.PurgeDeletedUsersUC(ctx); err != nil || n != 1 {
+			t.Fatalf("erase=%v: PurgeDeletedUsersUC = %d, %v, want 1", erase, n, err)
+		}
+		user, err := repo.GetByID(ctx, id)
+		if erase {
+			if !errors.Is(err, status.ErrNotFound) {
+				t.Fatalf("erase=true: GetByID = %v, %v, want ErrNotFound", user, err)
+			}
+		} else {
+			// 匿名化: 保留用户ID, 清除个人信息与密码
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.GetAccount() == "alice" || user.GetDisplayName() != "" || len(user.GetCryptPass()) != 0 || user.GetDeletionScheduledAt() != nil {
+				t.Fatalf("erase=false: user not anonymized: %+v", user)
+			}
+		}
+		// 账号已释放, 原账号无法登录
+		if _, err := uc.CheckAccountAndPassUC(ctx, "alice", "first-pass"); err == nil {
+			t.Fatalf("erase=%v: purged account can still log in", erase)
+		}
+		// 已清理的用户 不会再次处理
+		if n, err := uc.PurgeDeletedUsersUC(ctx); err != nil || n != 0 {
+			t.Fatalf("erase=%v: second PurgeDeletedUsersUC = %d, %v, want 0", erase, n, err)
+		}
+	}
+}
+
+func TestScheduleDeletionWithoutGrace(t *testing.T) {
+	repo := memrepo.NewMemoryUserRepository()
+	uc := NewUserUsecase(repo, time.Second, WithPasswordHasher(password.New(password.NewBcrypt(4))), WithEraseOnDelete(true))
+	ctx := context.Background()
+	id := registerAlice(t, uc, repo)
+
+	// 没有宽限期 立即删除
+	if _, err := uc.ScheduleDeletionUC(ctx, id, "first-pass"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetByID(ctx, id); !errors.Is(err, status.ErrNotFound) {
+		t.Fatalf("GetByID after immediate deletion err = %v, want ErrNotFound", err)
+	}
+}
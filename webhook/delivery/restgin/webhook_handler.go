@@ -0,0 +1,97 @@
+package restgin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/alibug/go-identity-entry/apierror"
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandler represent the httphandler for webhooks
+type WebhookHandler struct {
+	webhookUsecase domain.WebhookUsecase
+}
+
+// NewWebhookHandler - 在给定的 (已鉴权的) 路由组上 注册 webhook 管理接口
+func NewWebhookHandler(route gin.IRoutes, wuc domain.WebhookUsecase) {
+	handler := &WebhookHandler{
+		webhookUsecase: wuc,
+	}
+
+	route.POST("/webhooks", handler.Register)
+	route.GET("/webhooks", handler.List)
+	route.DELETE("/webhooks/:id", handler.Delete)
+	route.GET("/webhook-deliveries/dead", handler.ListDead)
+	route.POST("/webhook-deliveries/:id/replay", handler.Replay)
+}
+
+// Register - 注册 webhook, 签名密钥只在此时返回
+func (w *WebhookHandler) Register(c *gin.Context) {
+	var req body.RegisterWebhookBody
+	if err := c.ShouldBind(&req); err != nil {
+		apierror.Respond(c, apierror.Binding(err))
+		return
+	}
+
+	ctx := c.Request.Context()
+	webhook, err := w.webhookUsecase.RegisterWebhookUC(ctx, req.URL, req.Events)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// List - 列出所有 webhook, 不返回签名密钥
+func (w *WebhookHandler) List(c *gin.Context) {
+	ctx := c.Request.Context()
+	webhooks, err := w.webhookUsecase.ListWebhooksUC(ctx)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+
+	items := make([]body.WebhookBody, 0, len(webhooks))
+	for _, wh := range webhooks {
+		items = append(items, body.WebhookBody{ID: wh.GetWebhookID(), URL: wh.GetURL(), Events: wh.GetEvents(), CreatedAt: wh.GetCreatedAt()})
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// Delete - 删除 webhook
+func (w *WebhookHandler) Delete(c *gin.Context) {
+	ctx := c.Request.Context()
+	err := w.webhookUsecase.DeleteWebhookUC(ctx, c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// ListDead - 列出死信列表
+func (w *WebhookHandler) ListDead(c *gin.Context) {
+	limit, _ := strconv.ParseInt(c.Query("limit"), 10, 64)
+
+	ctx := c.Request.Context()
+	deliveries, err := w.webhookUsecase.ListDeadDeliveriesUC(ctx, limit)
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": deliveries})
+}
+
+// Replay - 重新投递
+func (w *WebhookHandler) Replay(c *gin.Context) {
+	ctx := c.Request.Context()
+	err := w.webhookUsecase.ReplayDeliveryUC(ctx, c.Param("id"))
+	if err != nil {
+		apierror.Respond(c, err)
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"ok": true})
+}
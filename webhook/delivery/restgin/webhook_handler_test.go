@@ -0,0 +1,190 @@
+package restgin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/apierror"
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/delivery/restgin"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	"github.com/alibug/go-identity-entry/webhook/usecase"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/gin-gonic/gin"
+)
+
+// stubRepository - 只实现管理接口用到的方法
+type stubRepository struct {
+	domain.WebhookRepository
+	mu         sync.Mutex
+	webhooks   []domain.Webhook
+	deliveries map[string]*body.DeliveryBody
+}
+
+func (s *stubRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.webhooks = append(s.webhooks, webhook)
+	return nil
+}
+
+func (s *stubRepository) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]domain.Webhook(nil), s.webhooks...), nil
+}
+
+func (s *stubRepository) DeleteWebhook(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, w := range s.webhooks {
+		if w.GetWebhookID() == id {
+			s.webhooks = append(s.webhooks[:i], s.webhooks[i+1:]...)
+			return nil
+		}
+	}
+	return status.ErrNotFound
+}
+
+func (s *stubRepository) ListDeadDeliveries(ctx context.Context, limit int64) ([]domain.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for _, d := range s.deliveries {
+		if d.Status == domain.DeliveryDead {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries, nil
+}
+
+func (s *stubRepository) ReplayDelivery(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.deliveries[id]
+	if !ok {
+		return status.ErrNotFound
+	}
+	d.Status, d.Attempts = domain.DeliveryPending, 0
+	return nil
+}
+
+// newEngine - webhook 管理接口挂在 /admin 下, 鉴权由调用方的路由组负责, 此处省略
+func newEngine(t *testing.T) (*gin.Engine, *stubRepository) {
+	gin.SetMode(gin.TestMode)
+	repo := &stubRepository{deliveries: map[string]*body.DeliveryBody{
+		"d1": {ID: "d1", WebhookID: "wh", EventType: domain.EventUserRegistered, Attempts: 8, Status: domain.DeliveryDead},
+	}}
+	engine := gin.New()
+	engine.Use(apierror.Middleware())
+	restgin.NewWebhookHandler(engine.Group("/admin"), usecase.NewWebhookUsecase(repo, time.Second))
+	return engine, repo
+}
+
+func do(t *testing.T, engine *gin.Engine, method, path, reqBody string, out interface{}) int {
+	t.Helper()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(method, path, strings.NewReader(reqBody))
+	if reqBody != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	engine.ServeHTTP(w, req)
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("decode %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+func TestRegisterWebhook(t *testing.T) {
+	engine, _ := newEngine(t)
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		code   domain.ErrorCode
+		field  string
+	}{
+		{"Valid", `{"url":"https://example.com/hook","events":["UserRegistered","PasswordChanged"]}`, http.StatusCreated, "", ""},
+		{"AllEvents", `{"url":"https://example.com/hook"}`, http.StatusCreated, "", ""},
+		{"BadURL", `{"url":"not a url"}`, http.StatusBadRequest, domain.CodeValidationFailed, "url"},
+		{"UnknownEvent", `{"url":"https://example.com/hook","events":["UserRegistered","UserDeleted"]}`, http.StatusBadRequest, domain.CodeValidationFailed, "events[1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var resp struct {
+				body.WebhookBody
+				apierror.Body
+			}
+			code := do(t, engine, http.MethodPost, "/admin/webhooks", tt.body, &resp)
+			if code != tt.status {
+				t.Fatalf("status %d, want %d", code, tt.status)
+			}
+			if tt.code == "" {
+				if resp.ID == "" || resp.Secret == "" {
+					t.Fatalf("registered webhook = %+v, want id and secret", resp.WebhookBody)
+				}
+				return
+			}
+			if resp.Code != tt.code || len(resp.Details) != 1 || resp.Details[0].Field != tt.field {
+				t.Fatalf("error = %+v, want %s on %s", resp.Body, tt.code, tt.field)
+			}
+		})
+	}
+}
+
+func TestListWebhooksHidesSecret(t *testing.T) {
+	engine, repo := newEngine(t)
+	repo.CreateWebhook(context.Background(), &body.WebhookBody{ID: "wh", URL: "https://example.com/hook", Secret: "s3cret"})
+
+	var resp struct {
+		Items []map[string]interface{} `json:"items"`
+	}
+	if code := do(t, engine, http.MethodGet, "/admin/webhooks", "", &resp); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if len(resp.Items) != 1 || resp.Items[0]["id"] != "wh" {
+		t.Fatalf("items = %v", resp.Items)
+	}
+	if _, ok := resp.Items[0]["secret"]; ok {
+		t.Fatalf("list leaked the signing secret: %v", resp.Items[0])
+	}
+
+	if code := do(t, engine, http.MethodDelete, "/admin/webhooks/wh", "", nil); code != http.StatusOK {
+		t.Fatalf("delete status %d", code)
+	}
+	if code := do(t, engine, http.MethodDelete, "/admin/webhooks/wh", "", nil); code != http.StatusNotFound {
+		t.Fatalf("delete missing status %d, want 404", code)
+	}
+}
+
+func TestReplayDeadDelivery(t *testing.T) {
+	engine, repo := newEngine(t)
+
+	var dead struct {
+		Items []body.DeliveryBody `json:"items"`
+	}
+	if code := do(t, engine, http.MethodGet, "/admin/webhook-deliveries/dead", "", &dead); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if len(dead.Items) != 1 || dead.Items[0].ID != "d1" || dead.Items[0].Attempts != 8 {
+		t.Fatalf("dead deliveries = %+v", dead.Items)
+	}
+
+	if code := do(t, engine, http.MethodPost, "/admin/webhook-deliveries/d1/replay", "", nil); code != http.StatusAccepted {
+		t.Fatalf("replay status %d, want 202", code)
+	}
+	if d := repo.deliveries["d1"]; d.Status != domain.DeliveryPending || d.Attempts != 0 {
+		t.Fatalf("replayed delivery = %s after %d attempts, want pending after 0", d.Status, d.Attempts)
+	}
+	if code := do(t, engine, http.MethodPost, "/admin/webhook-deliveries/missing/replay", "", nil); code != http.StatusNotFound {
+		t.Fatalf("replay missing status %d, want 404", code)
+	}
+}
@@ -0,0 +1,58 @@
+package body
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// DeliveryBody - implement domain.WebhookDelivery
+type DeliveryBody struct {
+	ID            string                       `bson:"_id" json:"id"`
+	WebhookID     string                       `bson:"webhook_id" json:"webhook_id"`
+	EventID       string                       `bson:"event_id" json:"event_id"`
+	EventType     domain.EventType             `bson:"event_type" json:"event_type"`
+	Payload       []byte                       `bson:"payload" json:"-"`
+	Attempts      int                          `bson:"attempts" json:"attempts"`
+	Status        domain.WebhookDeliveryStatus `bson:"status" json:"status"`
+	LastError     string                       `bson:"last_error,omitempty" json:"last_error,omitempty"`
+	NextAttemptAt time.Time                    `bson:"next_attempt_at" json:"next_attempt_at"`
+	LeaseUntil    *time.Time                   `bson:"lease_until,omitempty" json:"-"`
+	CreatedAt     time.Time                    `bson:"created_at" json:"created_at"`
+	UpdatedAt     *time.Time                   `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// GetDeliveryID - implement domain.WebhookDelivery
+func (d *DeliveryBody) GetDeliveryID() string {
+	return d.ID
+}
+
+// GetWebhookID - implement domain.WebhookDelivery
+func (d *DeliveryBody) GetWebhookID() string {
+	return d.WebhookID
+}
+
+// GetEventID - implement domain.WebhookDelivery
+func (d *DeliveryBody) GetEventID() string {
+	return d.EventID
+}
+
+// GetEventType - implement domain.WebhookDelivery
+func (d *DeliveryBody) GetEventType() domain.EventType {
+	return d.EventType
+}
+
+// GetPayload - implement domain.WebhookDelivery
+func (d *DeliveryBody) GetPayload() []byte {
+	return d.Payload
+}
+
+// GetAttempts - implement domain.WebhookDelivery
+func (d *DeliveryBody) GetAttempts() int {
+	return d.Attempts
+}
+
+// GetStatus - implement domain.WebhookDelivery
+func (d *DeliveryBody) GetStatus() domain.WebhookDeliveryStatus {
+	return d.Status
+}
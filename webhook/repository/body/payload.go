@@ -0,0 +1,16 @@
+package body
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// PayloadBody - POST 给 webhook 的 JSON 内容
+type PayloadBody struct {
+	ID         string            `json:"id"`
+	Type       domain.EventType  `json:"type"`
+	UserID     string            `json:"user_id,omitempty"`
+	Data       map[string]string `json:"data,omitempty"`
+	OccurredAt time.Time         `json:"occurred_at"`
+}
@@ -0,0 +1,9 @@
+package body
+
+import "github.com/alibug/go-identity-entry/domain"
+
+// RegisterWebhookBody - 注册 webhook 的请求内容
+type RegisterWebhookBody struct {
+	URL    string             `json:"url" binding:"required,url"`
+	Events []domain.EventType `json:"events"`
+}
@@ -0,0 +1,41 @@
+package body
+
+import (
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+)
+
+// WebhookBody - implement domain.Webhook
+type WebhookBody struct {
+	ID        string             `bson:"_id" json:"id"`
+	URL       string             `bson:"url" json:"url"`
+	Secret    string             `bson:"secret" json:"secret,omitempty"`
+	Events    []domain.EventType `bson:"events,omitempty" json:"events,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// GetWebhookID - implement domain.Webhook
+func (w *WebhookBody) GetWebhookID() string {
+	return w.ID
+}
+
+// GetURL - implement domain.Webhook
+func (w *WebhookBody) GetURL() string {
+	return w.URL
+}
+
+// GetSecret - implement domain.Webhook
+func (w *WebhookBody) GetSecret() string {
+	return w.Secret
+}
+
+// GetEvents - implement domain.Webhook
+func (w *WebhookBody) GetEvents() []domain.EventType {
+	return w.Events
+}
+
+// GetCreatedAt - implement domain.Webhook
+func (w *WebhookBody) GetCreatedAt() time.Time {
+	return w.CreatedAt
+}
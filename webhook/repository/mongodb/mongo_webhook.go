@@ -0,0 +1,170 @@
+package mongorepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoWebhookRepository struct {
+	webhookColl  *mongo.Collection
+	deliveryColl *mongo.Collection
+}
+
+// NewMongoWebhookRepository will create an object that represent the domain.WebhookRepository interface
+func NewMongoWebhookRepository(webhookColl *mongo.Collection, deliveryColl *mongo.Collection) domain.WebhookRepository {
+	return &mongoWebhookRepository{webhookColl, deliveryColl}
+}
+
+func (m *mongoWebhookRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	_, err := m.webhookColl.InsertOne(ctx, webhook)
+	if mongo.IsDuplicateKeyError(err) {
+		return status.ErrConflict
+	}
+	return err
+}
+
+func (m *mongoWebhookRepository) GetWebhook(ctx context.Context, id string) (domain.Webhook, error) {
+	var w body.WebhookBody
+	err := m.webhookColl.FindOne(ctx, bson.M{"_id": id}).Decode(&w)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+func (m *mongoWebhookRepository) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	cur, err := m.webhookColl.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	webhooks := make([]domain.Webhook, 0)
+	for cur.Next(ctx) {
+		var w body.WebhookBody
+		if err := cur.Decode(&w); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, &w)
+	}
+	return webhooks, cur.Err()
+}
+
+func (m *mongoWebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	res, err := m.webhookColl.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoWebhookRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	_, err := m.deliveryColl.InsertOne(ctx, delivery)
+	if mongo.IsDuplicateKeyError(err) {
+		return status.ErrConflict
+	}
+	return err
+}
+
+// ClaimDueDelivery - FindOneAndUpdate 在单个文档上是原子的, 同一条记录只会被一个实例领取;
+// 投递中的实例崩溃后 记录在租约过期时 重新被领取
+func (m *mongoWebhookRepository) ClaimDueDelivery(ctx context.Context, now time.Time, leaseUntil time.Time) (domain.WebhookDelivery, error) {
+	filter := bson.M{"$or": bson.A{
+		bson.M{"status": domain.DeliveryPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"status": domain.DeliveryInFlight, "lease_until": bson.M{"$lte": now}},
+	}}
+	update := bson.M{"$set": bson.M{
+		"status":      domain.DeliveryInFlight,
+		"lease_until": leaseUntil,
+		"updated_at":  now,
+	}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var d body.DeliveryBody
+	err := m.deliveryColl.FindOneAndUpdate(ctx, filter, update, opts).Decode(&d)
+	if err == mongo.ErrNoDocuments {
+		return nil, status.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (m *mongoWebhookRepository) ListDeadDeliveries(ctx context.Context, limit int64) ([]domain.WebhookDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}).SetLimit(limit)
+	return m.findDeliveries(ctx, bson.M{"status": domain.DeliveryDead}, opts)
+}
+
+func (m *mongoWebhookRepository) MarkDeliverySucceeded(ctx context.Context, id string, attempts int) error {
+	return m.updateDelivery(ctx, id, bson.M{
+		"status":   domain.DeliverySucceeded,
+		"attempts": attempts,
+	})
+}
+
+func (m *mongoWebhookRepository) MarkDeliveryFailed(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+	s := domain.DeliveryPending
+	if dead {
+		s = domain.DeliveryDead
+	}
+	return m.updateDelivery(ctx, id, bson.M{
+		"status":          s,
+		"attempts":        attempts,
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      lastError,
+	})
+}
+
+func (m *mongoWebhookRepository) ReplayDelivery(ctx context.Context, id string) error {
+	return m.updateDelivery(ctx, id, bson.M{
+		"status":          domain.DeliveryPending,
+		"attempts":        0,
+		"next_attempt_at": time.Now(),
+	})
+}
+
+func (m *mongoWebhookRepository) updateDelivery(ctx context.Context, id string, set bson.M) error {
+	set["updated_at"] = time.Now()
+	res, err := m.deliveryColl.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return status.ErrNotFound
+	}
+	return nil
+}
+
+func (m *mongoWebhookRepository) findDeliveries(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]domain.WebhookDelivery, error) {
+	cur, err := m.deliveryColl.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for cur.Next(ctx) {
+		var d body.DeliveryBody
+		if err := cur.Decode(&d); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, &d)
+	}
+	return deliveries, cur.Err()
+}
@@ -0,0 +1,130 @@
+package mongorepo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	mongorepo "github.com/alibug/go-identity-entry/webhook/repository/mongodb"
+	"github.com/alibug/go-identity-utils/status"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// 不需要 MongoDB: 使用驱动自带的 mock 部署, 检查发送的命令
+func newMock(t *testing.T) *mtest.T {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	t.Cleanup(mt.Close)
+	return mt
+}
+
+func TestClaimDueDelivery(t *testing.T) {
+	mt := newMock(t)
+
+	mt.Run("claimed", func(mt *mtest.T) {
+		repo := mongorepo.NewMongoWebhookRepository(mt.Coll, mt.Coll)
+		now := time.Now()
+		lease := now.Add(time.Minute)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: "d1"},
+			{Key: "webhook_id", Value: "wh"},
+			{Key: "attempts", Value: 2},
+			{Key: "status", Value: string(domain.DeliveryInFlight)},
+		}}))
+
+		d, err := repo.ClaimDueDelivery(context.Background(), now, lease)
+		if err != nil || d.GetDeliveryID() != "d1" || d.GetAttempts() != 2 || d.GetStatus() != domain.DeliveryInFlight {
+			t.Fatalf("ClaimDueDelivery = %+v, %v", d, err)
+		}
+
+		// 领取与置为投递中 必须是同一条 findAndModify 命令, 否则多个实例会领取到同一条
+		evt := mt.GetStartedEvent()
+		if evt.CommandName != "findAndModify" {
+			t.Fatalf("command = %s, want findAndModify", evt.CommandName)
+		}
+		cmd := evt.Command
+		or, ok := cmd.Lookup("query", "$or").ArrayOK()
+		if !ok {
+			t.Fatalf("query without $or: %v", cmd.Lookup("query"))
+		}
+		values, _ := or.Values()
+		if len(values) != 2 ||
+			values[0].Document().Lookup("status").StringValue() != string(domain.DeliveryPending) ||
+			values[1].Document().Lookup("status").StringValue() != string(domain.DeliveryInFlight) {
+			t.Errorf("query = %v, want due pending or expired in_flight", or)
+		}
+		if s := cmd.Lookup("update", "$set", "status").StringValue(); s != string(domain.DeliveryInFlight) {
+			t.Errorf("update status = %q, want in_flight", s)
+		}
+		if got := cmd.Lookup("update", "$set", "lease_until").Time(); !got.Equal(lease.Truncate(time.Millisecond)) {
+			t.Errorf("lease_until = %v, want %v", got, lease)
+		}
+		if !cmd.Lookup("new").Boolean() {
+			t.Error("findAndModify should return the updated document")
+		}
+	})
+
+	mt.Run("none due", func(mt *mtest.T) {
+		repo := mongorepo.NewMongoWebhookRepository(mt.Coll, mt.Coll)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		if _, err := repo.ClaimDueDelivery(context.Background(), time.Now(), time.Now()); !errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("ClaimDueDelivery err = %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestWebhookRepositoryErrors(t *testing.T) {
+	mt := newMock(t)
+
+	mt.Run("duplicate webhook", func(mt *mtest.T) {
+		repo := mongorepo.NewMongoWebhookRepository(mt.Coll, mt.Coll)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "duplicate key"}))
+
+		err := repo.CreateWebhook(context.Background(), &body.WebhookBody{ID: "wh", URL: "https://example.com"})
+		if !errors.Is(err, status.ErrConflict) {
+			t.Fatalf("CreateWebhook err = %v, want ErrConflict", err)
+		}
+	})
+
+	mt.Run("missing webhook", func(mt *mtest.T) {
+		repo := mongorepo.NewMongoWebhookRepository(mt.Coll, mt.Coll)
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.Coll.Database().Name()+"."+mt.Coll.Name(), mtest.FirstBatch))
+
+		if _, err := repo.GetWebhook(context.Background(), "missing"); !errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("GetWebhook err = %v, want ErrNotFound", err)
+		}
+	})
+
+	mt.Run("replay missing delivery", func(mt *mtest.T) {
+		repo := mongorepo.NewMongoWebhookRepository(mt.Coll, mt.Coll)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		if err := repo.ReplayDelivery(context.Background(), "missing"); !errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("ReplayDelivery err = %v, want ErrNotFound", err)
+		}
+		cmd := mt.GetStartedEvent().Command
+		update := cmd.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+		if s := update.Lookup("status").StringValue(); s != string(domain.DeliveryPending) {
+			t.Errorf("replay status = %q, want pending", s)
+		}
+		if n := update.Lookup("attempts").Int32(); n != 0 {
+			t.Errorf("replay attempts = %d, want 0", n)
+		}
+	})
+
+	mt.Run("server error", func(mt *mtest.T) {
+		repo := mongorepo.NewMongoWebhookRepository(mt.Coll, mt.Coll)
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}))
+
+		_, err := repo.ClaimDueDelivery(context.Background(), time.Now(), time.Now())
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || errors.Is(err, status.ErrNotFound) {
+			t.Fatalf("ClaimDueDelivery err = %v, want the command error", err)
+		}
+	})
+}
@@ -0,0 +1,181 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/logging"
+	"github.com/alibug/go-identity-utils/status"
+	"go.uber.org/zap"
+)
+
+const (
+	// SignatureHeader - TimestampHeader 与请求体的 HMAC-SHA256 签名, 格式为 sha256=<hex>, 见 Sign
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader - 发送时间, Unix 秒; 接收方应拒绝 与当前时间相差超过 SignatureTolerance 的请求
+	TimestampHeader = "X-Webhook-Timestamp"
+	// EventHeader - 事件类型
+	EventHeader = "X-Webhook-Event"
+	// DeliveryHeader - 投递ID, 接收方可用于去重
+	DeliveryHeader = "X-Webhook-Delivery"
+
+	// SignatureTolerance - 接收方允许的时间偏差, 超出时视为重放的请求
+	SignatureTolerance = 5 * time.Minute
+
+	// dueBatchSize - 每次处理的投递条数
+	dueBatchSize = 50
+	// deliveryLease - 领取一条记录后 持有的租约; postTimeout 须小于它, 以免投递未结束 租约已过期被重复领取
+	deliveryLease = time.Minute
+	postTimeout   = deliveryLease / 2
+	// maxBackoff - 重试间隔上限
+	maxBackoff = time.Hour
+)
+
+// ErrInvalidSignature - VerifySignature 校验失败
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// DeliveryWorker - 投递 webhook, 失败时按指数退避重试, 重试用尽后进入死信列表
+type DeliveryWorker struct {
+	webhookRepo domain.WebhookRepository
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+}
+
+// NewDeliveryWorker - backoff 为第一次重试的间隔, 之后每次翻倍
+func NewDeliveryWorker(repo domain.WebhookRepository, client *http.Client, maxAttempts int, backoff time.Duration) *DeliveryWorker {
+	return &DeliveryWorker{
+		webhookRepo: repo,
+		client:      client,
+		maxAttempts: maxAttempts,
+		backoff:     backoff,
+	}
+}
+
+// Sign - 计算 timestamp + "." + payload 的签名; 签名包含时间, 截获的请求 超过 SignatureTolerance 后无法重放
+func Sign(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature - 接收方校验 SignatureHeader 与 TimestampHeader, 时间与 now 相差超过 SignatureTolerance 时失败
+func VerifySignature(secret string, signature string, timestamp string, payload []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > SignatureTolerance || skew < -SignatureTolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, ts, payload))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// DeliverDue - 逐条领取并投递 本轮开始时已到期的记录, 最多 dueBatchSize 条, 返回处理的条数;
+// 本轮中失败并立即到期的记录 留到下一轮
+func (w *DeliveryWorker) DeliverDue(ctx context.Context) (int, error) {
+	start := time.Now()
+	for n := 0; n < dueBatchSize; n++ {
+		d, err := w.webhookRepo.ClaimDueDelivery(ctx, start, time.Now().Add(deliveryLease))
+		if errors.Is(err, status.ErrNotFound) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		if err := w.deliver(ctx, d); err != nil {
+			return n, err
+		}
+	}
+	return dueBatchSize, nil
+}
+
+// Run - 按 interval 轮询待投递记录, 直到 ctx 结束
+func (w *DeliveryWorker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.DeliverDue(ctx); err != nil {
+				logging.FromContext(ctx).Error("deliver webhooks failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// deliver - 只有更新投递记录失败时 才返回 error
+func (w *DeliveryWorker) deliver(ctx context.Context, d domain.WebhookDelivery) error {
+	attempts := d.GetAttempts() + 1
+
+	webhook, err := w.webhookRepo.GetWebhook(ctx, d.GetWebhookID())
+	if errors.Is(err, status.ErrNotFound) {
+		return w.webhookRepo.MarkDeliveryFailed(ctx, d.GetDeliveryID(), attempts, time.Now(), "webhook deleted", true)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = w.post(ctx, webhook, d)
+	if err == nil {
+		return w.webhookRepo.MarkDeliverySucceeded(ctx, d.GetDeliveryID(), attempts)
+	}
+
+	dead := attempts >= w.maxAttempts
+	return w.webhookRepo.MarkDeliveryFailed(ctx, d.GetDeliveryID(), attempts, time.Now().Add(w.nextBackoff(attempts)), err.Error(), dead)
+}
+
+func (w *DeliveryWorker) post(ctx context.Context, webhook domain.Webhook, d domain.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(ctx, postTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.GetURL(), bytes.NewReader(d.GetPayload()))
+	if err != nil {
+		return err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(webhook.GetSecret(), timestamp, d.GetPayload()))
+	req.Header.Set(EventHeader, string(d.GetEventType()))
+	req.Header.Set(DeliveryHeader, d.GetDeliveryID())
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// nextBackoff - 第 n 次失败后的等待时间: backoff * 2^(n-1), 不超过 maxBackoff
+func (w *DeliveryWorker) nextBackoff(attempts int) time.Duration {
+	delay := w.backoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,324 @@
+package usecase
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+// fakeWebhookRepository - 内存中的 domain.WebhookRepository, 仅用于测试
+type fakeWebhookRepository struct {
+	mu         sync.Mutex
+	webhooks   map[string]*body.WebhookBody
+	deliveries map[string]*body.DeliveryBody
+}
+
+func newFakeWebhookRepository() *fakeWebhookRepository {
+	return &fakeWebhookRepository{
+		webhooks:   map[string]*body.WebhookBody{},
+		deliveries: map[string]*body.DeliveryBody{},
+	}
+}
+
+func (f *fakeWebhookRepository) CreateWebhook(ctx context.Context, webhook domain.Webhook) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.webhooks[webhook.GetWebhookID()] = webhook.(*body.WebhookBody)
+	return nil
+}
+
+func (f *fakeWebhookRepository) GetWebhook(ctx context.Context, id string) (domain.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w, ok := f.webhooks[id]
+	if !ok {
+		return nil, status.ErrNotFound
+	}
+	return w, nil
+}
+
+func (f *fakeWebhookRepository) ListWebhooks(ctx context.Context) ([]domain.Webhook, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var webhooks []domain.Webhook
+	for _, w := range f.webhooks {
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, nil
+}
+
+func (f *fakeWebhookRepository) DeleteWebhook(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.webhooks, id)
+	return nil
+}
+
+func (f *fakeWebhookRepository) CreateDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.deliveries[delivery.GetDeliveryID()]; ok {
+		return status.ErrConflict
+	}
+	f.deliveries[delivery.GetDeliveryID()] = delivery.(*body.DeliveryBody)
+	return nil
+}
+
+func (f *fakeWebhookRepository) ClaimDueDelivery(ctx context.Context, now time.Time, leaseUntil time.Time) (domain.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.deliveries {
+		due := d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now)
+		expired := d.Status == domain.DeliveryInFlight && d.LeaseUntil != nil && !d.LeaseUntil.After(now)
+		if due || expired {
+			d.Status = domain.DeliveryInFlight
+			d.LeaseUntil = &leaseUntil
+			copied := *d
+			return &copied, nil
+		}
+	}
+	return nil, status.ErrNotFound
+}
+
+func (f *fakeWebhookRepository) ListDeadDeliveries(ctx context.Context, limit int64) ([]domain.WebhookDelivery, error) {
+	return f.list(func(d *body.DeliveryBody) bool { return d.Status == domain.DeliveryDead }), nil
+}
+
+func (f *fakeWebhookRepository) MarkDeliverySucceeded(ctx context.Context, id string, attempts int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deliveries[id].Status = domain.DeliverySucceeded
+	f.deliveries[id].Attempts = attempts
+	return nil
+}
+
+func (f *fakeWebhookRepository) MarkDeliveryFailed(ctx context.Context, id string, attempts int, nextAttemptAt time.Time, lastError string, dead bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d := f.deliveries[id]
+	d.Attempts = attempts
+	d.NextAttemptAt = nextAttemptAt
+	d.LastError = lastError
+	d.Status = domain.DeliveryPending
+	if dead {
+		d.Status = domain.DeliveryDead
+	}
+	return nil
+}
+
+func (f *fakeWebhookRepository) ReplayDelivery(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.deliveries[id]
+	if !ok {
+		return status.ErrNotFound
+	}
+	d.Status = domain.DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = time.Now()
+	return nil
+}
+
+func (f *fakeWebhookRepository) list(match func(d *body.DeliveryBody) bool) []domain.WebhookDelivery {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var deliveries []domain.WebhookDelivery
+	for _, d := range f.deliveries {
+		if match(d) {
+			copied := *d
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	return deliveries
+}
+
+func (f *fakeWebhookRepository) delivery(id string) body.DeliveryBody {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return *f.deliveries[id]
+}
+
+func seedDelivery(t *testing.T, repo *fakeWebhookRepository, url string) {
+	t.Helper()
+	ctx := context.Background()
+	repo.CreateWebhook(ctx, &body.WebhookBody{ID: "wh", URL: url, Secret: "s3cret"})
+	repo.CreateDelivery(ctx, &body.DeliveryBody{
+		ID:            "d1",
+		WebhookID:     "wh",
+		EventID:       "e1",
+		EventType:     domain.EventUserRegistered,
+		Payload:       []byte(`{"id":"e1"}`),
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+func TestDeliverDueSignsPayload(t *testing.T) {
+	var gotSignature, gotTimestamp, gotBody, gotDelivery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		gotSignature = r.Header.Get(SignatureHeader)
+		gotTimestamp = r.Header.Get(TimestampHeader)
+		gotDelivery = r.Header.Get(DeliveryHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepository()
+	seedDelivery(t, repo, server.URL)
+	worker := NewDeliveryWorker(repo, server.Client(), 3, time.Second)
+
+	n, err := worker.DeliverDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DeliverDue() = %d, %v; want 1, nil", n, err)
+	}
+	if gotBody != `{"id":"e1"}` {
+		t.Errorf("body = %q", gotBody)
+	}
+	if err := VerifySignature("s3cret", gotSignature, gotTimestamp, []byte(gotBody), time.Now()); err != nil {
+		t.Errorf("VerifySignature(%q, %q) = %v", gotSignature, gotTimestamp, err)
+	}
+	// 签名包含时间: 超出容忍范围后 同一请求不能重放
+	if err := VerifySignature("s3cret", gotSignature, gotTimestamp, []byte(gotBody), time.Now().Add(SignatureTolerance+time.Minute)); err != ErrInvalidSignature {
+		t.Errorf("VerifySignature after tolerance = %v, want ErrInvalidSignature", err)
+	}
+	if err := VerifySignature("s3cret", gotSignature, gotTimestamp, []byte(`{"id":"e2"}`), time.Now()); err != ErrInvalidSignature {
+		t.Errorf("VerifySignature(other body) = %v, want ErrInvalidSignature", err)
+	}
+	if gotDelivery != "d1" {
+		t.Errorf("delivery header = %q, want d1", gotDelivery)
+	}
+	if d := repo.delivery("d1"); d.Status != domain.DeliverySucceeded || d.Attempts != 1 {
+		t.Errorf("delivery = %s after %d attempts, want succeeded after 1", d.Status, d.Attempts)
+	}
+}
+
+func TestDeliverDueRetriesThenDeadLetters(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepository()
+	seedDelivery(t, repo, server.URL)
+	// 退避为 0, 每次失败后立即到期
+	worker := NewDeliveryWorker(repo, server.Client(), 3, 0)
+
+	for i := 1; i <= 3; i++ {
+		if _, err := worker.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("DeliverDue() #%d: %v", i, err)
+		}
+		d := repo.delivery("d1")
+		if d.Attempts != i {
+			t.Fatalf("attempts = %d, want %d", d.Attempts, i)
+		}
+		wantStatus := domain.DeliveryPending
+		if i == 3 {
+			wantStatus = domain.DeliveryDead
+		}
+		if d.Status != wantStatus {
+			t.Fatalf("status after %d attempts = %s, want %s", i, d.Status, wantStatus)
+		}
+	}
+
+	// 死信不再投递
+	if n, _ := worker.DeliverDue(context.Background()); n != 0 || calls != 3 {
+		t.Fatalf("dead delivery was retried: n=%d calls=%d", n, calls)
+	}
+
+	// replay 后重新投递
+	if err := NewWebhookUsecase(repo, time.Second).ReplayDeliveryUC(context.Background(), "d1"); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := worker.DeliverDue(context.Background()); n != 1 || calls != 4 {
+		t.Fatalf("replayed delivery not sent: n=%d calls=%d", n, calls)
+	}
+}
+
+// 多个实例同时处理时 每条记录只投递一次
+func TestDeliverDueClaimsEachDeliveryOnce(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepository()
+	seedDelivery(t, repo, server.URL)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := NewDeliveryWorker(repo, server.Client(), 3, time.Second).DeliverDue(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("delivery sent %d times, want 1", calls)
+	}
+	if d := repo.delivery("d1"); d.Status != domain.DeliverySucceeded || d.Attempts != 1 {
+		t.Fatalf("delivery = %s after %d attempts, want succeeded after 1", d.Status, d.Attempts)
+	}
+}
+
+// 领取后未完成投递 (如实例崩溃) 的记录, 租约过期后被重新领取
+func TestDeliverDueReclaimsExpiredLease(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := newFakeWebhookRepository()
+	seedDelivery(t, repo, server.URL)
+	now := time.Now()
+	if _, err := repo.ClaimDueDelivery(context.Background(), now, now.Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := NewDeliveryWorker(repo, server.Client(), 3, time.Second).DeliverDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DeliverDue() = %d, %v; want 1, nil", n, err)
+	}
+	if d := repo.delivery("d1"); d.Status != domain.DeliverySucceeded {
+		t.Fatalf("delivery status = %s, want succeeded", d.Status)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	worker := NewDeliveryWorker(nil, nil, 10, time.Minute)
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{6, 32 * time.Minute},
+		{7, maxBackoff},
+		{20, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := worker.nextBackoff(tt.attempts); got != tt.want {
+			t.Errorf("nextBackoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+// forwardedEvents - 会推送给 webhook 的事件: 注册、登录 与 账号状态变化
+var forwardedEvents = map[domain.EventType]bool{
+	domain.EventUserRegistered:        true,
+	domain.EventUserLoggedIn:          true,
+	domain.EventUserDeletionScheduled: true,
+	domain.EventUserDeletionCancelled: true,
+}
+
+// Dispatcher - 实现 domain.EventPublisher, 为订阅了该事件的 webhook 创建投递记录
+type Dispatcher struct {
+	webhookRepo domain.WebhookRepository
+}
+
+// NewDispatcher will create new an Dispatcher
+func NewDispatcher(repo domain.WebhookRepository) *Dispatcher {
+	return &Dispatcher{repo}
+}
+
+// Publish - implement domain.EventPublisher
+func (d *Dispatcher) Publish(ctx context.Context, event domain.Event) error {
+	if !forwardedEvents[event.GetType()] {
+		return nil
+	}
+
+	payload, err := json.Marshal(&body.PayloadBody{
+		ID:         event.GetEventID(),
+		Type:       event.GetType(),
+		UserID:     event.GetUserID(),
+		Data:       event.GetPayload(),
+		OccurredAt: event.GetOccurredAt(),
+	})
+	if err != nil {
+		return err
+	}
+
+	webhooks, err := d.webhookRepo.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, w := range webhooks {
+		if !subscribes(w, event.GetType()) {
+			continue
+		}
+		// 投递ID 由 事件ID 与 webhookID 组成, 事件被重复转发时不会重复投递
+		err := d.webhookRepo.CreateDelivery(ctx, &body.DeliveryBody{
+			ID:            event.GetEventID() + ":" + w.GetWebhookID(),
+			WebhookID:     w.GetWebhookID(),
+			EventID:       event.GetEventID(),
+			EventType:     event.GetType(),
+			Payload:       payload,
+			Status:        domain.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil && !errors.Is(err, status.ErrConflict) {
+			return err
+		}
+	}
+	return nil
+}
+
+func subscribes(w domain.Webhook, eventType domain.EventType) bool {
+	if len(w.GetEvents()) == 0 {
+		return true
+	}
+	for _, e := range w.GetEvents() {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/alibug/go-identity-entry/domain"
+	eventBody "github.com/alibug/go-identity-entry/event/repository/body"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+)
+
+func TestDispatcherPublish(t *testing.T) {
+	ctx := context.Background()
+	repo := newFakeWebhookRepository()
+	repo.CreateWebhook(ctx, &body.WebhookBody{ID: "all", URL: "http://all"})
+	repo.CreateWebhook(ctx, &body.WebhookBody{ID: "login", URL: "http://login", Events: []domain.EventType{domain.EventUserLoggedIn}})
+	dispatcher := NewDispatcher(repo)
+
+	registered := eventBody.NewEventBody(domain.EventUserRegistered, "u1", map[string]string{"account": "alice"})
+	if err := dispatcher.Publish(ctx, registered); err != nil {
+		t.Fatal(err)
+	}
+	// 重复转发同一事件 不会产生重复投递
+	if err := dispatcher.Publish(ctx, registered); err != nil {
+		t.Fatal(err)
+	}
+	// 不转发给 webhook 的事件
+	if err := dispatcher.Publish(ctx, eventBody.NewEventBody(domain.EventUserLoggedOut, "u1", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("got %d deliveries, want 1", len(repo.deliveries))
+	}
+	d := repo.delivery(registered.GetEventID() + ":all")
+	var payload body.PayloadBody
+	if err := json.Unmarshal(d.Payload, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Type != domain.EventUserRegistered || payload.UserID != "u1" || payload.Data["account"] != "alice" {
+		t.Errorf("unexpected payload %+v", payload)
+	}
+}
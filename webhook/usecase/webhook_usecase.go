@@ -0,0 +1,121 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+	"github.com/google/uuid"
+)
+
+// maxDeadLimit - 死信列表 每次最多返回的条数
+const maxDeadLimit = 100
+
+type webhookUsecase struct {
+	webhookRepo    domain.WebhookRepository
+	contextTimeout time.Duration
+}
+
+// NewWebhookUsecase will create new an webhookUsecase object representation of domain.WebhookUsecase interface
+func NewWebhookUsecase(repo domain.WebhookRepository, timeout time.Duration) domain.WebhookUsecase {
+	return &webhookUsecase{
+		webhookRepo:    repo,
+		contextTimeout: timeout,
+	}
+}
+
+func (w *webhookUsecase) RegisterWebhookUC(c context.Context, url string, events []domain.EventType) (domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+
+	if err := validateEvents(events); err != nil {
+		return nil, err
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	webhook := &body.WebhookBody{
+		ID:        uuid.NewString(),
+		URL:       url,
+		Secret:    secret,
+		Events:    events,
+		CreatedAt: time.Now(),
+	}
+	if err := w.webhookRepo.CreateWebhook(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (w *webhookUsecase) ListWebhooksUC(c context.Context) ([]domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	return w.webhookRepo.ListWebhooks(ctx)
+}
+
+func (w *webhookUsecase) DeleteWebhookUC(c context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	return w.webhookRepo.DeleteWebhook(ctx, id)
+}
+
+func (w *webhookUsecase) ListDeadDeliveriesUC(c context.Context, limit int64) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+
+	if limit < 1 || limit > maxDeadLimit {
+		limit = maxDeadLimit
+	}
+	return w.webhookRepo.ListDeadDeliveries(ctx, limit)
+}
+
+func (w *webhookUsecase) ReplayDeliveryUC(c context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(c, w.contextTimeout)
+	defer cancel()
+	return w.webhookRepo.ReplayDelivery(ctx, id)
+}
+
+// validateEvents - 订阅未定义的事件类型 永远不会收到投递, 多半是拼写错误
+func validateEvents(events []domain.EventType) error {
+	var details []domain.FieldError
+	for i, e := range events {
+		if e.Known() {
+			continue
+		}
+		known := make([]string, 0, len(domain.EventTypes))
+		for _, t := range domain.EventTypes {
+			known = append(known, string(t))
+		}
+		field := fmt.Sprintf("events[%d]", i)
+		details = append(details, domain.FieldError{
+			Field:   field,
+			Rule:    "oneof",
+			Message: fmt.Sprintf("%s must be one of [%s]", field, strings.Join(known, " ")),
+		})
+	}
+	if len(details) == 0 {
+		return nil
+	}
+	return &domain.CodedError{
+		Code:    domain.CodeValidationFailed,
+		Message: "request validation failed",
+		Details: details,
+		Err:     status.ErrBadParamInput,
+	}
+}
+
+// newSecret - 生成 32 字节的随机签名密钥
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,66 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alibug/go-identity-entry/domain"
+	"github.com/alibug/go-identity-entry/webhook/repository/body"
+	"github.com/alibug/go-identity-utils/status"
+)
+
+func TestRegisterWebhookUCValidatesEvents(t *testing.T) {
+	repo := newFakeWebhookRepository()
+	uc := NewWebhookUsecase(repo, time.Second)
+	ctx := context.Background()
+
+	webhook, err := uc.RegisterWebhookUC(ctx, "https://example.com/hook", []domain.EventType{domain.EventUserRegistered, domain.EventPasswordChanged})
+	if err != nil {
+		t.Fatalf("RegisterWebhookUC: %v", err)
+	}
+	if len(webhook.GetSecret()) != 64 {
+		t.Errorf("secret = %q, want 32 random bytes in hex", webhook.GetSecret())
+	}
+
+	_, err = uc.RegisterWebhookUC(ctx, "https://example.com/hook", []domain.EventType{domain.EventUserRegistered, "UserRegisterd"})
+	var coded *domain.CodedError
+	if !errors.As(err, &coded) || coded.Code != domain.CodeValidationFailed || !errors.Is(err, status.ErrBadParamInput) {
+		t.Fatalf("RegisterWebhookUC(unknown event) err = %v, want validation_failed", err)
+	}
+	if len(coded.Details) != 1 || coded.Details[0].Field != "events[1]" {
+		t.Errorf("details = %+v, want events[1]", coded.Details)
+	}
+	if webhooks, _ := repo.ListWebhooks(ctx); len(webhooks) != 1 {
+		t.Errorf("%d webhooks stored, want 1", len(webhooks))
+	}
+}
+
+func TestReplayDeliveryUC(t *testing.T) {
+	repo := newFakeWebhookRepository()
+	uc := NewWebhookUsecase(repo, time.Second)
+	ctx := context.Background()
+	repo.CreateDelivery(ctx, &body.DeliveryBody{
+		ID:            "d1",
+		WebhookID:     "wh",
+		Attempts:      8,
+		Status:        domain.DeliveryDead,
+		NextAttemptAt: time.Now().Add(time.Hour),
+	})
+
+	if err := uc.ReplayDeliveryUC(ctx, "d1"); err != nil {
+		t.Fatalf("ReplayDeliveryUC: %v", err)
+	}
+	d := repo.delivery("d1")
+	if d.Status != domain.DeliveryPending || d.Attempts != 0 || d.NextAttemptAt.After(time.Now()) {
+		t.Fatalf("replayed delivery = %+v, want pending, due now, 0 attempts", d)
+	}
+	if dead, _ := uc.ListDeadDeliveriesUC(ctx, 0); len(dead) != 0 {
+		t.Errorf("dead deliveries = %d after replay, want 0", len(dead))
+	}
+
+	if err := uc.ReplayDeliveryUC(ctx, "missing"); !errors.Is(err, status.ErrNotFound) {
+		t.Fatalf("ReplayDeliveryUC(missing) err = %v, want ErrNotFound", err)
+	}
+}